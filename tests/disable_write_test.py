@@ -35,7 +35,20 @@ async def test_disable_write_flag_disables_write_tools(grafana_env):
             # Verify write tools are NOT present
             write_tools = [
                 "update_dashboard",
+                "create_dashboard_from_spec",
+                "delete_dashboard",
+                "move_dashboards",
+                "clone_dashboard",
+                "create_library_panel",
+                "update_library_panel",
+                "import_dashboards",
+                "create_dashboard_snapshot",
+                "delete_snapshot",
+                "set_public_dashboard",
                 "create_folder",
+                "rename_folder",
+                "move_folder",
+                "delete_folder",
                 "create_incident",
                 "add_activity_to_incident",
                 "create_alert_rule",
@@ -87,7 +100,20 @@ async def test_without_disable_write_flag_enables_write_tools(grafana_env):
             # Verify write tools ARE present
             write_tools = [
                 "update_dashboard",
+                "create_dashboard_from_spec",
+                "delete_dashboard",
+                "move_dashboards",
+                "clone_dashboard",
+                "create_library_panel",
+                "update_library_panel",
+                "import_dashboards",
+                "create_dashboard_snapshot",
+                "delete_snapshot",
+                "set_public_dashboard",
                 "create_folder",
+                "rename_folder",
+                "move_folder",
+                "delete_folder",
                 "create_incident",
                 "add_activity_to_incident",
                 "create_alert_rule",
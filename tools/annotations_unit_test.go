@@ -29,6 +29,12 @@ func mockCtxWithClient(server *httptest.Server) context.Context {
 	return mcpgrafana.WithGrafanaClient(context.Background(), c)
 }
 
+// mockCtxWithGrafanaURL is mockCtxWithClient plus a Grafana config pointing
+// at the server, for tools that make raw HTTP requests to Grafana.
+func mockCtxWithGrafanaURL(server *httptest.Server) context.Context {
+	return mcpgrafana.WithGrafanaConfig(mockCtxWithClient(server), mcpgrafana.GrafanaConfig{URL: server.URL})
+}
+
 func TestGetAnnotations_UsesCorrectQueryParams(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/api/annotations", r.URL.Path)
@@ -0,0 +1,486 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultAnomalyWindow    = 30
+	defaultAnomalyThreshold = 3.0
+	defaultAnomalyLimit     = 20
+	defaultSeasonalWeeks    = 2
+	maxSeasonalWeeks        = 4
+	// minAnomalyHistory is the number of earlier points the rolling z-score
+	// needs before it scores a point.
+	minAnomalyHistory = 5
+	// maxAnomalyIntervals bounds the intervals returned per series; the
+	// strongest are kept.
+	maxAnomalyIntervals = 10
+	// maxAnomalyScore caps scores so that deviations from a perfectly flat
+	// baseline stay finite.
+	maxAnomalyScore = 1000
+	// madScale turns a median absolute deviation into an estimate of the
+	// standard deviation of normally distributed data.
+	madScale = 1.4826
+	// meanAbsScale does the same for a mean absolute deviation.
+	meanAbsScale = 1.2533
+)
+
+var defaultAnomalyMethods = []string{"zscore", "mad"}
+
+type DetectAnomaliesParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus or Loki datasource"`
+	Expr          string   `json:"expr" jsonschema:"required,description=The PromQL expression or LogQL metric query to analyze\\, e.g. sum by (job) (rate(http_requests_total[5m])) or sum(count_over_time({app=\"api\"} |= \"error\" [1m]))"`
+	StartTime     string   `json:"startTime,omitempty" jsonschema:"description=The start time in RFC3339 or relative to now (e.g. 'now-6h'). Defaults to now-6h"`
+	EndTime       string   `json:"endTime,omitempty" jsonschema:"description=The end time in RFC3339 or relative to now. Defaults to now"`
+	StepSeconds   int      `json:"stepSeconds,omitempty" jsonschema:"description=The step in seconds. Chosen automatically from maxPoints when omitted"`
+	MaxPoints     int      `json:"maxPoints,omitempty" jsonschema:"description=The maximum number of points per series used to choose the step (default 300)"`
+	Methods       []string `json:"methods,omitempty" jsonschema:"description=The detection methods to apply: 'zscore' (rolling z-score against the preceding window)\\, 'mad' (median absolute deviation over the whole range) and 'seasonal' (deviation from the median of the same window in prior weeks). Defaults to zscore and mad"`
+	Window        int      `json:"window,omitempty" jsonschema:"description=The number of preceding points used by the rolling z-score (default 30)"`
+	Threshold     float64  `json:"threshold,omitempty" jsonschema:"description=The score\\, in standard deviations\\, from which a point is anomalous (default 3)"`
+	SeasonalWeeks int      `json:"seasonalWeeks,omitempty" jsonschema:"description=The number of prior weeks used for the seasonal baseline (default 2\\, max 4)"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=The maximum number of anomalous series to return\\, strongest first (default 20)"`
+}
+
+// AnomalyInterval is a run of consecutive anomalous points deviating in
+// the same direction.
+type AnomalyInterval struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Points    int       `json:"points"`
+	PeakTime  time.Time `json:"peakTime"`
+	PeakValue float64   `json:"peakValue"`
+	// Baseline is the value the method expected at the peak.
+	Baseline float64 `json:"baseline"`
+	// Score is the deviation at the peak in (robust) standard deviations.
+	Score     float64 `json:"score"`
+	Direction string  `json:"direction"`
+	Severity  string  `json:"severity"`
+	Method    string  `json:"method"`
+}
+
+type SeriesAnomalies struct {
+	Labels    map[string]string `json:"labels"`
+	Points    int               `json:"points"`
+	MaxScore  float64           `json:"maxScore"`
+	Anomalies []AnomalyInterval `json:"anomalies"`
+}
+
+type AnomalyDetectionResult struct {
+	DatasourceType  string    `json:"datasourceType"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	StepSeconds     float64   `json:"stepSeconds"`
+	Methods         []string  `json:"methods"`
+	Threshold       float64   `json:"threshold"`
+	SeriesChecked   int       `json:"seriesChecked"`
+	SeriesAnomalous int       `json:"seriesAnomalous"`
+	// FirstAnomaly is the start of the earliest anomaly in any series, a
+	// good first guess at when an incident started.
+	FirstAnomaly *time.Time        `json:"firstAnomaly,omitempty"`
+	Series       []SeriesAnomalies `json:"series"`
+	Truncated    bool              `json:"truncated,omitempty"`
+	Notices      []string          `json:"notices,omitempty"`
+}
+
+// rangeQuerier runs a metric range query against a datasource.
+type rangeQuerier func(ctx context.Context, r promv1.Range) (model.Matrix, error)
+
+// metricRangeQuerier returns a range querier for a Prometheus or Loki
+// datasource, along with the datasource's kind.
+func metricRangeQuerier(ctx context.Context, datasourceUID, expr string) (rangeQuerier, string, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: datasourceUID})
+	if err != nil {
+		return nil, "", err
+	}
+	dsType := strings.ToLower(ds.Type)
+	switch {
+	case strings.Contains(dsType, "loki"):
+		client, err := newLokiClient(ctx, datasourceUID)
+		if err != nil {
+			return nil, "", fmt.Errorf("creating Loki client: %w", err)
+		}
+		return func(ctx context.Context, r promv1.Range) (model.Matrix, error) {
+			return client.fetchMetricRange(ctx, expr, r)
+		}, "loki", nil
+	case strings.Contains(dsType, "prometheus"):
+		client, err := promClientFromContext(ctx, datasourceUID)
+		if err != nil {
+			return nil, "", fmt.Errorf("getting Prometheus client: %w", err)
+		}
+		return func(ctx context.Context, r promv1.Range) (model.Matrix, error) {
+			value, _, err := client.QueryRange(ctx, expr, r)
+			if err != nil {
+				return nil, fmt.Errorf("querying Prometheus range: %w", err)
+			}
+			matrix, ok := value.(model.Matrix)
+			if !ok {
+				return nil, fmt.Errorf("expected a matrix result, got %s", value.Type())
+			}
+			return matrix, nil
+		}, "prometheus", nil
+	default:
+		return nil, "", fmt.Errorf("datasource %s has type %s: anomaly detection supports Prometheus and Loki datasources", datasourceUID, ds.Type)
+	}
+}
+
+// anomalySeries holds the finite points of a series and their step index
+// from the start of the range.
+type anomalySeries struct {
+	times  []time.Time
+	values []float64
+	steps  []int
+}
+
+func newAnomalySeries(stream *model.SampleStream, start time.Time, step time.Duration) anomalySeries {
+	var s anomalySeries
+	for _, p := range stream.Values {
+		v := float64(p.Value)
+		if !isFinite(v) {
+			continue
+		}
+		t := p.Timestamp.Time()
+		s.times = append(s.times, t)
+		s.values = append(s.values, v)
+		s.steps = append(s.steps, stepIndex(t, start, step))
+	}
+	return s
+}
+
+// pointScore is a method's score for a point; NaN means not scored.
+type pointScore struct {
+	score    float64
+	baseline float64
+	method   string
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return percentile(sorted, 0.5)
+}
+
+// robustScale estimates the spread of values around center from the median
+// absolute deviation, falling back to the mean absolute deviation when
+// more than half the values equal the center.
+func robustScale(values []float64, center float64) float64 {
+	deviations := make([]float64, len(values))
+	var sum float64
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+		sum += deviations[i]
+	}
+	if mad := median(deviations); mad > 0 {
+		return mad * madScale
+	}
+	return sum / float64(len(values)) * meanAbsScale
+}
+
+// anomalyScore returns how many spreads v is from the baseline, capped
+// at maxAnomalyScore.
+func anomalyScore(v, baseline, scale float64) float64 {
+	diff := v - baseline
+	if diff == 0 {
+		return 0
+	}
+	if scale == 0 {
+		return math.Copysign(maxAnomalyScore, diff)
+	}
+	return math.Max(-maxAnomalyScore, math.Min(maxAnomalyScore, diff/scale))
+}
+
+// rollingZScores scores each point against the mean and standard deviation
+// of the window of points before it.
+func rollingZScores(values []float64, window int) []pointScore {
+	scores := make([]pointScore, len(values))
+	for i, v := range values {
+		history := values[max(0, i-window):i]
+		if len(history) < minAnomalyHistory {
+			scores[i] = pointScore{score: math.NaN()}
+			continue
+		}
+		mean, std := meanStd(history)
+		scores[i] = pointScore{score: anomalyScore(v, mean, std), baseline: mean, method: "zscore"}
+	}
+	return scores
+}
+
+// madScores scores each point against the median of the whole series.
+func madScores(values []float64) []pointScore {
+	scores := make([]pointScore, len(values))
+	center := median(values)
+	scale := robustScale(values, center)
+	for i, v := range values {
+		scores[i] = pointScore{score: anomalyScore(v, center, scale), baseline: center, method: "mad"}
+	}
+	return scores
+}
+
+// seasonalScores scores each point against the median of the same step in
+// prior weeks, scaled by the spread of the residuals.
+func seasonalScores(s anomalySeries, weeks []map[int]float64) []pointScore {
+	scores := make([]pointScore, len(s.values))
+	baselines := make([]float64, len(s.values))
+	var residuals []float64
+	for i, step := range s.steps {
+		var prior []float64
+		for _, week := range weeks {
+			if v, ok := week[step]; ok {
+				prior = append(prior, v)
+			}
+		}
+		if len(prior) == 0 {
+			baselines[i] = math.NaN()
+			continue
+		}
+		baselines[i] = median(prior)
+		residuals = append(residuals, s.values[i]-baselines[i])
+	}
+	if len(residuals) == 0 {
+		for i := range scores {
+			scores[i] = pointScore{score: math.NaN()}
+		}
+		return scores
+	}
+	scale := robustScale(residuals, median(residuals))
+	for i, v := range s.values {
+		if math.IsNaN(baselines[i]) {
+			scores[i] = pointScore{score: math.NaN()}
+			continue
+		}
+		scores[i] = pointScore{score: anomalyScore(v, baselines[i], scale), baseline: baselines[i], method: "seasonal"}
+	}
+	return scores
+}
+
+func anomalySeverity(score, threshold float64) string {
+	switch abs := math.Abs(score); {
+	case abs >= 2*threshold:
+		return "high"
+	case abs >= 1.5*threshold:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// anomalyIntervals groups consecutive points whose strongest score passes
+// the threshold into intervals.
+func anomalyIntervals(s anomalySeries, methodScores [][]pointScore, threshold float64) []AnomalyInterval {
+	var intervals []AnomalyInterval
+	var current *AnomalyInterval
+	lastStep := 0
+	for i, v := range s.values {
+		best := pointScore{score: math.NaN()}
+		for _, scores := range methodScores {
+			if ps := scores[i]; !math.IsNaN(ps.score) && (math.IsNaN(best.score) || math.Abs(ps.score) > math.Abs(best.score)) {
+				best = ps
+			}
+		}
+		if math.IsNaN(best.score) || math.Abs(best.score) < threshold {
+			current = nil
+			continue
+		}
+		direction := "up"
+		if best.score < 0 {
+			direction = "down"
+		}
+		if current == nil || current.Direction != direction || s.steps[i]-lastStep > 1 {
+			intervals = append(intervals, AnomalyInterval{Start: s.times[i], Direction: direction})
+			current = &intervals[len(intervals)-1]
+		}
+		current.End = s.times[i]
+		current.Points++
+		lastStep = s.steps[i]
+		if current.Points == 1 || math.Abs(best.score) > math.Abs(current.Score) {
+			current.PeakTime, current.PeakValue = s.times[i], v
+			current.Baseline, current.Score, current.Method = best.baseline, best.score, best.method
+			current.Severity = anomalySeverity(best.score, threshold)
+		}
+	}
+	return intervals
+}
+
+func detectAnomalies(ctx context.Context, args DetectAnomaliesParams) (*AnomalyDetectionResult, error) {
+	if args.Expr == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	methods := args.Methods
+	if len(methods) == 0 {
+		methods = defaultAnomalyMethods
+	}
+	seasonal := false
+	for _, m := range methods {
+		switch m {
+		case "zscore", "mad":
+		case "seasonal":
+			seasonal = true
+		default:
+			return nil, fmt.Errorf("invalid method %q: must be zscore, mad or seasonal", m)
+		}
+	}
+	startTime, endTime := args.StartTime, args.EndTime
+	if startTime == "" {
+		startTime = "now-6h"
+	}
+	if endTime == "" {
+		endTime = "now"
+	}
+	start, err := parseTime(startTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := parseTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	step := time.Duration(args.StepSeconds) * time.Second
+	if step <= 0 {
+		step = autoStep(start, end, args.MaxPoints)
+	}
+	window := args.Window
+	if window <= 0 {
+		window = defaultAnomalyWindow
+	}
+	threshold := args.Threshold
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+	seasonalWeeks := args.SeasonalWeeks
+	if seasonalWeeks <= 0 {
+		seasonalWeeks = defaultSeasonalWeeks
+	}
+	seasonalWeeks = min(seasonalWeeks, maxSeasonalWeeks)
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultAnomalyLimit
+	}
+
+	query, dsType, err := metricRangeQuerier(ctx, args.DatasourceUID, args.Expr)
+	if err != nil {
+		return nil, err
+	}
+	matrix, err := query(ctx, promv1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	}
+
+	// priorWeeks holds, for each prior week, each series' values by step.
+	var priorWeeks []map[model.Fingerprint]map[int]float64
+	if seasonal {
+		for w := 1; w <= seasonalWeeks; w++ {
+			shift := time.Duration(w) * 7 * 24 * time.Hour
+			prior, err := query(ctx, promv1.Range{Start: start.Add(-shift), End: end.Add(-shift), Step: step})
+			if err != nil {
+				return nil, fmt.Errorf("querying week -%d for the seasonal baseline: %w", w, err)
+			}
+			week := make(map[model.Fingerprint]map[int]float64, len(prior))
+			for _, stream := range prior {
+				week[stream.Metric.Fingerprint()] = alignedValues(stream, start.Add(-shift), step)
+			}
+			priorWeeks = append(priorWeeks, week)
+		}
+	}
+
+	result := &AnomalyDetectionResult{
+		DatasourceType: dsType,
+		Start:          start,
+		End:            end,
+		StepSeconds:    step.Seconds(),
+		Methods:        methods,
+		Threshold:      threshold,
+		SeriesChecked:  len(matrix),
+		Series:         []SeriesAnomalies{},
+	}
+	withoutHistory := 0
+	for _, stream := range matrix {
+		s := newAnomalySeries(stream, start, step)
+		if len(s.values) == 0 {
+			continue
+		}
+		var methodScores [][]pointScore
+		for _, m := range methods {
+			switch m {
+			case "zscore":
+				methodScores = append(methodScores, rollingZScores(s.values, window))
+			case "mad":
+				methodScores = append(methodScores, madScores(s.values))
+			case "seasonal":
+				var weeks []map[int]float64
+				for _, week := range priorWeeks {
+					if values, ok := week[stream.Metric.Fingerprint()]; ok {
+						weeks = append(weeks, values)
+					}
+				}
+				if len(weeks) == 0 {
+					withoutHistory++
+				}
+				methodScores = append(methodScores, seasonalScores(s, weeks))
+			}
+		}
+		intervals := anomalyIntervals(s, methodScores, threshold)
+		if len(intervals) == 0 {
+			continue
+		}
+		series := SeriesAnomalies{Labels: labelSetMap(model.LabelSet(stream.Metric)), Points: len(s.values)}
+		for _, iv := range intervals {
+			series.MaxScore = math.Max(series.MaxScore, math.Abs(iv.Score))
+			if result.FirstAnomaly == nil || iv.Start.Before(*result.FirstAnomaly) {
+				t := iv.Start
+				result.FirstAnomaly = &t
+			}
+		}
+		if len(intervals) > maxAnomalyIntervals {
+			sort.SliceStable(intervals, func(i, j int) bool { return math.Abs(intervals[i].Score) > math.Abs(intervals[j].Score) })
+			intervals = intervals[:maxAnomalyIntervals]
+			sort.SliceStable(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+		}
+		series.Anomalies = intervals
+		result.Series = append(result.Series, series)
+	}
+	if withoutHistory > 0 {
+		result.Notices = append(result.Notices, fmt.Sprintf("%d series had no data in prior weeks, so they have no seasonal baseline", withoutHistory))
+	}
+
+	result.SeriesAnomalous = len(result.Series)
+	sort.SliceStable(result.Series, func(i, j int) bool { return result.Series[i].MaxScore > result.Series[j].MaxScore })
+	if len(result.Series) > limit {
+		result.Series = result.Series[:limit]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+var DetectAnomalies = mcpgrafana.MustTool(
+	"detect_anomalies",
+	"Find anomalous intervals in a Prometheus range query or Loki metric query without eyeballing graphs. Applies a rolling z-score, a median absolute deviation (MAD) test and optionally a seasonal baseline from the same window in prior weeks, and returns the anomalous series strongest first, each with its anomalous intervals (start, end, peak, expected baseline, score, direction and severity), plus the earliest anomaly across all series as a hint for when an incident started.",
+	detectAnomalies,
+	mcp.WithTitleAnnotation("Detect anomalies"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// registerDetectAnomalies adds DetectAnomalies to the server unless it is
+// already there: both the Prometheus and the Loki tool sets provide it, and
+// either may be disabled on its own.
+func registerDetectAnomalies(mcp *server.MCPServer) {
+	if mcp.GetTool(DetectAnomalies.Tool.Name) != nil {
+		return
+	}
+	DetectAnomalies.Register(mcp)
+}
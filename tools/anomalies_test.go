@@ -0,0 +1,186 @@
+//go:build unit
+
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anomalyStart is the start of the range used by these tests.
+var anomalyStart = time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+// matrixJSON renders a single-series matrix response with one point per
+// minute from start.
+func matrixJSON(start time.Time, labels string, values []float64) string {
+	points := make([]string, len(values))
+	for i, v := range values {
+		points[i] = fmt.Sprintf(`[%d, "%g"]`, start.Add(time.Duration(i)*time.Minute).Unix(), v)
+	}
+	return fmt.Sprintf(`{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": %s, "values": [%s]}]}}`, labels, strings.Join(points, ","))
+}
+
+// noisyValues returns n values oscillating around 10 with a spike of the
+// given height from spikeAt for three points.
+func noisyValues(n, spikeAt int, spike float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = 10 + float64(i%3) - 1
+		if i >= spikeAt && i < spikeAt+3 {
+			values[i] = spike
+		}
+	}
+	return values
+}
+
+func TestAnomalyIntervals(t *testing.T) {
+	values := noisyValues(60, 40, 50)
+	s := anomalySeries{values: values}
+	for i := range values {
+		s.times = append(s.times, anomalyStart.Add(time.Duration(i)*time.Minute))
+		s.steps = append(s.steps, i)
+	}
+
+	intervals := anomalyIntervals(s, [][]pointScore{rollingZScores(values, 30), madScores(values)}, 3)
+	require.Len(t, intervals, 1)
+	iv := intervals[0]
+	assert.Equal(t, anomalyStart.Add(40*time.Minute), iv.Start)
+	assert.Equal(t, anomalyStart.Add(42*time.Minute), iv.End)
+	assert.Equal(t, 3, iv.Points)
+	assert.Equal(t, "up", iv.Direction)
+	assert.Equal(t, "high", iv.Severity)
+	assert.Equal(t, 50.0, iv.PeakValue)
+	assert.Greater(t, iv.Score, 6.0)
+
+	// A drop is reported as a downward anomaly.
+	values = noisyValues(60, 40, -30)
+	s.values = values
+	intervals = anomalyIntervals(s, [][]pointScore{madScores(values)}, 3)
+	require.Len(t, intervals, 1)
+	assert.Equal(t, "down", intervals[0].Direction)
+	assert.Equal(t, "mad", intervals[0].Method)
+	assert.InDelta(t, 10, intervals[0].Baseline, 1)
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, r.ParseForm())
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case r.URL.Path == "/api/datasources/uid/loki":
+			_, _ = w.Write([]byte(`{"uid": "loki", "type": "loki"}`))
+		case r.URL.Path == "/api/datasources/uid/pg":
+			_, _ = w.Write([]byte(`{"uid": "pg", "type": "grafana-postgresql-datasource"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query_range") && strings.Contains(r.URL.Path, "/prom/"):
+			switch r.Form.Get("start") {
+			case fmt.Sprint(anomalyStart.Unix()):
+				_, _ = w.Write([]byte(matrixJSON(anomalyStart, `{"job": "api"}`, noisyValues(60, 40, 50))))
+			case fmt.Sprint(anomalyStart.Add(-7 * 24 * time.Hour).Unix()):
+				// Last week had the same spike, so it is seasonal.
+				_, _ = w.Write([]byte(matrixJSON(anomalyStart.Add(-7*24*time.Hour), `{"job": "api"}`, noisyValues(60, 40, 50))))
+			default:
+				t.Errorf("unexpected start: %s", r.Form.Get("start"))
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case strings.HasSuffix(r.URL.Path, "/loki/api/v1/query_range"):
+			assert.Equal(t, fmt.Sprint(anomalyStart.UnixNano()), r.Form.Get("start"))
+			assert.Equal(t, "60", r.Form.Get("step"))
+			if !strings.Contains(r.Form.Get("query"), "count_over_time") {
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "streams", "result": [{"stream": {"app": "api"}, "values": [["1704672000000000000", "error"]]}]}}`))
+				return
+			}
+			_, _ = w.Write([]byte(matrixJSON(anomalyStart, `{"app": "api"}`, noisyValues(60, 20, 200))))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+	params := DetectAnomaliesParams{
+		Expr:        "sum by (job) (rate(requests_total[5m]))",
+		StartTime:   anomalyStart.Format(time.RFC3339),
+		EndTime:     anomalyStart.Add(59 * time.Minute).Format(time.RFC3339),
+		StepSeconds: 60,
+	}
+
+	t.Run("prometheus", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "prom"
+		result, err := detectAnomalies(ctx, args)
+		require.NoError(t, err)
+		assert.Equal(t, "prometheus", result.DatasourceType)
+		assert.Equal(t, 1, result.SeriesChecked)
+		assert.Equal(t, 1, result.SeriesAnomalous)
+		require.Len(t, result.Series, 1)
+		assert.Equal(t, "api", result.Series[0].Labels["job"])
+		require.NotNil(t, result.FirstAnomaly)
+		assert.Equal(t, anomalyStart.Add(40*time.Minute), result.FirstAnomaly.UTC())
+	})
+
+	t.Run("seasonal", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "prom"
+		args.Methods = []string{"seasonal"}
+		args.SeasonalWeeks = 1
+		result, err := detectAnomalies(ctx, args)
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.SeriesAnomalous)
+		assert.Nil(t, result.FirstAnomaly)
+	})
+
+	t.Run("loki", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "loki"
+		args.Expr = `sum(count_over_time({app="api"} |= "error" [1m]))`
+		result, err := detectAnomalies(ctx, args)
+		require.NoError(t, err)
+		assert.Equal(t, "loki", result.DatasourceType)
+		require.Len(t, result.Series, 1)
+		assert.Equal(t, anomalyStart.Add(20*time.Minute), result.Series[0].Anomalies[0].Start.UTC())
+	})
+
+	t.Run("loki log query", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "loki"
+		args.Expr = `{app="api"} |= "error"`
+		_, err := detectAnomalies(ctx, args)
+		assert.ErrorContains(t, err, "expected a metric query returning a matrix, got streams results")
+	})
+
+	t.Run("unsupported datasource", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "pg"
+		_, err := detectAnomalies(ctx, args)
+		assert.ErrorContains(t, err, "supports Prometheus and Loki")
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		args := params
+		args.DatasourceUID = "prom"
+		args.Methods = []string{"prophet"}
+		_, err := detectAnomalies(ctx, args)
+		assert.ErrorContains(t, err, "invalid method")
+	})
+}
+
+func TestRegisterDetectAnomalies(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	AddPrometheusTools(s)
+	AddLokiTools(s)
+	assert.NotNil(t, s.GetTool("detect_anomalies"))
+
+	lokiOnly := server.NewMCPServer("test", "0.0.0")
+	AddLokiTools(lokiOnly)
+	assert.NotNil(t, lokiOnly.GetTool("detect_anomalies"))
+}
@@ -3,15 +3,18 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/PaesslerAG/gval"
 	"github.com/PaesslerAG/jsonpath"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/grafana/grafana-openapi-client-go/client/dashboards"
 	"github.com/grafana/grafana-openapi-client-go/models"
 	mcpgrafana "github.com/grafana/mcp-grafana"
 )
@@ -24,6 +27,16 @@ func getDashboardByUID(ctx context.Context, args GetDashboardByUIDParams) (*mode
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	dashboard, err := c.Dashboards.GetDashboardByUID(args.UID)
 	if err != nil {
+		// Dashboards stored in the v2 schema can only be read through the
+		// dashboard.grafana.app API.
+		var notAcceptable *dashboards.GetDashboardByUIDNotAcceptable
+		if errors.As(err, &notAcceptable) {
+			var hint string
+			if notAcceptable.Payload != nil && notAcceptable.Payload.Message != nil {
+				hint = *notAcceptable.Payload.Message
+			}
+			return getDashboardV2ByUID(ctx, args.UID, hint)
+		}
 		return nil, fmt.Errorf("get dashboard by uid %s: %w", args.UID, err)
 	}
 	return dashboard.Payload, nil
@@ -32,7 +45,7 @@ func getDashboardByUID(ctx context.Context, args GetDashboardByUIDParams) (*mode
 // PatchOperation represents a single patch operation
 type PatchOperation struct {
 	Op    string      `json:"op" jsonschema:"required,description=Operation type: 'replace'\\, 'add'\\, 'remove'"`
-	Path  string      `json:"path" jsonschema:"required,description=JSONPath to the property to modify. Supports: '$.title'\\, '$.panels[0].title'\\, '$.panels[0].targets[0].expr'\\, '$.panels[1].targets[0].datasource'\\, etc. For appending to arrays\\, use '/- ' syntax: '$.panels/- ' (append to panels array) or '$.panels[2]/- ' (append to nested array at index 2)."`
+	Path  string      `json:"path" jsonschema:"required,description=JSONPath to the property to modify. Supports: '$.title'\\, '$.panels[0].title'\\, '$.panels[0].targets[0].expr'\\, '$.panels[1].targets[0].datasource'\\, etc. For v2 dashboards use keys like '$.spec.elements[\"panel-1\"].spec.title'. For appending to arrays\\, use '/- ' syntax: '$.panels/- ' (append to panels array) or '$.panels[2]/- ' (append to nested array at index 2)."`
 	Value interface{} `json:"value,omitempty" jsonschema:"description=New value for replace/add operations"`
 }
 
@@ -114,6 +127,9 @@ func updateDashboardWithPatches(ctx context.Context, args UpdateDashboardParams)
 
 // updateDashboardWithFullJSON performs a traditional full dashboard update
 func updateDashboardWithFullJSON(ctx context.Context, args UpdateDashboardParams) (*models.PostDashboardOKBody, error) {
+	if isDashboardV2(args.Dashboard) {
+		return saveDashboardV2(ctx, args.Dashboard, args.FolderUID, args.Message, args.Overwrite)
+	}
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	cmd := &models.SaveDashboardCommand{
 		Dashboard: args.Dashboard,
@@ -131,7 +147,7 @@ func updateDashboardWithFullJSON(ctx context.Context, args UpdateDashboardParams
 
 var GetDashboardByUID = mcpgrafana.MustTool(
 	"get_dashboard_by_uid",
-	"Retrieves the complete dashboard, including panels, variables, and settings, for a specific dashboard identified by its UID. WARNING: Large dashboards can consume significant context window space. Consider using get_dashboard_summary for overview or get_dashboard_property for specific data instead.",
+	"Retrieves the complete dashboard, including panels, variables, and settings, for a specific dashboard identified by its UID. Dashboards stored in the v2 schema are returned as dashboard.grafana.app resources\\, with panels under spec.elements and their placement under spec.layout. WARNING: Large dashboards can consume significant context window space. Consider using get_dashboard_summary for overview or get_dashboard_property for specific data instead.",
 	getDashboardByUID,
 	mcp.WithTitleAnnotation("Get dashboard details"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -140,7 +156,7 @@ var GetDashboardByUID = mcpgrafana.MustTool(
 
 var UpdateDashboard = mcpgrafana.MustTool(
 	"update_dashboard",
-	"Create or update a dashboard using either full JSON or efficient patch operations. For new dashboards\\, provide the 'dashboard' field. For updating existing dashboards\\, use 'uid' + 'operations' for better context window efficiency. Patch operations support complex JSONPaths like '$.panels[0].targets[0].expr'\\, '$.panels[1].title'\\, '$.panels[2].targets[0].datasource'\\, etc. Supports appending to arrays using '/- ' syntax: '$.panels/- ' appends to panels array\\, '$.panels[2]/- ' appends to nested array at index 2.",
+	"Create or update a dashboard using either full JSON or efficient patch operations. For new dashboards\\, provide the 'dashboard' field. For updating existing dashboards\\, use 'uid' + 'operations' for better context window efficiency. Patch operations support complex JSONPaths like '$.panels[0].targets[0].expr'\\, '$.panels[1].title'\\, '$.panels[2].targets[0].datasource'\\, etc. Supports appending to arrays using '/- ' syntax: '$.panels/- ' appends to panels array\\, '$.panels[2]/- ' appends to nested array at index 2. v2 dashboards are patched in the same way using their resource paths\\, e.g. '$.spec.elements[\"panel-1\"].spec.title'. Use get_dashboard_summary to find the path of each panel.",
 	updateDashboard,
 	mcp.WithTitleAnnotation("Create or update dashboard"),
 	mcp.WithDestructiveHintAnnotation(true),
@@ -159,6 +175,11 @@ type panelQuery struct {
 	Title      string         `json:"title"`
 	Query      string         `json:"query"`
 	Datasource datasourceInfo `json:"datasource"`
+	// LibraryPanelUID is set when the query comes from a library panel.
+	LibraryPanelUID string `json:"libraryPanelUid,omitempty"`
+	// Error is set instead of Query when the panel's queries could not be
+	// loaded, e.g. because its library panel was deleted.
+	Error string `json:"error,omitempty"`
 }
 
 func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQueriesParams) ([]panelQuery, error) {
@@ -173,14 +194,35 @@ func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQuerie
 	if !ok {
 		return result, fmt.Errorf("dashboard is not a JSON object")
 	}
-	panels, ok := db["panels"].([]any)
-	if !ok {
+	v2 := isDashboardV2(db)
+	if _, ok := db["panels"].([]any); !ok && !v2 {
 		return result, fmt.Errorf("panels is not a JSON array")
 	}
 
-	for _, p := range panels {
-		panel, ok := p.(map[string]any)
-		if !ok {
+	libraryPanels := newLibraryPanelResolver()
+	for _, p := range collectDashboardPanels(db) {
+		panel := p.Panel
+		if isDashboardV2Element(panel) {
+			if safeString(panel, "kind") != "LibraryPanel" {
+				result = append(result, v2PanelQueryResults(panel)...)
+				continue
+			}
+			panel = v2LibraryPanelReference(panel)
+		}
+		// Library panel references only contain the panel position, so
+		// fetch the library panel to get its queries.
+		libraryPanelUID := safeString(safeObject(panel, "libraryPanel"), "uid")
+		panel, err = libraryPanels.resolve(ctx, panel)
+		if err != nil {
+			title := safeString(panel, "title")
+			if title == "" {
+				title = safeString(safeObject(panel, "libraryPanel"), "name")
+			}
+			result = append(result, panelQuery{
+				Title:           title,
+				LibraryPanelUID: libraryPanelUID,
+				Error:           fmt.Sprintf("resolve library panel: %v", err),
+			})
 			continue
 		}
 		title, _ := panel["title"].(string)
@@ -209,9 +251,10 @@ func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQuerie
 			expr, _ := target["expr"].(string)
 			if expr != "" {
 				result = append(result, panelQuery{
-					Title:      title,
-					Query:      expr,
-					Datasource: datasourceInfo,
+					Title:           title,
+					Query:           expr,
+					Datasource:      datasourceInfo,
+					LibraryPanelUID: libraryPanelUID,
 				})
 			}
 		}
@@ -220,9 +263,28 @@ func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQuerie
 	return result, nil
 }
 
+// v2PanelQueryResults returns the queries of a v2 panel element in the
+// get_dashboard_panel_queries format.
+func v2PanelQueryResults(element map[string]any) []panelQuery {
+	var result []panelQuery
+	title := safeString(safeObject(element, "spec"), "title")
+	for _, q := range v2PanelQueries(element) {
+		panelQuerySpec := safeObject(q.(map[string]any), "spec")
+		expr := safeString(safeObject(safeObject(panelQuerySpec, "query"), "spec"), "expr")
+		if expr != "" {
+			result = append(result, panelQuery{
+				Title:      title,
+				Query:      expr,
+				Datasource: v2QueryDatasource(panelQuerySpec),
+			})
+		}
+	}
+	return result
+}
+
 var GetDashboardPanelQueries = mcpgrafana.MustTool(
 	"get_dashboard_panel_queries",
-	"Use this tool to retrieve panel queries and information from a Grafana dashboard. When asked about panel queries, queries in a dashboard, or what queries a dashboard contains, call this tool with the dashboard UID. The datasource is an object with fields `uid` (which may be a concrete UID or a template variable like \"$datasource\") and `type`. If the datasource UID is a template variable, it won't be usable directly for queries. Returns an array of objects, each representing a panel, with fields: title, query, and datasource (an object with uid and type).",
+	"Use this tool to retrieve panel queries and information from a Grafana dashboard. When asked about panel queries, queries in a dashboard, or what queries a dashboard contains, call this tool with the dashboard UID. The datasource is an object with fields `uid` (which may be a concrete UID or a template variable like \"$datasource\") and `type`. If the datasource UID is a template variable, it won't be usable directly for queries. Panels nested in rows (including collapsed rows) and v2 dashboards are supported. Library panel references are resolved to the library panel's queries. Returns an array of objects, each representing a panel, with fields: title, query, and datasource (an object with uid and type).",
 	GetDashboardPanelQueriesTool,
 	mcp.WithTitleAnnotation("Get dashboard panel queries"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -271,7 +333,7 @@ func getDashboardProperty(ctx context.Context, args GetDashboardPropertyParams)
 
 var GetDashboardProperty = mcpgrafana.MustTool(
 	"get_dashboard_property",
-	"Get specific parts of a dashboard using JSONPath expressions to minimize context window usage. Common paths: '$.title' (title)\\, '$.panels[*].title' (all panel titles)\\, '$.panels[0]' (first panel)\\, '$.templating.list' (variables)\\, '$.tags' (tags)\\, '$.panels[*].targets[*].expr' (all queries). Use this instead of get_dashboard_by_uid when you only need specific dashboard properties.",
+	"Get specific parts of a dashboard using JSONPath expressions to minimize context window usage. Common paths: '$.title' (title)\\, '$.panels[*].title' (all panel titles)\\, '$.panels[0]' (first panel)\\, '$.templating.list' (variables)\\, '$.tags' (tags)\\, '$.panels[*].targets[*].expr' (all queries). v2 dashboards are dashboard.grafana.app resources: use paths like '$.spec.title'\\, '$.spec.elements[*].spec.title' and '$.spec.elements[\"panel-1\"]'. Use this instead of get_dashboard_by_uid when you only need specific dashboard properties.",
 	getDashboardProperty,
 	mcp.WithTitleAnnotation("Get dashboard property"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -298,11 +360,16 @@ type DashboardSummary struct {
 }
 
 type PanelSummary struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Type        string `json:"type"`
-	Description string `json:"description,omitempty"`
-	QueryCount  int    `json:"queryCount"`
+	ID              int    `json:"id"`
+	Title           string `json:"title"`
+	Type            string `json:"type"`
+	Description     string `json:"description,omitempty"`
+	QueryCount      int    `json:"queryCount"`
+	LibraryPanelUID string `json:"libraryPanelUid,omitempty"`
+	// Row is the title of the row (or tab) containing the panel, if any.
+	Row string `json:"row,omitempty"`
+	// Path is the JSONPath of the panel, for use with patch operations.
+	Path string `json:"path"`
 }
 
 type VariableSummary struct {
@@ -333,31 +400,36 @@ func getDashboardSummary(ctx context.Context, args GetDashboardSummaryParams) (*
 		Meta: dashboard.Meta,
 	}
 
-	// Extract basic info using helper functions
-	extractBasicDashboardInfo(db, summary)
-
-	// Extract time range
-	summary.TimeRange = extractTimeRange(db)
+	// Extract basic info and time range. v2 dashboards keep them in the
+	// spec, with the time range and refresh under timeSettings.
+	if isDashboardV2(db) {
+		spec, _ := dashboardV2Spec(db)
+		extractBasicDashboardInfo(spec, summary)
+		timeSettings := safeObject(spec, "timeSettings")
+		summary.TimeRange = TimeRangeSummary{From: safeString(timeSettings, "from"), To: safeString(timeSettings, "to")}
+		summary.Refresh = safeString(timeSettings, "autoRefresh")
+	} else {
+		extractBasicDashboardInfo(db, summary)
+		summary.TimeRange = extractTimeRange(db)
+	}
 
-	// Extract panel summaries
-	if panels := safeArray(db, "panels"); panels != nil {
-		summary.PanelCount = len(panels)
-		for _, p := range panels {
-			if panelObj, ok := p.(map[string]interface{}); ok {
-				summary.Panels = append(summary.Panels, extractPanelSummary(panelObj))
-			}
+	// Extract panel summaries, including panels nested in rows
+	for _, p := range collectDashboardPanels(db) {
+		var panelSummary PanelSummary
+		if isDashboardV2Element(p.Panel) {
+			panelSummary = extractV2PanelSummary(p.Panel)
+		} else {
+			panelSummary = extractPanelSummary(p.Panel)
 		}
+		panelSummary.Row = p.Row
+		panelSummary.Path = p.Path
+		summary.Panels = append(summary.Panels, panelSummary)
 	}
+	summary.PanelCount = len(summary.Panels)
 
 	// Extract variable summaries
-	if templating := safeObject(db, "templating"); templating != nil {
-		if list := safeArray(templating, "list"); list != nil {
-			for _, v := range list {
-				if variable, ok := v.(map[string]interface{}); ok {
-					summary.Variables = append(summary.Variables, extractVariableSummary(variable))
-				}
-			}
-		}
+	for _, variable := range dashboardVariableList(db) {
+		summary.Variables = append(summary.Variables, extractVariableSummary(variable))
 	}
 
 	return summary, nil
@@ -365,7 +437,7 @@ func getDashboardSummary(ctx context.Context, args GetDashboardSummaryParams) (*
 
 var GetDashboardSummary = mcpgrafana.MustTool(
 	"get_dashboard_summary",
-	"Get a compact summary of a dashboard including title\\, panel count\\, panel types\\, variables\\, and other metadata without the full JSON. Use this for dashboard overview and planning modifications without consuming large context windows.",
+	"Get a compact summary of a dashboard including title\\, panel count\\, panel types\\, variables\\, and other metadata without the full JSON. Every panel is listed with its row and its JSONPath for patch operations\\, including panels in collapsed rows and the elements of v2 dashboards. Use this for dashboard overview and planning modifications without consuming large context windows.",
 	getDashboardSummary,
 	mcp.WithTitleAnnotation("Get dashboard summary"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -424,6 +496,7 @@ func (s JSONPathSegment) String() string {
 // parseJSONPath parses a JSONPath string into segments
 // Supports paths like "panels[0].targets[1].expr", "title", "templating.list[0].name"
 // Also supports append syntax: "panels/-" or "panels[2]/-"
+// and quoted keys as used by v2 dashboards: elements["panel-1"].spec
 func parseJSONPath(path string) []JSONPathSegment {
 	var segments []JSONPathSegment
 
@@ -434,19 +507,20 @@ func parseJSONPath(path string) []JSONPathSegment {
 
 	// Enhanced regex to handle /- append syntax
 	// Matches: key, key[index], key/-, key[index]/-
-	re := regexp.MustCompile(`([^.\[\]\/]+)(?:\[(\d+)\])?(?:(\/-))?`)
+	re := regexp.MustCompile(`(?:\[(?:"([^"]+)"|'([^']+)')\]|([^.\[\]\/]+))(?:\[(\d+)\])?(?:(\/-))?`)
 	matches := re.FindAllStringSubmatch(path, -1)
 
 	for _, match := range matches {
-		if len(match) >= 2 && match[1] != "" {
+		key := match[1] + match[2] + match[3]
+		if key != "" {
 			segment := JSONPathSegment{
-				Key:      match[1],
-				IsArray:  len(match) >= 3 && match[2] != "",
-				IsAppend: len(match) >= 4 && match[3] == "/-",
+				Key:      key,
+				IsArray:  match[4] != "",
+				IsAppend: match[5] == "/-",
 			}
 
 			if segment.IsArray && !segment.IsAppend {
-				if index, err := strconv.Atoi(match[2]); err == nil {
+				if index, err := strconv.Atoi(match[4]); err == nil {
 					segment.Index = index
 				}
 			}
@@ -632,9 +706,205 @@ func extractPanelSummary(panel map[string]interface{}) PanelSummary {
 		summary.QueryCount = len(targets)
 	}
 
+	// Library panel references carry no queries of their own; point to the
+	// library panel so its model can be fetched with get_library_panel.
+	if libraryPanel := safeObject(panel, "libraryPanel"); libraryPanel != nil {
+		summary.LibraryPanelUID = safeString(libraryPanel, "uid")
+		if summary.Title == "" {
+			summary.Title = safeString(libraryPanel, "name")
+		}
+	}
+
 	return summary
 }
 
+// dashboardPanel is a panel found while walking a dashboard, along with the
+// JSONPath that addresses it.
+type dashboardPanel struct {
+	Panel map[string]interface{}
+	Path  string
+	// Row is the title of the row the panel belongs to, if any.
+	Row string
+}
+
+// collectPanels returns every panel in a classic dashboard, including panels
+// nested inside collapsed rows and panels of legacy `rows[]` dashboards. Use
+// collectDashboardPanels to also support v2 dashboards.
+func collectPanels(db map[string]interface{}) []dashboardPanel {
+	var result []dashboardPanel
+	// Panels of an expanded row follow the row panel at the top level.
+	var currentRow string
+	for i, p := range safeArray(db, "panels") {
+		panel, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("$.panels[%d]", i)
+		if safeString(panel, "type") != "row" {
+			result = append(result, dashboardPanel{Panel: panel, Path: path, Row: currentRow})
+			continue
+		}
+		result = append(result, dashboardPanel{Panel: panel, Path: path})
+		rowTitle := safeString(panel, "title")
+		currentRow = rowTitle
+		for j, np := range safeArray(panel, "panels") {
+			if nested, ok := np.(map[string]interface{}); ok {
+				result = append(result, dashboardPanel{
+					Panel: nested,
+					Path:  fmt.Sprintf("%s.panels[%d]", path, j),
+					Row:   rowTitle,
+				})
+			}
+		}
+	}
+	for i, r := range safeArray(db, "rows") {
+		row, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rowTitle := safeString(row, "title")
+		for j, p := range safeArray(row, "panels") {
+			if panel, ok := p.(map[string]interface{}); ok {
+				result = append(result, dashboardPanel{
+					Panel: panel,
+					Path:  fmt.Sprintf("$.rows[%d].panels[%d]", i, j),
+					Row:   rowTitle,
+				})
+			}
+		}
+	}
+	return result
+}
+
+// dashboardQuery is a single panel query found in a dashboard.
+type dashboardQuery struct {
+	PanelID    int
+	PanelTitle string
+	// Path is the JSONPath of the query target.
+	Path string
+	// Query is the query text (expr, rawSql or query field), if any.
+	Query      string
+	Datasource datasourceInfo
+	Target     map[string]interface{}
+}
+
+// extractDashboardQueries returns every panel target of a classic or v2
+// dashboard. Target datasources default to the panel datasource, and
+// datasource template variables are resolved to their current value.
+func extractDashboardQueries(db map[string]interface{}) []dashboardQuery {
+	variables := datasourceVariables(db)
+	var result []dashboardQuery
+	for _, p := range collectDashboardPanels(db) {
+		if isDashboardV2Element(p.Panel) {
+			result = append(result, extractV2PanelQueries(p, variables)...)
+			continue
+		}
+		result = append(result, extractPanelQueries(p, variables)...)
+	}
+	return result
+}
+
+// extractPanelQueries returns the targets of a single panel. See
+// extractDashboardQueries.
+func extractPanelQueries(p dashboardPanel, variables map[string]datasourceInfo) []dashboardQuery {
+	var result []dashboardQuery
+	panelDS := parseDatasourceRef(p.Panel["datasource"])
+	for i, t := range safeArray(p.Panel, "targets") {
+		target, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ds := panelDS
+		if targetDS, ok := target["datasource"]; ok && targetDS != nil {
+			ds = parseDatasourceRef(targetDS)
+			if ds.Type == "" && ds.UID == panelDS.UID {
+				ds.Type = panelDS.Type
+			}
+		}
+		result = append(result, dashboardQuery{
+			PanelID:    safeInt(p.Panel, "id"),
+			PanelTitle: safeString(p.Panel, "title"),
+			Path:       fmt.Sprintf("%s.targets[%d]", p.Path, i),
+			Query:      targetQueryText(target),
+			Datasource: resolveDatasource(ds, variables),
+			Target:     target,
+		})
+	}
+	return result
+}
+
+// targetQueryText returns the query text of a panel target.
+func targetQueryText(target map[string]interface{}) string {
+	for _, key := range []string{"expr", "rawSql", "query"} {
+		if q := safeString(target, key); q != "" {
+			return q
+		}
+	}
+	return ""
+}
+
+// parseDatasourceRef extracts the UID and type from a panel or target
+// datasource field. Legacy dashboards reference datasources by name as a plain
+// string, in which case the name is returned as the UID.
+func parseDatasourceRef(v interface{}) datasourceInfo {
+	switch ds := v.(type) {
+	case map[string]interface{}:
+		return datasourceInfo{UID: safeString(ds, "uid"), Type: safeString(ds, "type")}
+	case string:
+		return datasourceInfo{UID: ds}
+	}
+	return datasourceInfo{}
+}
+
+// isTemplateVariable reports whether s is a dashboard template variable reference.
+func isTemplateVariable(s string) bool {
+	return strings.HasPrefix(s, "$") || strings.HasPrefix(s, "[[")
+}
+
+// datasourceVariables maps datasource template variable names to the plugin
+// type they select (as Type) and their current value (as UID).
+func datasourceVariables(db map[string]interface{}) map[string]datasourceInfo {
+	result := map[string]datasourceInfo{}
+	for _, variable := range dashboardVariableList(db) {
+		if safeString(variable, "type") != "datasource" {
+			continue
+		}
+		info := datasourceInfo{Type: safeString(variable, "query")}
+		switch current := safeObject(variable, "current")["value"].(type) {
+		case string:
+			info.UID = current
+		case []interface{}:
+			if len(current) > 0 {
+				info.UID, _ = current[0].(string)
+			}
+		}
+		result[safeString(variable, "name")] = info
+	}
+	return result
+}
+
+// resolveDatasource follows a datasource template variable reference to the
+// datasource it currently selects. Non-variable references are returned as is.
+func resolveDatasource(ds datasourceInfo, variables map[string]datasourceInfo) datasourceInfo {
+	if !isTemplateVariable(ds.UID) {
+		return ds
+	}
+	name := strings.Trim(ds.UID, "${}[]")
+	name, _, _ = strings.Cut(name, ":")
+	variable, ok := variables[name]
+	if !ok {
+		return ds
+	}
+	resolved := ds
+	if variable.UID != "" && !isTemplateVariable(variable.UID) {
+		resolved.UID = variable.UID
+	}
+	if resolved.Type == "" {
+		resolved.Type = variable.Type
+	}
+	return resolved
+}
+
 // extractVariableSummary creates a variable summary from variable data
 func extractVariableSummary(variable map[string]interface{}) VariableSummary {
 	return VariableSummary{
@@ -648,8 +918,28 @@ func AddDashboardTools(mcp *server.MCPServer, enableWriteTools bool) {
 	GetDashboardByUID.Register(mcp)
 	if enableWriteTools {
 		UpdateDashboard.Register(mcp)
+		CreateDashboardFromSpec.Register(mcp)
+		DeleteDashboard.Register(mcp)
+		MoveDashboards.Register(mcp)
+		CloneDashboard.Register(mcp)
+		CreateLibraryPanel.Register(mcp)
+		UpdateLibraryPanel.Register(mcp)
+		ImportDashboards.Register(mcp)
+		CreateDashboardSnapshot.Register(mcp)
+		DeleteSnapshot.Register(mcp)
+		SetPublicDashboard.Register(mcp)
 	}
 	GetDashboardPanelQueries.Register(mcp)
 	GetDashboardProperty.Register(mcp)
 	GetDashboardSummary.Register(mcp)
+	LintDashboard.Register(mcp)
+	FindDependents.Register(mcp)
+	CheckDashboardHealth.Register(mcp)
+	GenerateDashboard.Register(mcp)
+	ListLibraryPanels.Register(mcp)
+	GetLibraryPanel.Register(mcp)
+	ExportDashboards.Register(mcp)
+	ListSnapshots.Register(mcp)
+	GetPublicDashboard.Register(mcp)
+	ListPublicDashboards.Register(mcp)
 }
@@ -0,0 +1,614 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/client/folders"
+	"github.com/grafana/grafana-openapi-client-go/client/library_elements"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// dashboardBundleVersion is the version of the bundle format produced by
+// export_dashboards.
+const dashboardBundleVersion = 1
+
+// inputNameSanitizer matches characters that are not allowed in input names.
+var inputNameSanitizer = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// DashboardInput is a placeholder for a datasource in an exported dashboard,
+// in the same format as Grafana's "Export for sharing externally".
+type DashboardInput struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	PluginID    string `json:"pluginId"`
+	PluginName  string `json:"pluginName"`
+}
+
+// BundleFolder is a folder included in a dashboard bundle.
+type BundleFolder struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+// BundleDashboard is a portable dashboard in a bundle. The dashboard JSON
+// contains an `__inputs` list and references datasources as ${DS_NAME}.
+type BundleDashboard struct {
+	FolderUID string                 `json:"folderUid,omitempty"`
+	Dashboard map[string]interface{} `json:"dashboard"`
+}
+
+// DashboardBundle is a set of portable dashboards and their folders.
+type DashboardBundle struct {
+	BundleVersion int               `json:"bundleVersion"`
+	Folders       []BundleFolder    `json:"folders,omitempty"`
+	Dashboards    []BundleDashboard `json:"dashboards"`
+	// Warnings lists datasource references that could not be made portable.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type ExportDashboardsParams struct {
+	UID           string `json:"uid,omitempty" jsonschema:"description=UID of the dashboard to export. Either uid or folderUid is required."`
+	FolderUID     string `json:"folderUid,omitempty" jsonschema:"description=Export every dashboard in this folder"`
+	MaxDashboards int    `json:"maxDashboards,omitempty" jsonschema:"default=100,description=Maximum number of dashboards to export from a folder"`
+}
+
+// datasourceIndex looks up datasources by UID or name.
+type datasourceIndex map[string]*models.DataSourceListItemDTO
+
+func newDatasourceIndex(ctx context.Context) (datasourceIndex, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("list datasources: %w", err)
+	}
+	return indexDatasources(resp.Payload), nil
+}
+
+// indexDatasources indexes datasources by UID and by name, since legacy
+// dashboards reference datasources by name. UIDs take precedence over names.
+func indexDatasources(datasources []*models.DataSourceListItemDTO) datasourceIndex {
+	index := datasourceIndex{}
+	for _, ds := range datasources {
+		index[ds.UID] = ds
+	}
+	for _, ds := range datasources {
+		if _, exists := index[ds.Name]; !exists {
+			index[ds.Name] = ds
+		}
+	}
+	return index
+}
+
+func datasourceInputName(ds *models.DataSourceListItemDTO) string {
+	return "DS_" + strings.Trim(inputNameSanitizer.ReplaceAllString(strings.ToUpper(ds.Name), "_"), "_")
+}
+
+// dashboardExporter turns datasource references into `__inputs` placeholders.
+type dashboardExporter struct {
+	datasources datasourceIndex
+	inputs      map[string]DashboardInput
+	warnings    map[string]bool
+	// inputNames maps datasource UIDs to their input name so that each
+	// datasource keeps the same name across the bundle.
+	inputNames map[string]string
+	usedNames  map[string]bool
+	// libraryPanels caches the JSON models of library panels by UID. Each
+	// dashboard gets its own copy since datasource references are replaced
+	// in place.
+	libraryPanels map[string][]byte
+}
+
+func newDashboardExporter(datasources datasourceIndex) *dashboardExporter {
+	return &dashboardExporter{
+		datasources:   datasources,
+		warnings:      map[string]bool{},
+		inputNames:    map[string]string{},
+		usedNames:     map[string]bool{},
+		libraryPanels: map[string][]byte{},
+	}
+}
+
+// inputName returns the input name of a datasource. Datasources whose names
+// sanitize to the same input name get a numeric suffix.
+func (e *dashboardExporter) inputName(ds *models.DataSourceListItemDTO) string {
+	if name, ok := e.inputNames[ds.UID]; ok {
+		return name
+	}
+	base := datasourceInputName(ds)
+	name := base
+	for i := 1; e.usedNames[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	e.inputNames[ds.UID] = name
+	e.usedNames[name] = true
+	return name
+}
+
+// exportDashboard makes a dashboard portable: instance-specific fields are
+// removed, library panels are embedded in `__elements`, datasource variable
+// selections are cleared and concrete datasource references are replaced
+// with ${DS_NAME} placeholders declared in `__inputs`.
+func (e *dashboardExporter) exportDashboard(ctx context.Context, db map[string]interface{}) map[string]interface{} {
+	e.inputs = map[string]DashboardInput{}
+	delete(db, "id")
+	if elements := e.libraryPanelElements(ctx, db); len(elements) > 0 {
+		db["__elements"] = elements
+	}
+	clearDatasourceVariables(db)
+	e.replaceRefs(db)
+
+	inputs := make([]DashboardInput, 0, len(e.inputs))
+	for _, input := range e.inputs {
+		inputs = append(inputs, input)
+	}
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+
+	requires := []map[string]string{}
+	seen := map[string]bool{}
+	for _, input := range inputs {
+		if !seen["datasource/"+input.PluginID] {
+			seen["datasource/"+input.PluginID] = true
+			requires = append(requires, map[string]string{"type": "datasource", "id": input.PluginID, "name": input.PluginName})
+		}
+	}
+	for _, p := range collectPanels(db) {
+		panelType := safeString(p.Panel, "type")
+		if panelType != "" && panelType != "row" && !seen["panel/"+panelType] {
+			seen["panel/"+panelType] = true
+			requires = append(requires, map[string]string{"type": "panel", "id": panelType, "name": panelType})
+		}
+	}
+
+	db["__inputs"] = inputs
+	db["__requires"] = requires
+	return db
+}
+
+// libraryPanelElements returns the library panels used by a dashboard in the
+// `__elements` format of Grafana's "Export for sharing externally".
+func (e *dashboardExporter) libraryPanelElements(ctx context.Context, db map[string]interface{}) map[string]interface{} {
+	elements := map[string]interface{}{}
+	for _, p := range collectPanels(db) {
+		ref := safeObject(p.Panel, "libraryPanel")
+		uid := safeString(ref, "uid")
+		if uid == "" || elements[uid] != nil {
+			continue
+		}
+		raw, ok := e.libraryPanels[uid]
+		if !ok {
+			element, err := getLibraryPanelByUID(ctx, uid)
+			if err != nil {
+				e.warnings[fmt.Sprintf("library panel %s could not be exported: %v", uid, err)] = true
+				continue
+			}
+			if raw, err = json.Marshal(element.Model); err != nil {
+				e.warnings[fmt.Sprintf("library panel %s could not be exported: %v", uid, err)] = true
+				continue
+			}
+			e.libraryPanels[uid] = raw
+		}
+		var model map[string]interface{}
+		if err := json.Unmarshal(raw, &model); err != nil || model == nil {
+			continue
+		}
+		name := safeString(ref, "name")
+		if name == "" {
+			name = safeString(model, "title")
+		}
+		elements[uid] = map[string]interface{}{
+			"name":  name,
+			"uid":   uid,
+			"kind":  libraryElementKindPanel,
+			"model": model,
+		}
+	}
+	return elements
+}
+
+// clearDatasourceVariables removes the instance-specific selection of
+// datasource template variables.
+func clearDatasourceVariables(db map[string]interface{}) {
+	for _, v := range safeArray(safeObject(db, "templating"), "list") {
+		variable, ok := v.(map[string]interface{})
+		if !ok || safeString(variable, "type") != "datasource" {
+			continue
+		}
+		variable["current"] = map[string]interface{}{}
+		variable["options"] = []interface{}{}
+	}
+}
+
+// replaceRefs replaces datasource references throughout a JSON value. Keys
+// are visited in sorted order so that input names are assigned
+// deterministically.
+func (e *dashboardExporter) replaceRefs(node interface{}) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := n[key]
+			if key == "datasource" {
+				if ref, ok := e.portableRef(value); ok {
+					n[key] = ref
+					continue
+				}
+			}
+			e.replaceRefs(value)
+		}
+	case []interface{}:
+		for _, item := range n {
+			e.replaceRefs(item)
+		}
+	}
+}
+
+// portableRef returns the placeholder form of a concrete datasource reference.
+func (e *dashboardExporter) portableRef(value interface{}) (map[string]interface{}, bool) {
+	ref := parseDatasourceRef(value)
+	if builtinDatasourceUIDs[ref.UID] || isTemplateVariable(ref.UID) || ref.UID == "__expr__" || ref.Type == "datasource" {
+		return nil, false
+	}
+	ds, ok := e.datasources[ref.UID]
+	if !ok {
+		e.warnings[fmt.Sprintf("datasource %s not found; left unchanged", ref.UID)] = true
+		return nil, false
+	}
+	name := e.inputName(ds)
+	e.inputs[name] = DashboardInput{
+		Name:       name,
+		Label:      ds.Name,
+		Type:       "datasource",
+		PluginID:   ds.Type,
+		PluginName: ds.TypeName,
+	}
+	return map[string]interface{}{"type": ds.Type, "uid": "${" + name + "}"}, true
+}
+
+func exportDashboards(ctx context.Context, args ExportDashboardsParams) (*DashboardBundle, error) {
+	if (args.UID == "") == (args.FolderUID == "") {
+		return nil, fmt.Errorf("export dashboards: exactly one of uid or folderUid is required")
+	}
+	maxDashboards := args.MaxDashboards
+	if maxDashboards <= 0 {
+		maxDashboards = 100
+	}
+
+	datasources, err := newDatasourceIndex(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export dashboards: %w", err)
+	}
+	bundle := &DashboardBundle{BundleVersion: dashboardBundleVersion, Dashboards: []BundleDashboard{}}
+	exporter := newDashboardExporter(datasources)
+
+	uids := []string{args.UID}
+	if args.FolderUID != "" {
+		c := mcpgrafana.GrafanaClientFromContext(ctx)
+		folder, err := c.Folders.GetFolderByUID(args.FolderUID)
+		if err != nil {
+			return nil, fmt.Errorf("export dashboards: get folder %s: %w", args.FolderUID, err)
+		}
+		bundle.Folders = append(bundle.Folders, BundleFolder{UID: folder.Payload.UID, Title: folder.Payload.Title})
+
+		hits, truncated, err := searchAllDashboards(ctx, []string{args.FolderUID}, maxDashboards)
+		if err != nil {
+			return nil, fmt.Errorf("export dashboards: %w", err)
+		}
+		if truncated {
+			bundle.Warnings = append(bundle.Warnings, fmt.Sprintf("folder contains more than %d dashboards; only the first %d were exported", maxDashboards, maxDashboards))
+		}
+		uids = uids[:0]
+		for _, hit := range hits {
+			uids = append(uids, hit.UID)
+		}
+	}
+
+	for _, uid := range uids {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			return nil, fmt.Errorf("export dashboards: %w", err)
+		}
+		db, ok := dashboard.Dashboard.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("export dashboards: dashboard %s is not a JSON object", uid)
+		}
+		if isDashboardV2(db) {
+			if args.FolderUID == "" {
+				return nil, fmt.Errorf("export dashboards: dashboard %s uses the v2 schema, which cannot be exported as a bundle", uid)
+			}
+			exporter.warnings[fmt.Sprintf("dashboard %s uses the v2 schema and was skipped", uid)] = true
+			continue
+		}
+		entry := BundleDashboard{Dashboard: exporter.exportDashboard(ctx, db)}
+		if args.FolderUID != "" {
+			entry.FolderUID = args.FolderUID
+		}
+		bundle.Dashboards = append(bundle.Dashboards, entry)
+	}
+
+	for warning := range exporter.warnings {
+		bundle.Warnings = append(bundle.Warnings, warning)
+	}
+	sort.Strings(bundle.Warnings)
+	return bundle, nil
+}
+
+var ExportDashboards = mcpgrafana.MustTool(
+	"export_dashboards",
+	"Export a dashboard\\, or every dashboard in a folder\\, as a portable bundle for moving to another Grafana instance. Datasource references are replaced with ${DS_NAME} placeholders declared in each dashboard's `__inputs` list and library panels are embedded in `__elements` (the same format as Grafana's 'Export for sharing externally'). Datasource variable selections are cleared. v2 dashboards are not supported. Pass the bundle to import_dashboards on the target instance.",
+	exportDashboards,
+	mcp.WithTitleAnnotation("Export dashboards"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ImportDashboardsParams struct {
+	Bundle    *DashboardBundle       `json:"bundle,omitempty" jsonschema:"description=A bundle produced by export_dashboards"`
+	Dashboard map[string]interface{} `json:"dashboard,omitempty" jsonschema:"description=A single exported dashboard with an __inputs list (e.g. from Grafana's 'Export for sharing externally'). Alternative to bundle."`
+	Inputs    map[string]string      `json:"inputs,omitempty" jsonschema:"description=Explicit mapping of input names (e.g. DS_PROMETHEUS) to target datasource UIDs. Inputs that are not listed are matched by datasource type and name."`
+	FolderUID string                 `json:"folderUid,omitempty" jsonschema:"description=Import every dashboard into this folder instead of the folders in the bundle"`
+	Overwrite bool                   `json:"overwrite,omitempty" jsonschema:"description=Overwrite existing dashboards with the same UID"`
+}
+
+func (p ImportDashboardsParams) validate() error {
+	if (p.Bundle == nil) == (p.Dashboard == nil) {
+		return fmt.Errorf("exactly one of bundle or dashboard is required")
+	}
+	return nil
+}
+
+// ImportedDashboard is the outcome of importing a single dashboard.
+type ImportedDashboard struct {
+	UID       string            `json:"uid,omitempty"`
+	Title     string            `json:"title"`
+	FolderUID string            `json:"folderUid,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Inputs    map[string]string `json:"inputs,omitempty"`
+	// LibraryPanelsCreated lists the UIDs of library panels from `__elements`
+	// that did not exist on this instance yet.
+	LibraryPanelsCreated []string `json:"libraryPanelsCreated,omitempty"`
+	Error                string   `json:"error,omitempty"`
+}
+
+// ImportDashboardsResult is the result of importing a bundle.
+type ImportDashboardsResult struct {
+	Dashboards     []ImportedDashboard `json:"dashboards"`
+	FoldersCreated []string            `json:"foldersCreated,omitempty"`
+}
+
+// resolveInput picks the target datasource for an input: an explicit mapping
+// wins, then a datasource of the same type and name, then the only or
+// default datasource of that type.
+func resolveInput(input DashboardInput, explicit map[string]string, datasources []*models.DataSourceListItemDTO) (string, error) {
+	if uid, ok := explicit[input.Name]; ok {
+		return uid, nil
+	}
+	var candidates []*models.DataSourceListItemDTO
+	for _, ds := range datasources {
+		if ds.Type == input.PluginID {
+			candidates = append(candidates, ds)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no %s datasource found for input %s", input.PluginID, input.Name)
+	case 1:
+		return candidates[0].UID, nil
+	}
+	for _, ds := range candidates {
+		if ds.Name == input.Label {
+			return ds.UID, nil
+		}
+	}
+	for _, ds := range candidates {
+		if ds.IsDefault {
+			return ds.UID, nil
+		}
+	}
+	return "", fmt.Errorf("%d %s datasources match input %s; specify one in inputs", len(candidates), input.PluginID, input.Name)
+}
+
+// applyInputs substitutes ${INPUT} placeholders throughout a dashboard and
+// removes the export-only fields. The library panels in `__elements` are
+// returned separately, with their placeholders substituted too.
+func applyInputs(db map[string]interface{}, values map[string]string) (map[string]interface{}, map[string]interface{}, error) {
+	delete(db, "__inputs")
+	delete(db, "__requires")
+	delete(db, "id")
+	raw, err := json.Marshal(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := string(raw)
+	for name, value := range values {
+		s = strings.ReplaceAll(s, "${"+name+"}", value)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &result); err != nil {
+		return nil, nil, err
+	}
+	elements := safeObject(result, "__elements")
+	delete(result, "__elements")
+	return result, elements, nil
+}
+
+// importLibraryPanels creates the library panels from `__elements` that do
+// not exist yet and returns their UIDs. Existing library panels are left
+// unchanged.
+func importLibraryPanels(ctx context.Context, elements map[string]interface{}, folderUID string) ([]string, error) {
+	uids := make([]string, 0, len(elements))
+	for uid := range elements {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var created []string
+	for _, uid := range uids {
+		element, ok := elements[uid].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, err := getLibraryPanelByUID(ctx, uid); err == nil {
+			continue
+		} else if notFound := (*library_elements.GetLibraryElementByUIDNotFound)(nil); !errors.As(err, &notFound) {
+			return created, err
+		}
+		_, err := c.LibraryElements.CreateLibraryElement(&models.CreateLibraryElementCommand{
+			FolderUID: folderUID,
+			Kind:      libraryElementKindPanel,
+			Model:     element["model"],
+			Name:      safeString(element, "name"),
+			UID:       uid,
+		})
+		if err != nil {
+			return created, fmt.Errorf("create library panel %s: %w", uid, err)
+		}
+		created = append(created, uid)
+	}
+	return created, nil
+}
+
+func dashboardInputs(db map[string]interface{}) ([]DashboardInput, error) {
+	raw, err := json.Marshal(db["__inputs"])
+	if err != nil {
+		return nil, err
+	}
+	var inputs []DashboardInput
+	if err := json.Unmarshal(raw, &inputs); err != nil {
+		return nil, fmt.Errorf("invalid __inputs: %w", err)
+	}
+	return inputs, nil
+}
+
+// ensureFolder creates a bundle folder on the target instance if it does not
+// exist yet. It returns true if the folder was created.
+func ensureFolder(ctx context.Context, folder BundleFolder) (bool, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.Folders.GetFolderByUID(folder.UID); err == nil {
+		return false, nil
+	} else if notFound := (*folders.GetFolderByUIDNotFound)(nil); !errors.As(err, &notFound) {
+		return false, fmt.Errorf("get folder %s: %w", folder.UID, err)
+	}
+	if _, err := createFolder(ctx, CreateFolderParams{Title: folder.Title, UID: folder.UID}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func importDashboards(ctx context.Context, args ImportDashboardsParams) (*ImportDashboardsResult, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("import dashboards: %w", err)
+	}
+	bundle := args.Bundle
+	if bundle == nil {
+		bundle = &DashboardBundle{BundleVersion: dashboardBundleVersion, Dashboards: []BundleDashboard{{Dashboard: args.Dashboard}}}
+	}
+	if bundle.BundleVersion > dashboardBundleVersion {
+		return nil, fmt.Errorf("import dashboards: unsupported bundle version %d", bundle.BundleVersion)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("import dashboards: list datasources: %w", err)
+	}
+	datasources := resp.Payload
+
+	result := &ImportDashboardsResult{Dashboards: []ImportedDashboard{}}
+	if args.FolderUID == "" {
+		for _, folder := range bundle.Folders {
+			created, err := ensureFolder(ctx, folder)
+			if err != nil {
+				return nil, fmt.Errorf("import dashboards: %w", err)
+			}
+			if created {
+				result.FoldersCreated = append(result.FoldersCreated, folder.UID)
+			}
+		}
+	}
+
+	for _, entry := range bundle.Dashboards {
+		imported := ImportedDashboard{Title: safeString(entry.Dashboard, "title"), FolderUID: entry.FolderUID}
+		if args.FolderUID != "" {
+			imported.FolderUID = args.FolderUID
+		}
+		if err := importBundleDashboard(ctx, entry.Dashboard, args, datasources, &imported); err != nil {
+			imported.Error = err.Error()
+		}
+		result.Dashboards = append(result.Dashboards, imported)
+	}
+	return result, nil
+}
+
+func importBundleDashboard(ctx context.Context, db map[string]interface{}, args ImportDashboardsParams, datasources []*models.DataSourceListItemDTO, imported *ImportedDashboard) error {
+	if db == nil {
+		return fmt.Errorf("dashboard is empty")
+	}
+	inputs, err := dashboardInputs(db)
+	if err != nil {
+		return err
+	}
+	values := map[string]string{}
+	for _, input := range inputs {
+		if input.Type != "datasource" {
+			continue
+		}
+		uid, err := resolveInput(input, args.Inputs, datasources)
+		if err != nil {
+			return err
+		}
+		values[input.Name] = uid
+	}
+	imported.Inputs = values
+
+	dashboard, elements, err := applyInputs(db, values)
+	if err != nil {
+		return fmt.Errorf("apply inputs: %w", err)
+	}
+	// Library panels must exist before the dashboard that references them
+	// is saved.
+	imported.LibraryPanelsCreated, err = importLibraryPanels(ctx, elements, imported.FolderUID)
+	if err != nil {
+		return err
+	}
+	saved, err := updateDashboardWithFullJSON(ctx, UpdateDashboardParams{
+		Dashboard: dashboard,
+		FolderUID: imported.FolderUID,
+		Message:   "Imported dashboard",
+		Overwrite: args.Overwrite,
+	})
+	if err != nil {
+		return err
+	}
+	if saved.UID != nil {
+		imported.UID = *saved.UID
+	}
+	if saved.URL != nil {
+		imported.URL = *saved.URL
+	}
+	return nil
+}
+
+var ImportDashboards = mcpgrafana.MustTool(
+	"import_dashboards",
+	"Import a bundle produced by export_dashboards\\, or a single dashboard exported with `__inputs`. Datasource inputs are mapped to datasources on this instance: explicit mappings first\\, then a datasource with the same type and name\\, then the only or default datasource of that type. Folders in the bundle and library panels in `__elements` are created if missing. Returns the outcome and input mapping of each dashboard.",
+	importDashboards,
+	mcp.WithTitleAnnotation("Import dashboards"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
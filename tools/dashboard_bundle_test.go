@@ -0,0 +1,202 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardExporter(t *testing.T) {
+	libraryRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/library-elements/lib-1":
+			libraryRequests++
+			_, _ = w.Write([]byte(`{"result": {"uid": "lib-1", "name": "Shared", "model": {"type": "stat", "title": "Shared",
+				"datasource": {"uid": "prom-2", "type": "prometheus"}, "targets": [{"expr": "up"}]}}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	exporter := newDashboardExporter(datasourceIndex{
+		"prom-1":      {UID: "prom-1", Name: "Prometheus (prod)", Type: "prometheus", TypeName: "Prometheus"},
+		"prom-2":      {UID: "prom-2", Name: "Prometheus prod", Type: "prometheus", TypeName: "Prometheus"},
+		"Legacy Loki": {UID: "loki-1", Name: "Legacy Loki", Type: "loki", TypeName: "Loki"},
+	})
+	dashboardJSON := `{"id": 12, "uid": "db-1", "title": "API",
+		"templating": {"list": [{"name": "job", "type": "query", "datasource": {"uid": "prom-1"}},
+			{"name": "ds", "type": "datasource", "query": "prometheus", "current": {"text": "Prometheus (prod)", "value": "prom-1"}, "options": [{"value": "prom-1"}]}]},
+		"panels": [
+			{"type": "timeseries", "datasource": {"uid": "prom-1", "type": "prometheus"}, "targets": [{"datasource": {"uid": "prom-1"}}]},
+			{"type": "logs", "datasource": "Legacy Loki"},
+			{"type": "stat", "datasource": {"uid": "$datasource"}},
+			{"type": "text", "datasource": {"uid": "gone"}},
+			{"id": 5, "gridPos": {"x": 0, "y": 8, "w": 12, "h": 8}, "libraryPanel": {"uid": "lib-1", "name": "Shared"}}
+		]}`
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(dashboardJSON), &db))
+
+	exported := exporter.exportDashboard(ctx, db)
+	assert.NotContains(t, exported, "id")
+	assert.Equal(t, "db-1", exported["uid"])
+	// "Prometheus (prod)" sanitizes to the same name as "Prometheus prod",
+	// which is found first in the library panel.
+	assert.Equal(t, []DashboardInput{
+		{Name: "DS_LEGACY_LOKI", Label: "Legacy Loki", Type: "datasource", PluginID: "loki", PluginName: "Loki"},
+		{Name: "DS_PROMETHEUS_PROD", Label: "Prometheus prod", Type: "datasource", PluginID: "prometheus", PluginName: "Prometheus"},
+		{Name: "DS_PROMETHEUS_PROD_1", Label: "Prometheus (prod)", Type: "datasource", PluginID: "prometheus", PluginName: "Prometheus"},
+	}, exported["__inputs"])
+
+	element := exported["__elements"].(map[string]interface{})["lib-1"].(map[string]interface{})
+	assert.Equal(t, "Shared", element["name"])
+	assert.Equal(t, map[string]interface{}{"type": "prometheus", "uid": "${DS_PROMETHEUS_PROD}"}, element["model"].(map[string]interface{})["datasource"])
+
+	dsVar := exported["templating"].(map[string]interface{})["list"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{}, dsVar["current"])
+	assert.Empty(t, dsVar["options"])
+
+	out, err := json.Marshal(exported)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), `"prom-1"`)
+	assert.Contains(t, string(out), `{"type":"loki","uid":"${DS_LEGACY_LOKI}"}`)
+	assert.Contains(t, string(out), `"uid":"$datasource"`)
+	assert.Contains(t, string(out), `"uid":"gone"`)
+	assert.True(t, exporter.warnings["datasource gone not found; left unchanged"])
+
+	requires := exported["__requires"].([]map[string]string)
+	assert.Contains(t, requires, map[string]string{"type": "panel", "id": "timeseries", "name": "timeseries"})
+	assert.Contains(t, requires, map[string]string{"type": "datasource", "id": "loki", "name": "Loki"})
+
+	// Input names and library panel models are reused across dashboards.
+	require.NoError(t, json.Unmarshal([]byte(dashboardJSON), &db))
+	exported = exporter.exportDashboard(ctx, db)
+	assert.Len(t, exported["__inputs"], 3)
+	element = exported["__elements"].(map[string]interface{})["lib-1"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "prometheus", "uid": "${DS_PROMETHEUS_PROD}"}, element["model"].(map[string]interface{})["datasource"])
+	assert.Equal(t, 1, libraryRequests)
+}
+
+func TestResolveInput(t *testing.T) {
+	datasources := []*models.DataSourceListItemDTO{
+		{UID: "a", Name: "Prometheus", Type: "prometheus"},
+		{UID: "b", Name: "Thanos", Type: "prometheus", IsDefault: true},
+		{UID: "c", Name: "Loki", Type: "loki"},
+	}
+	resolve := func(input DashboardInput, explicit map[string]string) (string, error) {
+		return resolveInput(input, explicit, datasources)
+	}
+
+	uid, err := resolve(DashboardInput{Name: "DS_LOGS", Label: "Other", PluginID: "loki"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c", uid, "only datasource of the type")
+
+	uid, err = resolve(DashboardInput{Name: "DS_PROM", Label: "Prometheus", PluginID: "prometheus"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a", uid, "same name")
+
+	uid, err = resolve(DashboardInput{Name: "DS_PROM", Label: "Mimir", PluginID: "prometheus"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "b", uid, "default datasource")
+
+	uid, err = resolve(DashboardInput{Name: "DS_PROM", Label: "Prometheus", PluginID: "prometheus"}, map[string]string{"DS_PROM": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", uid, "explicit mapping")
+
+	_, err = resolve(DashboardInput{Name: "DS_TEMPO", PluginID: "tempo"}, nil)
+	assert.Error(t, err)
+}
+
+func TestImportDashboards(t *testing.T) {
+	var saved []map[string]interface{}
+	var createdFolders []string
+	var createdLibraryPanels []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources":
+			_, _ = w.Write([]byte(`[{"uid": "prom-target", "name": "Prometheus", "type": "prometheus"}]`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders/team":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "folder not found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/folders":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			createdFolders = append(createdFolders, body["uid"].(string))
+			_, _ = w.Write([]byte(`{"uid": "team", "title": "Team"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/library-elements/lib-1":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "library element could not be found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/library-elements":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			createdLibraryPanels = append(createdLibraryPanels, body)
+			_, _ = w.Write([]byte(`{"result": {"uid": "lib-1", "name": "Shared"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			saved = append(saved, body)
+			_, _ = w.Write([]byte(`{"uid": "db-1", "url": "/d/db-1/api", "status": "success"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var dashboard map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"uid": "db-1", "title": "API",
+		"__inputs": [{"name": "DS_PROM", "label": "Prometheus (prod)", "type": "datasource", "pluginId": "prometheus", "pluginName": "Prometheus"}],
+		"__requires": [{"type": "datasource", "id": "prometheus"}],
+		"__elements": {"lib-1": {"uid": "lib-1", "name": "Shared", "kind": 1, "model": {"type": "stat", "datasource": {"type": "prometheus", "uid": "${DS_PROM}"}}}},
+		"panels": [{"datasource": {"type": "prometheus", "uid": "${DS_PROM}"}, "targets": [{"expr": "up"}]},
+			{"id": 2, "libraryPanel": {"uid": "lib-1", "name": "Shared"}}]}`), &dashboard))
+	var broken map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"title": "Traces",
+		"__inputs": [{"name": "DS_TEMPO", "type": "datasource", "pluginId": "tempo"}]}`), &broken))
+
+	result, err := importDashboards(mockCtxWithClient(server), ImportDashboardsParams{
+		Bundle: &DashboardBundle{
+			BundleVersion: 1,
+			Folders:       []BundleFolder{{UID: "team", Title: "Team"}},
+			Dashboards:    []BundleDashboard{{FolderUID: "team", Dashboard: dashboard}, {FolderUID: "team", Dashboard: broken}},
+		},
+		Overwrite: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team"}, result.FoldersCreated)
+	assert.Equal(t, []string{"team"}, createdFolders)
+	require.Len(t, result.Dashboards, 2)
+	assert.Equal(t, "/d/db-1/api", result.Dashboards[0].URL)
+	assert.Equal(t, map[string]string{"DS_PROM": "prom-target"}, result.Dashboards[0].Inputs)
+	assert.Empty(t, result.Dashboards[0].Error)
+	assert.Equal(t, []string{"lib-1"}, result.Dashboards[0].LibraryPanelsCreated)
+	require.Len(t, createdLibraryPanels, 1)
+	assert.Equal(t, "team", createdLibraryPanels[0]["folderUid"])
+	assert.Equal(t, "prom-target", createdLibraryPanels[0]["model"].(map[string]interface{})["datasource"].(map[string]interface{})["uid"])
+	assert.Contains(t, result.Dashboards[1].Error, "no tempo datasource found")
+
+	require.Len(t, saved, 1)
+	assert.Equal(t, "team", saved[0]["folderUid"])
+	assert.Equal(t, true, saved[0]["overwrite"])
+	importedDashboard := saved[0]["dashboard"].(map[string]interface{})
+	assert.NotContains(t, importedDashboard, "__inputs")
+	assert.NotContains(t, importedDashboard, "__requires")
+	assert.NotContains(t, importedDashboard, "__elements")
+	ds := importedDashboard["panels"].([]interface{})[0].(map[string]interface{})["datasource"].(map[string]interface{})
+	assert.Equal(t, "prom-target", ds["uid"])
+
+	_, err = importDashboards(mockCtxWithClient(server), ImportDashboardsParams{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,492 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana-openapi-client-go/client/dashboards"
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultDependencyConcurrency   = 8
+	maxDependencyConcurrency       = 32
+	defaultDependencyMaxDashboards = 1000
+	dependencySearchPageSize       = 1000
+	maxDashboardQueryCacheEntries  = 5000
+)
+
+// streamSelectorRegex matches LogQL stream selectors such as {app="api"}.
+var streamSelectorRegex = regexp.MustCompile(`\{[^{}]*\}`)
+
+// queryReferences are the metric and label names referenced by a query.
+type queryReferences struct {
+	Metrics []string
+	Labels  []string
+	// Parsed is false when the query could not be parsed, in which case
+	// matching falls back to a plain text search.
+	Parsed bool
+}
+
+// extractQueryReferences parses a PromQL or LogQL query and returns the
+// metric and label names it references.
+func extractQueryReferences(expr, dsType string) queryReferences {
+	metrics := map[string]bool{}
+	labelNames := map[string]bool{}
+	addMatchers := func(name string, matchers []*labels.Matcher) {
+		if name != "" && name != "__var__" {
+			metrics[name] = true
+		}
+		for _, m := range matchers {
+			if m.Name == "__name__" {
+				if m.Value != "__var__" {
+					metrics[m.Value] = true
+				}
+				continue
+			}
+			labelNames[m.Name] = true
+		}
+	}
+
+	refs := queryReferences{}
+	interpolated := interpolatePromQLVariables(expr)
+	if strings.Contains(dsType, "loki") {
+		refs.Parsed = true
+		for _, sel := range streamSelectorRegex.FindAllString(interpolated, -1) {
+			matchers, err := parser.ParseMetricSelector(sel)
+			if err != nil {
+				refs.Parsed = false
+				continue
+			}
+			addMatchers("", matchers)
+		}
+	} else if parsed, err := parser.ParseExpr(interpolated); err == nil {
+		refs.Parsed = true
+		parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+			switch n := node.(type) {
+			case *parser.VectorSelector:
+				addMatchers(n.Name, n.LabelMatchers)
+			case *parser.AggregateExpr:
+				for _, l := range n.Grouping {
+					labelNames[l] = true
+				}
+			case *parser.BinaryExpr:
+				if n.VectorMatching != nil {
+					for _, l := range n.VectorMatching.MatchingLabels {
+						labelNames[l] = true
+					}
+					for _, l := range n.VectorMatching.Include {
+						labelNames[l] = true
+					}
+				}
+			}
+			return nil
+		})
+	}
+	delete(labelNames, "__var__")
+
+	refs.Metrics = sortedKeys(metrics)
+	refs.Labels = sortedKeys(labelNames)
+	return refs
+}
+
+func sortedKeys(m map[string]bool) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+type FindDependentsParams struct {
+	Metric         string   `json:"metric,omitempty" jsonschema:"description=Metric name to search for (e.g. 'http_requests_total')"`
+	Label          string   `json:"label,omitempty" jsonschema:"description=Label name to search for in matchers and grouping clauses (e.g. 'cluster')"`
+	DatasourceUID  string   `json:"datasourceUid,omitempty" jsonschema:"description=Datasource UID to search for. Matches direct references and datasource variables whose current value is this datasource."`
+	FolderUIDs     []string `json:"folderUids,omitempty" jsonschema:"description=Only scan dashboards in these folders"`
+	SkipDashboards bool     `json:"skipDashboards,omitempty" jsonschema:"description=Do not scan dashboards"`
+	SkipAlertRules bool     `json:"skipAlertRules,omitempty" jsonschema:"description=Do not scan Grafana-managed alert rules"`
+	MaxDashboards  int      `json:"maxDashboards,omitempty" jsonschema:"default=1000,description=Maximum number of dashboards to scan"`
+	Concurrency    int      `json:"concurrency,omitempty" jsonschema:"default=8,description=Number of dashboards fetched in parallel (max 32)"`
+}
+
+func (p FindDependentsParams) validate() error {
+	if p.Metric == "" && p.Label == "" && p.DatasourceUID == "" {
+		return fmt.Errorf("at least one of metric, label or datasourceUid is required")
+	}
+	if p.MaxDashboards < 0 || p.Concurrency < 0 {
+		return fmt.Errorf("maxDashboards and concurrency must not be negative")
+	}
+	return nil
+}
+
+// DependencyMatch is a dashboard panel query or alert rule query that matches
+// the search criteria.
+type DependencyMatch struct {
+	Kind        string         `json:"kind"`
+	UID         string         `json:"uid"`
+	Title       string         `json:"title"`
+	FolderTitle string         `json:"folderTitle,omitempty"`
+	PanelID     int            `json:"panelId,omitempty"`
+	PanelTitle  string         `json:"panelTitle,omitempty"`
+	Path        string         `json:"path"`
+	Query       string         `json:"query,omitempty"`
+	Datasource  datasourceInfo `json:"datasource"`
+}
+
+// FindDependentsResult is the result of a reverse-dependency search.
+type FindDependentsResult struct {
+	Matches           []DependencyMatch `json:"matches"`
+	DashboardsScanned int               `json:"dashboardsScanned"`
+	AlertRulesScanned int               `json:"alertRulesScanned"`
+	Truncated         bool              `json:"truncated,omitempty"`
+	Errors            []string          `json:"errors,omitempty"`
+}
+
+// indexedQuery is a dashboard query together with its parsed references.
+type indexedQuery struct {
+	dashboardQuery
+	Refs queryReferences
+}
+
+type dashboardQueryCacheEntry struct {
+	version int64
+	queries []indexedQuery
+}
+
+// dashboardQueryCache caches the parsed queries of dashboards keyed by
+// Grafana instance, org and dashboard UID. Entries are only reused while the
+// dashboard version is unchanged.
+type dashboardQueryCache struct {
+	mu      sync.Mutex
+	entries map[string]dashboardQueryCacheEntry
+}
+
+var dependencyQueryCache = &dashboardQueryCache{entries: map[string]dashboardQueryCacheEntry{}}
+
+func (c *dashboardQueryCache) get(key string, version int64) ([]indexedQuery, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.version != version {
+		return nil, false
+	}
+	return entry.queries, true
+}
+
+func (c *dashboardQueryCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key]
+	return ok
+}
+
+func (c *dashboardQueryCache) put(key string, version int64, queries []indexedQuery) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxDashboardQueryCacheEntries {
+		// Evict an arbitrary entry to bound memory usage.
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[key] = dashboardQueryCacheEntry{version: version, queries: queries}
+}
+
+// dependencyMatcher decides whether a query matches the search criteria.
+type dependencyMatcher struct {
+	metric, label string
+	// datasourceRefs are the UID and name of the searched datasource.
+	datasourceRefs map[string]bool
+	metricRegex    *regexp.Regexp
+}
+
+func newDependencyMatcher(args FindDependentsParams, ds *models.DataSourceListItemDTO) *dependencyMatcher {
+	m := &dependencyMatcher{metric: args.Metric, label: args.Label}
+	if args.DatasourceUID != "" {
+		m.datasourceRefs = map[string]bool{args.DatasourceUID: true}
+		if ds != nil && ds.Name != "" {
+			m.datasourceRefs[ds.Name] = true
+		}
+	}
+	if args.Metric != "" {
+		m.metricRegex = regexp.MustCompile(`(^|[^a-zA-Z0-9_:])` + regexp.QuoteMeta(args.Metric) + `($|[^a-zA-Z0-9_:])`)
+	}
+	return m
+}
+
+func (m *dependencyMatcher) matches(query string, ds datasourceInfo, refs queryReferences) bool {
+	if m.datasourceRefs != nil && !m.datasourceRefs[ds.UID] {
+		return false
+	}
+	if m.metric != "" {
+		if refs.Parsed {
+			if !containsString(refs.Metrics, m.metric) {
+				return false
+			}
+		} else if !m.metricRegex.MatchString(query) {
+			return false
+		}
+	}
+	if m.label != "" {
+		if refs.Parsed {
+			if !containsString(refs.Labels, m.label) {
+				return false
+			}
+		} else if !strings.Contains(query, m.label) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func findDependents(ctx context.Context, args FindDependentsParams) (*FindDependentsResult, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("find dependents: %w", err)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	result := &FindDependentsResult{Matches: []DependencyMatch{}}
+
+	// Datasource types are needed to tell PromQL and LogQL queries apart when
+	// panels or alert queries only reference a datasource by UID.
+	dsTypes := map[string]string{}
+	var searched *models.DataSourceListItemDTO
+	if resp, err := c.Datasources.GetDataSources(); err == nil {
+		for _, ds := range resp.Payload {
+			dsTypes[ds.UID] = ds.Type
+			if ds.UID == args.DatasourceUID {
+				searched = ds
+			}
+		}
+	} else {
+		result.Errors = append(result.Errors, fmt.Sprintf("list datasources: %v", err))
+	}
+	matcher := newDependencyMatcher(args, searched)
+
+	if !args.SkipDashboards {
+		if err := findDashboardDependents(ctx, args, matcher, dsTypes, result); err != nil {
+			return nil, err
+		}
+	}
+	if !args.SkipAlertRules {
+		findAlertRuleDependents(ctx, matcher, dsTypes, result)
+	}
+	return result, nil
+}
+
+// searchAllDashboards pages through the search API and returns up to max dashboards.
+func searchAllDashboards(ctx context.Context, folderUIDs []string, max int) ([]*models.Hit, bool, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var hits []*models.Hit
+	for page := int64(1); ; page++ {
+		params := search.NewSearchParamsWithContext(ctx)
+		params.SetType(&dashboardTypeStr)
+		limit := int64(dependencySearchPageSize)
+		params.SetLimit(&limit)
+		params.SetPage(&page)
+		resp, err := c.Search.Search(params, repeatedQueryParams(map[string][]string{"folderUIDs": folderUIDs}))
+		if err != nil {
+			return nil, false, fmt.Errorf("search dashboards: %w", err)
+		}
+		hits = append(hits, resp.Payload...)
+		if len(hits) >= max {
+			return hits[:max], len(hits) > max || len(resp.Payload) == dependencySearchPageSize, nil
+		}
+		if len(resp.Payload) < dependencySearchPageSize {
+			return hits, false, nil
+		}
+	}
+}
+
+func findDashboardDependents(ctx context.Context, args FindDependentsParams, matcher *dependencyMatcher, dsTypes map[string]string, result *FindDependentsResult) error {
+	maxDashboards := args.MaxDashboards
+	if maxDashboards == 0 {
+		maxDashboards = defaultDependencyMaxDashboards
+	}
+	concurrency := args.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultDependencyConcurrency
+	}
+	if concurrency > maxDependencyConcurrency {
+		concurrency = maxDependencyConcurrency
+	}
+
+	hits, truncated, err := searchAllDashboards(ctx, args.FolderUIDs, maxDashboards)
+	if err != nil {
+		return fmt.Errorf("find dependents: %w", err)
+	}
+	result.Truncated = truncated
+
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	for _, hit := range hits {
+		wg.Add(1)
+		go func(hit *models.Hit) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			queries, err := indexDashboardQueries(ctx, cfg, hit.UID, dsTypes)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("dashboard %s: %v", hit.UID, err))
+				return
+			}
+			result.DashboardsScanned++
+			for _, q := range queries {
+				if matcher.matches(q.Query, q.Datasource, q.Refs) {
+					result.Matches = append(result.Matches, DependencyMatch{
+						Kind:        "dashboard",
+						UID:         hit.UID,
+						Title:       hit.Title,
+						FolderTitle: hit.FolderTitle,
+						PanelID:     q.PanelID,
+						PanelTitle:  q.PanelTitle,
+						Path:        q.Path,
+						Query:       q.Query,
+						Datasource:  q.Datasource,
+					})
+				}
+			}
+		}(hit)
+	}
+	wg.Wait()
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		if result.Matches[i].Title != result.Matches[j].Title {
+			return result.Matches[i].Title < result.Matches[j].Title
+		}
+		return result.Matches[i].Path < result.Matches[j].Path
+	})
+	return nil
+}
+
+// latestDashboardVersion returns the current version number of a dashboard
+// from its version history, which is much cheaper than fetching the full model.
+func latestDashboardVersion(ctx context.Context, uid string) (int64, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	limit := int64(1)
+	params := dashboards.NewGetDashboardVersionsByUIDParamsWithContext(ctx).WithUID(uid).WithLimit(&limit)
+	resp, err := c.Dashboards.GetDashboardVersionsByUID(params)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Payload == nil || len(resp.Payload.Versions) == 0 || resp.Payload.Versions[0] == nil {
+		return 0, fmt.Errorf("no versions found")
+	}
+	return resp.Payload.Versions[0].Version, nil
+}
+
+// indexDashboardQueries fetches a dashboard and returns its queries with
+// parsed references. When the dashboard is already cached, its latest version
+// is checked first so unchanged dashboards are not fetched again.
+func indexDashboardQueries(ctx context.Context, cfg mcpgrafana.GrafanaConfig, uid string, dsTypes map[string]string) ([]indexedQuery, error) {
+	key := fmt.Sprintf("%s|%d|%s", cfg.URL, cfg.OrgID, uid)
+	if dependencyQueryCache.has(key) {
+		// Fall back to a full fetch if the version history is unavailable.
+		if version, err := latestDashboardVersion(ctx, uid); err == nil {
+			if queries, ok := dependencyQueryCache.get(key, version); ok {
+				return queries, nil
+			}
+		}
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	var version int64
+	if dashboard.Meta != nil {
+		version = dashboard.Meta.Version
+	}
+
+	db, ok := dashboard.Dashboard.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	var queries []indexedQuery
+	for _, q := range extractDashboardQueries(db) {
+		if q.Datasource.Type == "" {
+			q.Datasource.Type = dsTypes[q.Datasource.UID]
+		}
+		queries = append(queries, indexedQuery{
+			dashboardQuery: q,
+			Refs:           extractQueryReferences(q.Query, q.Datasource.Type),
+		})
+	}
+	dependencyQueryCache.put(key, version, queries)
+	return queries, nil
+}
+
+func findAlertRuleDependents(ctx context.Context, matcher *dependencyMatcher, dsTypes map[string]string, result *FindDependentsResult) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Provisioning.GetAlertRules()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("list alert rules: %v", err))
+		return
+	}
+	for _, rule := range resp.Payload {
+		result.AlertRulesScanned++
+		title := ""
+		if rule.Title != nil {
+			title = *rule.Title
+		}
+		for i, q := range rule.Data {
+			if q == nil {
+				continue
+			}
+			model, _ := q.Model.(map[string]interface{})
+			query := targetQueryText(model)
+			ds := datasourceInfo{UID: q.DatasourceUID, Type: dsTypes[q.DatasourceUID]}
+			if !matcher.matches(query, ds, extractQueryReferences(query, ds.Type)) {
+				continue
+			}
+			result.Matches = append(result.Matches, DependencyMatch{
+				Kind:       "alert_rule",
+				UID:        rule.UID,
+				Title:      title,
+				Path:       fmt.Sprintf("$.data[%d].model", i),
+				Query:      query,
+				Datasource: ds,
+			})
+		}
+	}
+}
+
+var FindDependents = mcpgrafana.MustTool(
+	"find_dependents",
+	"Find dashboards and Grafana-managed alert rules that use a given metric\\, label or datasource. Scans dashboards (optionally limited to folders) and alert rules\\, parses their PromQL/LogQL queries locally and returns each matching panel or rule query with its JSONPath. All provided criteria must match. Useful for migrations and impact analysis\\, e.g. 'which dashboards query http_requests_total' or 'what depends on datasource X'.",
+	findDependents,
+	mcp.WithTitleAnnotation("Find dashboards and alert rules using a metric, label or datasource"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,109 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractQueryReferences(t *testing.T) {
+	refs := extractQueryReferences(`sum by (cluster) (rate(http_requests_total{job="$job",code=~"5.."}[$__rate_interval])) / on (instance) up`, "prometheus")
+	assert.True(t, refs.Parsed)
+	assert.Equal(t, []string{"http_requests_total", "up"}, refs.Metrics)
+	assert.Equal(t, []string{"cluster", "code", "instance", "job"}, refs.Labels)
+
+	refs = extractQueryReferences(`{__name__="node_load1", env="prod"}`, "")
+	assert.Equal(t, []string{"node_load1"}, refs.Metrics)
+	assert.Equal(t, []string{"env"}, refs.Labels)
+
+	refs = extractQueryReferences(`sum(count_over_time({app="api", namespace="$ns"} |= "error" [5m]))`, "loki")
+	assert.True(t, refs.Parsed)
+	assert.Empty(t, refs.Metrics)
+	assert.Equal(t, []string{"app", "namespace"}, refs.Labels)
+
+	refs = extractQueryReferences(`sum(rate(foo[5m])`, "prometheus")
+	assert.False(t, refs.Parsed)
+}
+
+func TestDependencyMatcher(t *testing.T) {
+	m := newDependencyMatcher(FindDependentsParams{Metric: "foo_total", Label: "job"}, nil)
+	refs := extractQueryReferences(`rate(foo_total{job="a"}[5m])`, "prometheus")
+	assert.True(t, m.matches("", datasourceInfo{}, refs))
+	assert.False(t, m.matches("", datasourceInfo{}, extractQueryReferences(`rate(foo_total_bucket{job="a"}[5m])`, "prometheus")))
+
+	// Unparseable queries fall back to a word-boundary text match.
+	assert.True(t, m.matches(`rate(foo_total{job="a"}[5m]`, datasourceInfo{}, queryReferences{}))
+	assert.False(t, m.matches(`rate(foo_total_x{job="a"}[5m]`, datasourceInfo{}, queryReferences{}))
+
+	m = newDependencyMatcher(FindDependentsParams{DatasourceUID: "prom-1"}, nil)
+	assert.True(t, m.matches("up", datasourceInfo{UID: "prom-1"}, queryReferences{}))
+	assert.False(t, m.matches("up", datasourceInfo{UID: "prom-2"}, queryReferences{}))
+}
+
+func TestFindDependents(t *testing.T) {
+	dependencyQueryCache = &dashboardQueryCache{entries: map[string]dashboardQueryCacheEntry{}}
+	dashboards := map[string]string{
+		"db-1": `{"uid": "db-1", "title": "API", "templating": {"list": [{"name": "ds", "type": "datasource", "query": "prometheus", "current": {"value": "prom-1"}}]},
+			"panels": [{"id": 1, "title": "Requests", "datasource": {"uid": "$ds"}, "targets": [{"expr": "sum(rate(http_requests_total[5m]))"}]},
+			{"id": 2, "type": "row", "collapsed": true, "panels": [{"id": 3, "title": "Up", "datasource": {"uid": "prom-2"}, "targets": [{"expr": "up"}]}]}]}`,
+		"db-2": `{"uid": "db-2", "title": "Other", "panels": [{"id": 1, "title": "CPU", "datasource": {"uid": "prom-1"}, "targets": [{"expr": "node_cpu_seconds_total"}]}]}`,
+	}
+
+	var fullFetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/dashboards/uid/db-1/versions" || r.URL.Path == "/api/dashboards/uid/db-2/versions":
+			_, _ = w.Write([]byte(`{"versions": [{"version": 1}]}`))
+		case r.URL.Path == "/api/datasources":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"uid": "prom-1", "name": "Prometheus", "type": "prometheus"}, {"uid": "prom-2", "name": "Other", "type": "prometheus"}})
+		case r.URL.Path == "/api/search":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"uid": "db-1", "title": "API"}, {"uid": "db-2", "title": "Other"}})
+		case r.URL.Path == "/api/dashboards/uid/db-1" || r.URL.Path == "/api/dashboards/uid/db-2":
+			fullFetches.Add(1)
+			uid := r.URL.Path[len("/api/dashboards/uid/"):]
+			_, _ = w.Write([]byte(`{"meta": {"version": 1}, "dashboard": ` + dashboards[uid] + `}`))
+		case r.URL.Path == "/api/v1/provisioning/alert-rules":
+			_, _ = w.Write([]byte(`[{"uid": "rule-1", "title": "High error rate", "data": [
+				{"refId": "A", "datasourceUid": "prom-1", "model": {"expr": "rate(http_requests_total{code=\"500\"}[5m])"}},
+				{"refId": "B", "datasourceUid": "__expr__", "model": {"expression": "A"}}]}]`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	result, err := findDependents(ctx, FindDependentsParams{Metric: "http_requests_total"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, 2, result.DashboardsScanned)
+	assert.Equal(t, 1, result.AlertRulesScanned)
+	require.Len(t, result.Matches, 2)
+	assert.Equal(t, "alert_rule", result.Matches[1].Kind)
+	assert.Equal(t, "$.data[0].model", result.Matches[1].Path)
+	assert.Equal(t, "dashboard", result.Matches[0].Kind)
+	assert.Equal(t, "$.panels[0].targets[0]", result.Matches[0].Path)
+	assert.Equal(t, "prom-1", result.Matches[0].Datasource.UID)
+
+	assert.Equal(t, int32(2), fullFetches.Load())
+
+	// Cached dashboards whose version is unchanged are not fetched again.
+	result, err = findDependents(ctx, FindDependentsParams{DatasourceUID: "prom-2", SkipAlertRules: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fullFetches.Load())
+	require.Len(t, result.Matches, 1)
+	assert.Equal(t, "$.panels[1].panels[0].targets[0]", result.Matches[0].Path)
+	assert.Equal(t, "up", result.Matches[0].Query)
+
+	_, err = findDependents(ctx, FindDependentsParams{})
+	assert.Error(t, err)
+}
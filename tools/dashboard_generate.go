@@ -0,0 +1,537 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	dashboardGridWidth     = 24
+	defaultDatasourceVar   = "datasource"
+	defaultDashboardFrom   = "now-6h"
+	defaultDashboardTo     = "now"
+	dashboardSchemaVersion = 39
+)
+
+// defaultPanelSizes are the default width and height of generated panels by type.
+var defaultPanelSizes = map[string][2]int{
+	"stat":     {6, 4},
+	"gauge":    {6, 4},
+	"bargauge": {6, 4},
+	"text":     {24, 4},
+	"logs":     {24, 10},
+	"table":    {24, 8},
+}
+
+// DashboardDatasourceRef references a datasource by UID and plugin type.
+type DashboardDatasourceRef struct {
+	UID  string `json:"uid,omitempty" jsonschema:"description=The datasource UID or a template variable such as '${datasource}'"`
+	Type string `json:"type,omitempty" jsonschema:"description=The datasource plugin type (e.g. 'prometheus'\\, 'loki'\\, 'grafana-postgresql-datasource')"`
+}
+
+// DashboardVariableSpec describes a dashboard template variable.
+type DashboardVariableSpec struct {
+	Name       string                  `json:"name" jsonschema:"required,description=The variable name"`
+	Type       string                  `json:"type,omitempty" jsonschema:"default=query,enum=query,enum=custom,enum=datasource,enum=interval,enum=constant,enum=textbox,description=The variable type"`
+	Label      string                  `json:"label,omitempty" jsonschema:"description=The label displayed in the variable picker"`
+	Query      string                  `json:"query,omitempty" jsonschema:"description=The variable query (e.g. 'label_values(up\\, job)'). For datasource variables this is the plugin type."`
+	Datasource *DashboardDatasourceRef `json:"datasource,omitempty" jsonschema:"description=Datasource for query variables. Defaults to the dashboard datasource."`
+	Options    []string                `json:"options,omitempty" jsonschema:"description=Values for custom and interval variables"`
+	Multi      bool                    `json:"multi,omitempty" jsonschema:"description=Allow selecting multiple values"`
+	IncludeAll bool                    `json:"includeAll,omitempty" jsonschema:"description=Add an 'All' option"`
+}
+
+// DashboardThresholdSpec is a single threshold step.
+type DashboardThresholdSpec struct {
+	Value float64 `json:"value" jsonschema:"required,description=The threshold value"`
+	Color string  `json:"color" jsonschema:"required,description=The color used at and above the value (e.g. 'orange'\\, 'red'\\, '#FF0000')"`
+}
+
+// DashboardQuerySpec describes a single panel query.
+type DashboardQuerySpec struct {
+	RefID        string                 `json:"refId,omitempty" jsonschema:"description=The query ref ID. Defaults to A\\, B\\, C..."`
+	Expr         string                 `json:"expr,omitempty" jsonschema:"description=PromQL or LogQL expression"`
+	RawSQL       string                 `json:"rawSql,omitempty" jsonschema:"description=SQL query for SQL datasources"`
+	LegendFormat string                 `json:"legendFormat,omitempty" jsonschema:"description=Legend format (e.g. '{{instance}}')"`
+	Model        map[string]interface{} `json:"model,omitempty" jsonschema:"description=Additional datasource-specific query fields merged into the target"`
+}
+
+// DashboardPanelSpec describes a panel in a generated dashboard.
+type DashboardPanelSpec struct {
+	Title       string                   `json:"title" jsonschema:"required,description=The panel title"`
+	Type        string                   `json:"type,omitempty" jsonschema:"default=timeseries,description=The panel type (e.g. 'timeseries'\\, 'stat'\\, 'gauge'\\, 'table'\\, 'logs'\\, 'text')"`
+	Description string                   `json:"description,omitempty" jsonschema:"description=The panel description"`
+	Datasource  *DashboardDatasourceRef  `json:"datasource,omitempty" jsonschema:"description=Overrides the dashboard datasource for this panel"`
+	Queries     []DashboardQuerySpec     `json:"queries,omitempty" jsonschema:"description=The panel queries"`
+	Unit        string                   `json:"unit,omitempty" jsonschema:"description=The display unit (e.g. 'reqps'\\, 's'\\, 'percent'\\, 'bytes')"`
+	Decimals    *int                     `json:"decimals,omitempty" jsonschema:"description=Number of decimals to display"`
+	Min         *float64                 `json:"min,omitempty" jsonschema:"description=Minimum value of the axis or gauge"`
+	Max         *float64                 `json:"max,omitempty" jsonschema:"description=Maximum value of the axis or gauge"`
+	Thresholds  []DashboardThresholdSpec `json:"thresholds,omitempty" jsonschema:"description=Threshold steps above the base (green) step"`
+	Content     string                   `json:"content,omitempty" jsonschema:"description=Markdown content for text panels"`
+	Width       int                      `json:"width,omitempty" jsonschema:"description=Panel width in grid columns (1-24). Defaults depend on the panel type."`
+	Height      int                      `json:"height,omitempty" jsonschema:"description=Panel height in grid rows. Defaults depend on the panel type."`
+}
+
+// DashboardRowSpec describes a row of panels.
+type DashboardRowSpec struct {
+	Title     string               `json:"title" jsonschema:"required,description=The row title"`
+	Collapsed bool                 `json:"collapsed,omitempty" jsonschema:"description=Whether the row starts collapsed"`
+	Panels    []DashboardPanelSpec `json:"panels" jsonschema:"required,description=The panels in the row"`
+}
+
+// DashboardSpec is a compact description of a dashboard.
+type DashboardSpec struct {
+	Title       string                  `json:"title" jsonschema:"required,description=The dashboard title"`
+	UID         string                  `json:"uid,omitempty" jsonschema:"description=Optional dashboard UID. If omitted\\, Grafana generates one when saving."`
+	Description string                  `json:"description,omitempty" jsonschema:"description=The dashboard description"`
+	Tags        []string                `json:"tags,omitempty" jsonschema:"description=Dashboard tags"`
+	From        string                  `json:"from,omitempty" jsonschema:"default=now-6h,description=Default start of the time range"`
+	To          string                  `json:"to,omitempty" jsonschema:"default=now,description=Default end of the time range"`
+	Refresh     string                  `json:"refresh,omitempty" jsonschema:"description=Auto-refresh interval (e.g. '30s')"`
+	Datasource  *DashboardDatasourceRef `json:"datasource,omitempty" jsonschema:"description=Default datasource. A 'datasource' variable of this type is added and panels reference it."`
+	Variables   []DashboardVariableSpec `json:"variables,omitempty" jsonschema:"description=Template variables"`
+	Panels      []DashboardPanelSpec    `json:"panels,omitempty" jsonschema:"description=Panels placed before any rows"`
+	Rows        []DashboardRowSpec      `json:"rows,omitempty" jsonschema:"description=Rows of panels"`
+}
+
+func (s DashboardSpec) validate() error {
+	if strings.TrimSpace(s.Title) == "" {
+		return fmt.Errorf("title is required")
+	}
+	if s.Datasource != nil && s.Datasource.Type == "" {
+		return fmt.Errorf("datasource type is required")
+	}
+	names := map[string]bool{}
+	for _, v := range s.Variables {
+		if v.Name == "" {
+			return fmt.Errorf("variable name is required")
+		}
+		if s.Datasource != nil && v.Name == defaultDatasourceVar {
+			return fmt.Errorf("variable name '%s' is reserved for the dashboard datasource variable", defaultDatasourceVar)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate variable name '%s'", v.Name)
+		}
+		names[v.Name] = true
+	}
+	panels := append([]DashboardPanelSpec{}, s.Panels...)
+	for _, r := range s.Rows {
+		panels = append(panels, r.Panels...)
+	}
+	for _, p := range panels {
+		if p.Width < 0 || p.Width > dashboardGridWidth {
+			return fmt.Errorf("panel '%s': width must be between 1 and %d", p.Title, dashboardGridWidth)
+		}
+		if p.Height < 0 {
+			return fmt.Errorf("panel '%s': height must be positive", p.Title)
+		}
+		refIDs := map[string]bool{}
+		for _, q := range p.Queries {
+			if q.RefID == "" {
+				continue
+			}
+			if refIDs[q.RefID] {
+				return fmt.Errorf("panel '%s': duplicate refId '%s'", p.Title, q.RefID)
+			}
+			refIDs[q.RefID] = true
+		}
+	}
+	return nil
+}
+
+// gridLayout places panels left to right on the 24 column dashboard grid,
+// wrapping to a new line when a panel does not fit.
+type gridLayout struct {
+	x, y, lineHeight int
+}
+
+func (l *gridLayout) place(w, h int) map[string]interface{} {
+	if l.x+w > dashboardGridWidth {
+		l.newLine()
+	}
+	pos := map[string]interface{}{"x": l.x, "y": l.y, "w": w, "h": h}
+	l.x += w
+	if h > l.lineHeight {
+		l.lineHeight = h
+	}
+	return pos
+}
+
+func (l *gridLayout) newLine() {
+	if l.x > 0 {
+		l.y += l.lineHeight
+	}
+	l.x, l.lineHeight = 0, 0
+}
+
+// dashboardBuilder converts a DashboardSpec into dashboard JSON.
+type dashboardBuilder struct {
+	spec       DashboardSpec
+	nextID     int
+	datasource map[string]interface{}
+}
+
+// buildDashboardFromSpec generates classic dashboard JSON from a spec.
+func buildDashboardFromSpec(spec DashboardSpec) (map[string]interface{}, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	b := &dashboardBuilder{spec: spec, nextID: 1}
+
+	var variables []interface{}
+	if spec.Datasource != nil {
+		b.datasource = map[string]interface{}{"type": spec.Datasource.Type, "uid": "${" + defaultDatasourceVar + "}"}
+		variables = append(variables, datasourceVariable(*spec.Datasource))
+	}
+	for _, v := range spec.Variables {
+		variables = append(variables, b.variable(v))
+	}
+
+	layout := &gridLayout{}
+	panels := []interface{}{}
+	for _, p := range spec.Panels {
+		panels = append(panels, b.panel(p, layout))
+	}
+	for _, r := range spec.Rows {
+		layout.newLine()
+		row := map[string]interface{}{
+			"id":        b.id(),
+			"type":      "row",
+			"title":     r.Title,
+			"collapsed": r.Collapsed,
+			"gridPos":   map[string]interface{}{"x": 0, "y": layout.y, "w": dashboardGridWidth, "h": 1},
+			"panels":    []interface{}{},
+		}
+		panels = append(panels, row)
+		layout.y++
+
+		if !r.Collapsed {
+			for _, p := range r.Panels {
+				panels = append(panels, b.panel(p, layout))
+			}
+			continue
+		}
+		// Panels of collapsed rows are stored on the row and positioned as if
+		// the row were expanded; the next row follows directly below.
+		nested := &gridLayout{y: layout.y}
+		rowPanels := []interface{}{}
+		for _, p := range r.Panels {
+			rowPanels = append(rowPanels, b.panel(p, nested))
+		}
+		row["panels"] = rowPanels
+	}
+
+	from, to := spec.From, spec.To
+	if from == "" {
+		from = defaultDashboardFrom
+	}
+	if to == "" {
+		to = defaultDashboardTo
+	}
+	tags := spec.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	if variables == nil {
+		variables = []interface{}{}
+	}
+
+	db := map[string]interface{}{
+		"title":         spec.Title,
+		"tags":          tags,
+		"editable":      true,
+		"schemaVersion": dashboardSchemaVersion,
+		"time":          map[string]interface{}{"from": from, "to": to},
+		"timezone":      "browser",
+		"panels":        panels,
+		"templating":    map[string]interface{}{"list": variables},
+	}
+	if spec.UID != "" {
+		db["uid"] = spec.UID
+	}
+	if spec.Description != "" {
+		db["description"] = spec.Description
+	}
+	if spec.Refresh != "" {
+		db["refresh"] = spec.Refresh
+	}
+	return db, nil
+}
+
+func (b *dashboardBuilder) id() int {
+	id := b.nextID
+	b.nextID++
+	return id
+}
+
+func datasourceVariable(ds DashboardDatasourceRef) map[string]interface{} {
+	v := map[string]interface{}{
+		"name":    defaultDatasourceVar,
+		"label":   "Data source",
+		"type":    "datasource",
+		"query":   ds.Type,
+		"refresh": 1,
+		"hide":    0,
+	}
+	if ds.UID != "" {
+		v["current"] = map[string]interface{}{"value": ds.UID}
+	}
+	return v
+}
+
+func (b *dashboardBuilder) datasourceRef(ref *DashboardDatasourceRef) map[string]interface{} {
+	if ref != nil {
+		return map[string]interface{}{"type": ref.Type, "uid": ref.UID}
+	}
+	return b.datasource
+}
+
+func (b *dashboardBuilder) variable(v DashboardVariableSpec) map[string]interface{} {
+	varType := v.Type
+	if varType == "" {
+		varType = "query"
+	}
+	result := map[string]interface{}{
+		"name":       v.Name,
+		"type":       varType,
+		"hide":       0,
+		"multi":      v.Multi,
+		"includeAll": v.IncludeAll,
+	}
+	if v.Label != "" {
+		result["label"] = v.Label
+	}
+
+	switch varType {
+	case "query":
+		if ds := b.datasourceRef(v.Datasource); ds != nil {
+			result["datasource"] = ds
+		}
+		result["query"] = v.Query
+		result["definition"] = v.Query
+		// Refresh the options whenever the time range changes.
+		result["refresh"] = 2
+		result["sort"] = 1
+	case "custom", "interval":
+		query := v.Query
+		if len(v.Options) > 0 {
+			query = strings.Join(v.Options, ",")
+		}
+		result["query"] = query
+		options := []interface{}{}
+		for i, o := range strings.Split(query, ",") {
+			o = strings.TrimSpace(o)
+			if o == "" {
+				continue
+			}
+			options = append(options, map[string]interface{}{"text": o, "value": o, "selected": i == 0})
+		}
+		result["options"] = options
+	default:
+		result["query"] = v.Query
+	}
+	return result
+}
+
+func (b *dashboardBuilder) panel(p DashboardPanelSpec, layout *gridLayout) map[string]interface{} {
+	panelType := p.Type
+	if panelType == "" {
+		panelType = "timeseries"
+	}
+	w, h := 12, 8
+	if size, ok := defaultPanelSizes[panelType]; ok {
+		w, h = size[0], size[1]
+	}
+	if p.Width > 0 {
+		w = p.Width
+	}
+	if p.Height > 0 {
+		h = p.Height
+	}
+
+	panel := map[string]interface{}{
+		"id":      b.id(),
+		"type":    panelType,
+		"title":   p.Title,
+		"gridPos": layout.place(w, h),
+	}
+	if p.Description != "" {
+		panel["description"] = p.Description
+	}
+	if panelType == "text" {
+		panel["options"] = map[string]interface{}{"mode": "markdown", "content": p.Content}
+		return panel
+	}
+
+	ds := b.datasourceRef(p.Datasource)
+	if ds != nil {
+		panel["datasource"] = ds
+	}
+	dsType, _ := ds["type"].(string)
+
+	// Explicit refIds are reserved first so that generated ones cannot
+	// collide with them.
+	refIDs := map[string]bool{}
+	for _, q := range p.Queries {
+		if q.RefID != "" {
+			refIDs[q.RefID] = true
+		}
+	}
+	nextRefID := 0
+	targets := []interface{}{}
+	for _, q := range p.Queries {
+		refID := q.RefID
+		if refID == "" {
+			for refIDs[refIDForIndex(nextRefID)] {
+				nextRefID++
+			}
+			refID = refIDForIndex(nextRefID)
+			refIDs[refID] = true
+		}
+		targets = append(targets, buildQueryTarget(q, refID, ds, dsType))
+	}
+	panel["targets"] = targets
+
+	defaults := map[string]interface{}{}
+	if p.Unit != "" {
+		defaults["unit"] = p.Unit
+	}
+	if p.Decimals != nil {
+		defaults["decimals"] = *p.Decimals
+	}
+	if p.Min != nil {
+		defaults["min"] = *p.Min
+	}
+	if p.Max != nil {
+		defaults["max"] = *p.Max
+	}
+	steps := []interface{}{map[string]interface{}{"color": "green", "value": nil}}
+	for _, t := range p.Thresholds {
+		steps = append(steps, map[string]interface{}{"color": t.Color, "value": t.Value})
+	}
+	defaults["thresholds"] = map[string]interface{}{"mode": "absolute", "steps": steps}
+	panel["fieldConfig"] = map[string]interface{}{"defaults": defaults, "overrides": []interface{}{}}
+	return panel
+}
+
+// buildQueryTarget builds a panel target using the query model expected by
+// the datasource type.
+func buildQueryTarget(q DashboardQuerySpec, refID string, ds map[string]interface{}, dsType string) map[string]interface{} {
+	target := map[string]interface{}{}
+	for k, v := range q.Model {
+		target[k] = v
+	}
+	target["refId"] = refID
+	if ds != nil {
+		target["datasource"] = ds
+	}
+	if q.Expr != "" {
+		target["expr"] = q.Expr
+		if strings.Contains(dsType, "prometheus") {
+			target["range"] = true
+		}
+	}
+	if q.RawSQL != "" {
+		target["rawSql"] = q.RawSQL
+		target["rawQuery"] = true
+		target["editorMode"] = "code"
+		if _, ok := target["format"]; !ok {
+			target["format"] = "time_series"
+		}
+	}
+	if q.LegendFormat != "" {
+		target["legendFormat"] = q.LegendFormat
+	}
+	return target
+}
+
+// refIDForIndex returns the conventional ref ID for the i-th query: A..Z, AA, AB...
+func refIDForIndex(i int) string {
+	id := ""
+	for i >= 0 {
+		id = string(rune('A'+i%26)) + id
+		i = i/26 - 1
+	}
+	return id
+}
+
+type GenerateDashboardParams struct {
+	Spec DashboardSpec `json:"spec" jsonschema:"required,description=The dashboard specification"`
+}
+
+// resolveSpecDatasource fills in the type of a dashboard datasource given
+// by UID only.
+func resolveSpecDatasource(ctx context.Context, spec *DashboardSpec) error {
+	if spec.Datasource == nil || spec.Datasource.Type != "" || spec.Datasource.UID == "" {
+		return nil
+	}
+	if strings.HasPrefix(spec.Datasource.UID, "$") {
+		return fmt.Errorf("datasource type is required when the datasource UID is a template variable")
+	}
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: spec.Datasource.UID})
+	if err != nil {
+		return err
+	}
+	resolved := *spec.Datasource
+	resolved.Type = ds.Type
+	spec.Datasource = &resolved
+	return nil
+}
+
+func generateDashboard(ctx context.Context, args GenerateDashboardParams) (map[string]interface{}, error) {
+	if err := resolveSpecDatasource(ctx, &args.Spec); err != nil {
+		return nil, fmt.Errorf("generate dashboard: %w", err)
+	}
+	db, err := buildDashboardFromSpec(args.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("generate dashboard: %w", err)
+	}
+	return db, nil
+}
+
+var GenerateDashboard = mcpgrafana.MustTool(
+	"generate_dashboard",
+	"Generate valid Grafana dashboard JSON from a compact spec (title\\, variables\\, rows and panels with type\\, queries\\, unit and thresholds) without saving it. Panels are laid out automatically with correct gridPos\\, IDs and datasource references. Use this to preview a dashboard; use create_dashboard_from_spec to save it.",
+	generateDashboard,
+	mcp.WithTitleAnnotation("Generate dashboard from spec"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateDashboardFromSpecParams struct {
+	Spec      DashboardSpec `json:"spec" jsonschema:"required,description=The dashboard specification"`
+	FolderUID string        `json:"folderUid,omitempty" jsonschema:"description=The UID of the folder to save the dashboard in"`
+	Message   string        `json:"message,omitempty" jsonschema:"description=Set a commit message for the version history"`
+	Overwrite bool          `json:"overwrite,omitempty" jsonschema:"description=Overwrite an existing dashboard with the same UID or title"`
+}
+
+func createDashboardFromSpec(ctx context.Context, args CreateDashboardFromSpecParams) (*models.PostDashboardOKBody, error) {
+	if err := resolveSpecDatasource(ctx, &args.Spec); err != nil {
+		return nil, fmt.Errorf("generate dashboard: %w", err)
+	}
+	db, err := buildDashboardFromSpec(args.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("generate dashboard: %w", err)
+	}
+	return updateDashboardWithFullJSON(ctx, UpdateDashboardParams{
+		Dashboard: db,
+		FolderUID: args.FolderUID,
+		Message:   args.Message,
+		Overwrite: args.Overwrite,
+	})
+}
+
+var CreateDashboardFromSpec = mcpgrafana.MustTool(
+	"create_dashboard_from_spec",
+	"Generate a dashboard from a compact spec (see generate_dashboard) and save it to Grafana. Returns the saved dashboard's UID\\, URL and version.",
+	createDashboardFromSpec,
+	mcp.WithTitleAnnotation("Create dashboard from spec"),
+	mcp.WithIdempotentHintAnnotation(false),
+)
@@ -0,0 +1,182 @@
+//go:build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDashboardFromSpec(t *testing.T) {
+	spec := DashboardSpec{
+		Title:      "Service overview",
+		Tags:       []string{"generated"},
+		Datasource: &DashboardDatasourceRef{UID: "prom-uid", Type: "prometheus"},
+		Variables: []DashboardVariableSpec{
+			{Name: "job", Query: "label_values(up, job)", Multi: true},
+			{Name: "env", Type: "custom", Options: []string{"prod", "dev"}},
+		},
+		Panels: []DashboardPanelSpec{
+			{Title: "Up", Type: "stat", Queries: []DashboardQuerySpec{{Expr: `up{job="$job"}`}}},
+			{Title: "CPU", Type: "stat", Unit: "percent"},
+			{Title: "Requests", Unit: "reqps", Thresholds: []DashboardThresholdSpec{{Value: 100, Color: "red"}},
+				Queries: []DashboardQuerySpec{{Expr: "sum(rate(http_requests_total[$__rate_interval]))"}, {Expr: "vector(1)", LegendFormat: "one"}}},
+		},
+		Rows: []DashboardRowSpec{
+			{Title: "Details", Panels: []DashboardPanelSpec{{Title: "Latency", Width: 24}}},
+			{Title: "Logs", Collapsed: true, Panels: []DashboardPanelSpec{
+				{Title: "Errors", Type: "logs", Datasource: &DashboardDatasourceRef{UID: "loki-uid", Type: "loki"}, Queries: []DashboardQuerySpec{{Expr: `{job="api"}`}}},
+			}},
+		},
+	}
+
+	db, err := buildDashboardFromSpec(spec)
+	require.NoError(t, err)
+
+	vars := db["templating"].(map[string]interface{})["list"].([]interface{})
+	require.Len(t, vars, 3)
+	assert.Equal(t, "datasource", vars[0].(map[string]interface{})["name"])
+	assert.Equal(t, "prometheus", vars[0].(map[string]interface{})["query"])
+	assert.Equal(t, map[string]interface{}{"type": "prometheus", "uid": "${datasource}"}, vars[1].(map[string]interface{})["datasource"])
+	assert.Len(t, vars[2].(map[string]interface{})["options"], 2)
+
+	panels := db["panels"].([]interface{})
+	require.Len(t, panels, 6)
+	pos := func(i int) map[string]interface{} {
+		return panels[i].(map[string]interface{})["gridPos"].(map[string]interface{})
+	}
+	assert.Equal(t, map[string]interface{}{"x": 0, "y": 0, "w": 6, "h": 4}, pos(0))
+	assert.Equal(t, map[string]interface{}{"x": 6, "y": 0, "w": 6, "h": 4}, pos(1))
+	assert.Equal(t, map[string]interface{}{"x": 12, "y": 0, "w": 12, "h": 8}, pos(2))
+	assert.Equal(t, map[string]interface{}{"x": 0, "y": 8, "w": 24, "h": 1}, pos(3))
+	assert.Equal(t, map[string]interface{}{"x": 0, "y": 9, "w": 24, "h": 8}, pos(4))
+	assert.Equal(t, map[string]interface{}{"x": 0, "y": 17, "w": 24, "h": 1}, pos(5))
+
+	ids := map[int]bool{}
+	for _, p := range panels {
+		ids[p.(map[string]interface{})["id"].(int)] = true
+	}
+	assert.Len(t, ids, 6)
+
+	requests := panels[2].(map[string]interface{})
+	targets := requests["targets"].([]interface{})
+	require.Len(t, targets, 2)
+	assert.Equal(t, "A", targets[0].(map[string]interface{})["refId"])
+	assert.Equal(t, "B", targets[1].(map[string]interface{})["refId"])
+	assert.Equal(t, "one", targets[1].(map[string]interface{})["legendFormat"])
+	defaults := requests["fieldConfig"].(map[string]interface{})["defaults"].(map[string]interface{})
+	assert.Equal(t, "reqps", defaults["unit"])
+	assert.Len(t, defaults["thresholds"].(map[string]interface{})["steps"], 2)
+
+	logsRow := panels[5].(map[string]interface{})
+	assert.Equal(t, true, logsRow["collapsed"])
+	nested := logsRow["panels"].([]interface{})
+	require.Len(t, nested, 1)
+	assert.Equal(t, map[string]interface{}{"type": "loki", "uid": "loki-uid"}, nested[0].(map[string]interface{})["datasource"])
+	assert.Equal(t, 18, nested[0].(map[string]interface{})["gridPos"].(map[string]interface{})["y"])
+}
+
+func TestBuildDashboardFromSpec_Validation(t *testing.T) {
+	_, err := buildDashboardFromSpec(DashboardSpec{})
+	assert.ErrorContains(t, err, "title is required")
+
+	_, err = buildDashboardFromSpec(DashboardSpec{Title: "x", Variables: []DashboardVariableSpec{{Name: "a"}, {Name: "a"}}})
+	assert.ErrorContains(t, err, "duplicate variable")
+
+	_, err = buildDashboardFromSpec(DashboardSpec{Title: "x", Panels: []DashboardPanelSpec{{Title: "wide", Width: 30}}})
+	assert.ErrorContains(t, err, "width")
+
+	_, err = buildDashboardFromSpec(DashboardSpec{Title: "x", Datasource: &DashboardDatasourceRef{UID: "prom-uid"}})
+	assert.ErrorContains(t, err, "datasource type is required")
+
+	_, err = buildDashboardFromSpec(DashboardSpec{
+		Title:      "x",
+		Datasource: &DashboardDatasourceRef{UID: "prom-uid", Type: "prometheus"},
+		Variables:  []DashboardVariableSpec{{Name: "datasource", Type: "datasource", Query: "prometheus"}},
+	})
+	assert.ErrorContains(t, err, "reserved")
+
+	_, err = buildDashboardFromSpec(DashboardSpec{Title: "x", Panels: []DashboardPanelSpec{
+		{Title: "dup", Queries: []DashboardQuerySpec{{RefID: "A"}, {RefID: "A"}}},
+	}})
+	assert.ErrorContains(t, err, "duplicate refId")
+}
+
+func TestBuildDashboardFromSpec_GeneratedRefIDsSkipExplicitOnes(t *testing.T) {
+	db, err := buildDashboardFromSpec(DashboardSpec{Title: "x", Panels: []DashboardPanelSpec{
+		{Title: "mixed", Queries: []DashboardQuerySpec{{RefID: "B", Expr: "up"}, {Expr: "down"}, {Expr: "sideways"}}},
+	}})
+	require.NoError(t, err)
+	panels := db["panels"].([]interface{})
+	targets := panels[0].(map[string]interface{})["targets"].([]interface{})
+	var refIDs []string
+	for _, target := range targets {
+		refIDs = append(refIDs, target.(map[string]interface{})["refId"].(string))
+	}
+	assert.Equal(t, []string{"B", "A", "C"}, refIDs)
+}
+
+func TestGenerateDashboard_ResolvesDatasourceType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/datasources/uid/prom-uid":
+			_, _ = w.Write([]byte(`{"uid": "prom-uid", "type": "prometheus"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "not found"}`))
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	db, err := generateDashboard(ctx, GenerateDashboardParams{Spec: DashboardSpec{
+		Title:      "By UID",
+		Datasource: &DashboardDatasourceRef{UID: "prom-uid"},
+		Panels:     []DashboardPanelSpec{{Title: "A", Queries: []DashboardQuerySpec{{Expr: "up"}}}},
+	}})
+	require.NoError(t, err)
+	panel := db["panels"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "prometheus", panel["datasource"].(map[string]interface{})["type"])
+	variable := db["templating"].(map[string]interface{})["list"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "prometheus", variable["query"])
+
+	_, err = generateDashboard(ctx, GenerateDashboardParams{Spec: DashboardSpec{Title: "x", Datasource: &DashboardDatasourceRef{UID: "missing"}}})
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = generateDashboard(ctx, GenerateDashboardParams{Spec: DashboardSpec{Title: "x", Datasource: &DashboardDatasourceRef{UID: "${ds}"}}})
+	assert.ErrorContains(t, err, "template variable")
+}
+
+func TestGeneratedDashboardPassesLint(t *testing.T) {
+	db, err := generateDashboard(context.Background(), GenerateDashboardParams{Spec: DashboardSpec{
+		Title:      "Lint clean",
+		Datasource: &DashboardDatasourceRef{UID: "prom-uid", Type: "prometheus"},
+		Panels: []DashboardPanelSpec{
+			{Title: "A", Unit: "s", Queries: []DashboardQuerySpec{{Expr: "rate(foo[$__rate_interval])"}}},
+			{Title: "B", Unit: "s"},
+			{Title: "C", Unit: "s"},
+		},
+	}})
+	require.NoError(t, err)
+
+	// Round-trip through JSON so numbers are decoded as they would be from Grafana.
+	raw, err := json.Marshal(db)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	assert.Empty(t, lintDashboardJSON(decoded))
+}
+
+func TestRefIDForIndex(t *testing.T) {
+	assert.Equal(t, "A", refIDForIndex(0))
+	assert.Equal(t, "Z", refIDForIndex(25))
+	assert.Equal(t, "AA", refIDForIndex(26))
+	assert.Equal(t, "AB", refIDForIndex(27))
+}
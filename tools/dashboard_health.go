@@ -0,0 +1,439 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	healthIssueQueryError        = "query_error"
+	healthIssueNoData            = "no_data"
+	healthIssueMissingDatasource = "missing_datasource"
+	healthIssueUnknownMetric     = "unknown_metric"
+	healthIssueLibraryPanel      = "library_panel_error"
+
+	defaultHealthMaxDashboards = 50
+	defaultHealthConcurrency   = 8
+	maxHealthConcurrency       = 32
+	// healthMetricLookback is how far back unknown metrics are searched for
+	// before a panel is reported as using a metric that no longer exists.
+	healthMetricLookback = 24 * time.Hour
+)
+
+// dashboardVariableRegex captures the variable name of $var, ${var},
+// ${var:format} and [[var]] references.
+var dashboardVariableRegex = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)(?::[^}]*)?\}|\$([A-Za-z_][A-Za-z0-9_]*)|\[\[([A-Za-z0-9_]+)(?::[^\]]*)?\]\]`)
+
+// dashboardVariableValues returns the current values of the dashboard's
+// template variables. "All" selections become a match-anything regex unless
+// the variable defines a custom all value.
+func dashboardVariableValues(db map[string]interface{}) map[string][]string {
+	result := map[string][]string{}
+	for _, variable := range dashboardVariableList(db) {
+		var values []string
+		switch current := safeObject(variable, "current")["value"].(type) {
+		case string:
+			values = []string{current}
+		case []interface{}:
+			for _, c := range current {
+				if s, ok := c.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		for _, value := range values {
+			if value == "$__all" {
+				values = []string{".*"}
+				if custom := safeString(variable, "allValue"); custom != "" {
+					values = []string{custom}
+				}
+				break
+			}
+		}
+		if name := safeString(variable, "name"); name != "" && len(values) > 0 {
+			result[name] = values
+		}
+	}
+	return result
+}
+
+// interpolateDashboardVariables substitutes template variables in a query
+// with their current values. Multiple values are rendered as a regex
+// alternation. Grafana's built-in variables (e.g. $__rate_interval) and
+// unknown variables are left for the datasource to handle.
+func interpolateDashboardVariables(query string, values map[string][]string) string {
+	return dashboardVariableRegex.ReplaceAllStringFunc(query, func(match string) string {
+		groups := dashboardVariableRegex.FindStringSubmatch(match)
+		name := groups[1] + groups[2] + groups[3]
+		vals, ok := values[name]
+		if !ok || strings.HasPrefix(name, "__") {
+			return match
+		}
+		if len(vals) == 1 {
+			return vals[0]
+		}
+		return "(" + strings.Join(vals, "|") + ")"
+	})
+}
+
+type CheckDashboardHealthParams struct {
+	UID           string `json:"uid,omitempty" jsonschema:"description=UID of the dashboard to check. Either uid or folderUid is required."`
+	FolderUID     string `json:"folderUid,omitempty" jsonschema:"description=Check every dashboard in this folder instead of a single dashboard"`
+	From          string `json:"from,omitempty" jsonschema:"default=now-15m,description=Start of the time range the panel queries are executed over (e.g. 'now-1h' or an RFC3339 timestamp)"`
+	To            string `json:"to,omitempty" jsonschema:"default=now,description=End of the time range the panel queries are executed over"`
+	MaxDashboards int    `json:"maxDashboards,omitempty" jsonschema:"default=50,description=Maximum number of dashboards to check when checking a folder"`
+	Concurrency   int    `json:"concurrency,omitempty" jsonschema:"default=8,description=Number of queries executed in parallel (max 32)"`
+}
+
+// PanelHealthIssue describes a broken panel query.
+type PanelHealthIssue struct {
+	Kind       string         `json:"kind"`
+	PanelID    int            `json:"panelId,omitempty"`
+	PanelTitle string         `json:"panelTitle,omitempty"`
+	Path       string         `json:"path"`
+	Query      string         `json:"query,omitempty"`
+	Datasource datasourceInfo `json:"datasource"`
+	Message    string         `json:"message"`
+	// Metrics lists the metrics that were not found, for unknown_metric issues.
+	Metrics []string `json:"metrics,omitempty"`
+}
+
+// DashboardHealthReport is the health of a single dashboard.
+type DashboardHealthReport struct {
+	UID            string             `json:"uid"`
+	Title          string             `json:"title"`
+	FolderTitle    string             `json:"folderTitle,omitempty"`
+	QueriesChecked int                `json:"queriesChecked"`
+	Healthy        bool               `json:"healthy"`
+	Issues         []PanelHealthIssue `json:"issues,omitempty"`
+}
+
+// DashboardHealthResult is the result of a dashboard health check.
+type DashboardHealthResult struct {
+	Dashboards          []DashboardHealthReport `json:"dashboards"`
+	UnhealthyDashboards int                     `json:"unhealthyDashboards"`
+	IssueCounts         map[string]int          `json:"issueCounts"`
+	Truncated           bool                    `json:"truncated,omitempty"`
+	Errors              []string                `json:"errors,omitempty"`
+}
+
+// healthChecker executes panel queries and caches datasource and metric
+// lookups for the duration of a single health check.
+type healthChecker struct {
+	from, to string
+	end      time.Time
+
+	datasources       datasourceIndex
+	defaultDatasource *models.DataSourceListItemDTO
+
+	mu            sync.Mutex
+	metricsExists map[string]bool
+}
+
+func newHealthChecker(ctx context.Context, from, to string) (*healthChecker, error) {
+	end, err := parseTime(to)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if _, err := parseTime(from); err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("list datasources: %w", err)
+	}
+	hc := &healthChecker{
+		from:          from,
+		to:            to,
+		end:           end,
+		datasources:   indexDatasources(resp.Payload),
+		metricsExists: map[string]bool{},
+	}
+	for _, ds := range resp.Payload {
+		if ds.IsDefault {
+			hc.defaultDatasource = ds
+		}
+	}
+	return hc, nil
+}
+
+// checkQuery executes a single panel query and returns an issue if it is
+// broken. It returns false if the query was skipped.
+func (hc *healthChecker) checkQuery(ctx context.Context, q dashboardQuery, values map[string][]string) (*PanelHealthIssue, bool) {
+	if hidden, _ := q.Target["hide"].(bool); hidden {
+		return nil, false
+	}
+	issue := &PanelHealthIssue{
+		PanelID:    q.PanelID,
+		PanelTitle: q.PanelTitle,
+		Path:       q.Path,
+		Query:      q.Query,
+		Datasource: q.Datasource,
+	}
+
+	ref := q.Datasource
+	if ref.UID == "" || ref.UID == "default" {
+		if hc.defaultDatasource == nil {
+			issue.Kind = healthIssueMissingDatasource
+			issue.Message = "query uses the default datasource but no default datasource is configured"
+			return issue, true
+		}
+		ref.UID = hc.defaultDatasource.UID
+	}
+	// Built-in datasources and server-side expressions are not checked.
+	if builtinDatasourceUIDs[ref.UID] || ref.UID == "__expr__" || ref.Type == "__expr__" || ref.Type == "datasource" {
+		return nil, false
+	}
+	if isTemplateVariable(ref.UID) {
+		issue.Kind = healthIssueMissingDatasource
+		issue.Message = fmt.Sprintf("datasource variable %s has no current value", ref.UID)
+		return issue, true
+	}
+	ds, ok := hc.datasources[ref.UID]
+	if !ok {
+		issue.Kind = healthIssueMissingDatasource
+		issue.Message = fmt.Sprintf("datasource %s does not exist", ref.UID)
+		return issue, true
+	}
+	issue.Datasource = datasourceInfo{UID: ds.UID, Type: ds.Type}
+
+	query := make(map[string]interface{}, len(q.Target)+3)
+	for k, v := range q.Target {
+		if s, ok := v.(string); ok {
+			v = interpolateDashboardVariables(s, values)
+		}
+		query[k] = v
+	}
+	query["refId"] = "A"
+	query["datasource"] = map[string]string{"uid": ds.UID, "type": ds.Type}
+	query["maxDataPoints"] = 100
+
+	resp, err := queryDatasources(ctx, dsQueryRequest{From: hc.from, To: hc.to, Queries: []map[string]interface{}{query}})
+	if err != nil {
+		issue.Kind = healthIssueQueryError
+		issue.Message = err.Error()
+		return issue, true
+	}
+	result := resp.Results["A"]
+	if result.Error != "" {
+		issue.Kind = healthIssueQueryError
+		issue.Message = result.Error
+		return issue, true
+	}
+	for _, frame := range result.Frames {
+		if frameRowCount(frame) > 0 {
+			return nil, true
+		}
+	}
+
+	if ds.Type == "prometheus" {
+		if missing := hc.missingMetrics(ctx, ds.UID, q.Query); len(missing) > 0 {
+			issue.Kind = healthIssueUnknownMetric
+			issue.Metrics = missing
+			issue.Message = fmt.Sprintf("metrics not found in the last %s: %s", healthMetricLookback, strings.Join(missing, ", "))
+			return issue, true
+		}
+	}
+	issue.Kind = healthIssueNoData
+	issue.Message = "query returned no data"
+	return issue, true
+}
+
+// missingMetrics returns the metrics referenced by a PromQL query that have no
+// series in the lookback window.
+func (hc *healthChecker) missingMetrics(ctx context.Context, uid, expr string) []string {
+	refs := extractQueryReferences(expr, "prometheus")
+	var missing []string
+	for _, metric := range refs.Metrics {
+		key := uid + "|" + metric
+		hc.mu.Lock()
+		exists, cached := hc.metricsExists[key]
+		hc.mu.Unlock()
+		if !cached {
+			promClient, err := promClientFromContext(ctx, uid)
+			if err != nil {
+				continue
+			}
+			values, _, err := promClient.LabelValues(ctx, "__name__", []string{fmt.Sprintf("{__name__=%q}", metric)}, hc.end.Add(-healthMetricLookback), hc.end)
+			if err != nil {
+				continue
+			}
+			exists = len(values) > 0
+			hc.mu.Lock()
+			hc.metricsExists[key] = exists
+			hc.mu.Unlock()
+		}
+		if !exists {
+			missing = append(missing, metric)
+		}
+	}
+	return missing
+}
+
+// healthDashboardQueries returns the queries of every panel of a dashboard
+// with library panels resolved to their model, so their queries are checked
+// too. Library panels that can't be resolved are reported as issues.
+func healthDashboardQueries(ctx context.Context, db map[string]interface{}) ([]dashboardQuery, []PanelHealthIssue) {
+	variables := datasourceVariables(db)
+	libraryPanels := newLibraryPanelResolver()
+	var (
+		queries []dashboardQuery
+		issues  []PanelHealthIssue
+	)
+	for _, p := range collectDashboardPanels(db) {
+		panel := p.Panel
+		if isDashboardV2Element(panel) {
+			if safeString(panel, "kind") != "LibraryPanel" {
+				queries = append(queries, extractV2PanelQueries(p, variables)...)
+				continue
+			}
+			panel = v2LibraryPanelReference(panel)
+		}
+		libraryPanel := safeObject(panel, "libraryPanel")
+		if safeString(libraryPanel, "uid") == "" {
+			queries = append(queries, extractPanelQueries(p, variables)...)
+			continue
+		}
+		resolved, err := libraryPanels.resolve(ctx, panel)
+		if err != nil {
+			title := safeString(panel, "title")
+			if title == "" {
+				title = safeString(libraryPanel, "name")
+			}
+			issues = append(issues, PanelHealthIssue{
+				Kind:       healthIssueLibraryPanel,
+				PanelID:    safeInt(panel, "id"),
+				PanelTitle: title,
+				Path:       p.Path,
+				Message:    fmt.Sprintf("library panel %s could not be loaded: %v", safeString(libraryPanel, "uid"), err),
+			})
+			continue
+		}
+		p.Panel = resolved
+		queries = append(queries, extractPanelQueries(p, variables)...)
+	}
+	return queries, issues
+}
+
+func checkDashboardHealth(ctx context.Context, args CheckDashboardHealthParams) (*DashboardHealthResult, error) {
+	if (args.UID == "") == (args.FolderUID == "") {
+		return nil, fmt.Errorf("check dashboard health: exactly one of uid or folderUid is required")
+	}
+	if args.From == "" {
+		args.From = "now-15m"
+	}
+	if args.To == "" {
+		args.To = "now"
+	}
+	maxDashboards := args.MaxDashboards
+	if maxDashboards <= 0 {
+		maxDashboards = defaultHealthMaxDashboards
+	}
+	concurrency := args.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultHealthConcurrency
+	}
+	if concurrency > maxHealthConcurrency {
+		concurrency = maxHealthConcurrency
+	}
+
+	hc, err := newHealthChecker(ctx, args.From, args.To)
+	if err != nil {
+		return nil, fmt.Errorf("check dashboard health: %w", err)
+	}
+
+	result := &DashboardHealthResult{Dashboards: []DashboardHealthReport{}, IssueCounts: map[string]int{}}
+	uids := []string{args.UID}
+	folderTitles := map[string]string{}
+	if args.FolderUID != "" {
+		hits, truncated, err := searchAllDashboards(ctx, []string{args.FolderUID}, maxDashboards)
+		if err != nil {
+			return nil, fmt.Errorf("check dashboard health: %w", err)
+		}
+		result.Truncated = truncated
+		uids = uids[:0]
+		for _, hit := range hits {
+			uids = append(uids, hit.UID)
+			folderTitles[hit.UID] = hit.FolderTitle
+		}
+	}
+
+	sem := make(chan struct{}, concurrency)
+	for _, uid := range uids {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			if args.UID != "" {
+				return nil, fmt.Errorf("check dashboard health: %w", err)
+			}
+			result.Errors = append(result.Errors, fmt.Sprintf("dashboard %s: %v", uid, err))
+			continue
+		}
+		db, ok := dashboard.Dashboard.(map[string]interface{})
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("dashboard %s: dashboard is not a JSON object", uid))
+			continue
+		}
+		report := DashboardHealthReport{UID: uid, Title: safeString(db, "title"), FolderTitle: folderTitles[uid]}
+		if report.FolderTitle == "" && dashboard.Meta != nil {
+			report.FolderTitle = dashboard.Meta.FolderTitle
+		}
+
+		values := dashboardVariableValues(db)
+		queries, issues := healthDashboardQueries(ctx, db)
+		report.Issues = append(report.Issues, issues...)
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for _, q := range queries {
+			wg.Add(1)
+			go func(q dashboardQuery) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				issue, checked := hc.checkQuery(ctx, q, values)
+				mu.Lock()
+				defer mu.Unlock()
+				if checked {
+					report.QueriesChecked++
+				}
+				if issue != nil {
+					report.Issues = append(report.Issues, *issue)
+				}
+			}(q)
+		}
+		wg.Wait()
+
+		sort.Slice(report.Issues, func(i, j int) bool { return report.Issues[i].Path < report.Issues[j].Path })
+		report.Healthy = len(report.Issues) == 0
+		if !report.Healthy {
+			result.UnhealthyDashboards++
+		}
+		for _, issue := range report.Issues {
+			result.IssueCounts[issue.Kind]++
+		}
+		result.Dashboards = append(result.Dashboards, report)
+	}
+	return result, nil
+}
+
+var CheckDashboardHealth = mcpgrafana.MustTool(
+	"check_dashboard_health",
+	"Find broken panels by executing every panel query of a dashboard (or of every dashboard in a folder) over a short time range. Reports queries that return errors\\, return no data\\, reference datasources that no longer exist\\, use library panels that can't be loaded\\, or use Prometheus metrics that have had no series in the last 24 hours. Library panels are resolved and template variables are substituted with their current values. Useful for finding dashboards broken by relabeling or datasource changes.",
+	checkDashboardHealth,
+	mcp.WithTitleAnnotation("Check dashboard health"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
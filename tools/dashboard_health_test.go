@@ -0,0 +1,132 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpolateDashboardVariables(t *testing.T) {
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"templating": {"list": [
+		{"name": "job", "current": {"value": "api"}},
+		{"name": "instance", "current": {"value": ["a:9090", "b:9090"]}},
+		{"name": "env", "current": {"value": "$__all"}},
+		{"name": "cluster", "allValue": "prod-.*", "current": {"value": ["$__all"]}}
+	]}}`), &db))
+	values := dashboardVariableValues(db)
+
+	assert.Equal(t,
+		`rate(up{job="api",instance=~"(a:9090|b:9090)",env=~".*",cluster=~"prod-.*"}[$__rate_interval])`,
+		interpolateDashboardVariables(`rate(up{job="$job",instance=~"${instance:regex}",env=~"[[env]]",cluster=~"$cluster"}[$__rate_interval])`, values),
+	)
+	assert.Equal(t, `up{x="$unknown"}`, interpolateDashboardVariables(`up{x="$unknown"}`, values))
+}
+
+func TestCheckDashboardHealth(t *testing.T) {
+	dashboard := `{"uid": "db-1", "title": "API",
+		"templating": {"list": [{"name": "job", "current": {"value": "api"}}]},
+		"panels": [
+			{"id": 1, "title": "OK", "datasource": {"uid": "prom"}, "targets": [{"refId": "A", "expr": "up{job=\"$job\"}"}]},
+			{"id": 2, "title": "Broken", "datasource": {"uid": "prom"}, "targets": [{"refId": "A", "expr": "sum(rate(foo[5m])"}]},
+			{"id": 3, "title": "Empty", "datasource": {"uid": "prom"}, "targets": [{"refId": "A", "expr": "rate(old_metric_total[5m])"}]},
+			{"id": 4, "title": "Quiet", "datasource": {"uid": "prom"}, "targets": [{"refId": "A", "expr": "rate(errors_total[5m])"}, {"refId": "B", "expr": "hidden", "hide": true}]},
+			{"id": 5, "title": "Gone", "datasource": {"uid": "deleted"}, "targets": [{"refId": "A", "expr": "up"}]},
+			{"id": 6, "title": "Text", "type": "text"},
+			{"id": 7, "libraryPanel": {"uid": "lib-1", "name": "Shared requests"}},
+			{"id": 8, "libraryPanel": {"uid": "lib-gone", "name": "Deleted panel"}}
+		]}`
+
+	var (
+		mu       sync.Mutex
+		executed []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources":
+			_, _ = w.Write([]byte(`[{"uid": "prom", "name": "Prometheus", "type": "prometheus", "isDefault": true}]`))
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "name": "Prometheus", "type": "prometheus"}`))
+		case r.URL.Path == "/api/dashboards/uid/db-1":
+			_, _ = w.Write([]byte(`{"meta": {"folderTitle": "Team"}, "dashboard": ` + dashboard + `}`))
+		case r.URL.Path == "/api/library-elements/lib-1":
+			_, _ = w.Write([]byte(libraryPanelResponse))
+		case r.URL.Path == "/api/library-elements/lib-gone":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "library element could not be found"}`))
+		case r.URL.Path == "/api/ds/query":
+			var req dsQueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Len(t, req.Queries, 1)
+			assert.Equal(t, "now-15m", req.From)
+			expr := req.Queries[0]["expr"].(string)
+			mu.Lock()
+			executed = append(executed, expr)
+			mu.Unlock()
+			switch {
+			case strings.HasPrefix(expr, "up"):
+				_, _ = w.Write([]byte(`{"results": {"A": {"status": 200, "frames": [{"schema": {"fields": [{"name": "Time", "type": "time", "typeInfo": {"frame": "time.Time"}}, {"name": "Value", "type": "number", "typeInfo": {"frame": "float64"}}]}, "data": {"values": [[1700000000000], [1]]}}]}}}`))
+			case strings.HasPrefix(expr, "sum"):
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"results": {"A": {"status": 400, "error": "parse error: unclosed left parenthesis"}}}`))
+			default:
+				_, _ = w.Write([]byte(`{"results": {"A": {"status": 200, "frames": []}}}`))
+			}
+		case strings.HasPrefix(r.URL.Path, "/api/datasources/proxy/uid/prom/api/v1/label/__name__/values"):
+			require.NoError(t, r.ParseForm())
+			if strings.Contains(r.Form.Get("match[]"), "errors_total") {
+				_, _ = w.Write([]byte(`{"status": "success", "data": ["errors_total"]}`))
+			} else {
+				_, _ = w.Write([]byte(`{"status": "success", "data": []}`))
+			}
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mcpgrafana.WithGrafanaConfig(mockCtxWithClient(server), mcpgrafana.GrafanaConfig{URL: server.URL})
+
+	result, err := checkDashboardHealth(ctx, CheckDashboardHealthParams{UID: "db-1"})
+	require.NoError(t, err)
+	require.Len(t, result.Dashboards, 1)
+	report := result.Dashboards[0]
+	assert.Equal(t, "Team", report.FolderTitle)
+	assert.Equal(t, 6, report.QueriesChecked)
+	assert.False(t, report.Healthy)
+	assert.Equal(t, 1, result.UnhealthyDashboards)
+	assert.Contains(t, executed, `up{job="api"}`)
+	assert.NotContains(t, executed, "hidden")
+
+	issues := map[int]PanelHealthIssue{}
+	for _, issue := range report.Issues {
+		issues[issue.PanelID] = issue
+	}
+	require.Len(t, issues, 6)
+	assert.Equal(t, healthIssueQueryError, issues[2].Kind)
+	assert.Contains(t, issues[2].Message, "unclosed left parenthesis")
+	assert.Equal(t, healthIssueUnknownMetric, issues[3].Kind)
+	assert.Equal(t, []string{"old_metric_total"}, issues[3].Metrics)
+	assert.Equal(t, healthIssueNoData, issues[4].Kind)
+	assert.Equal(t, healthIssueMissingDatasource, issues[5].Kind)
+	// Library panels are resolved and their queries checked.
+	assert.Equal(t, healthIssueQueryError, issues[7].Kind)
+	assert.Equal(t, "Shared requests", issues[7].PanelTitle)
+	assert.Equal(t, healthIssueLibraryPanel, issues[8].Kind)
+	assert.Equal(t, "Deleted panel", issues[8].PanelTitle)
+	assert.Contains(t, issues[8].Message, "lib-gone")
+	assert.Equal(t, map[string]int{healthIssueQueryError: 2, healthIssueUnknownMetric: 1, healthIssueNoData: 1, healthIssueMissingDatasource: 1, healthIssueLibraryPanel: 1}, result.IssueCounts)
+
+	_, err = checkDashboardHealth(ctx, CheckDashboardHealthParams{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/client/dashboards"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type DeleteDashboardParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the dashboard to delete"`
+}
+
+func (p DeleteDashboardParams) validate() error {
+	if p.UID == "" {
+		return fmt.Errorf("uid is required")
+	}
+	return nil
+}
+
+func deleteDashboard(ctx context.Context, args DeleteDashboardParams) (string, error) {
+	if err := args.validate(); err != nil {
+		return "", fmt.Errorf("delete dashboard: %w", err)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := dashboards.NewDeleteDashboardByUIDParams().WithContext(ctx).WithUID(args.UID)
+	resp, err := c.Dashboards.DeleteDashboardByUIDWithParams(params)
+	if err != nil {
+		return "", fmt.Errorf("delete dashboard %s: %w", args.UID, err)
+	}
+
+	title := args.UID
+	if resp.Payload != nil && resp.Payload.Title != nil {
+		title = fmt.Sprintf("'%s' (%s)", *resp.Payload.Title, args.UID)
+	}
+	return fmt.Sprintf("Dashboard %s deleted successfully", title), nil
+}
+
+var DeleteDashboard = mcpgrafana.MustTool(
+	"delete_dashboard",
+	"Deletes a Grafana dashboard by its UID. Depending on the Grafana version the dashboard is either deleted permanently or moved to the recently deleted list.",
+	deleteDashboard,
+	mcp.WithTitleAnnotation("Delete dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
+
+type MoveDashboardsParams struct {
+	UIDs      []string `json:"uids" jsonschema:"required,description=UIDs of the dashboards to move"`
+	FolderUID string   `json:"folderUid,omitempty" jsonschema:"description=UID of the destination folder. Leave empty to move the dashboards to the root (General) folder."`
+}
+
+func (p MoveDashboardsParams) validate() error {
+	if len(p.UIDs) == 0 {
+		return fmt.Errorf("at least one dashboard uid is required")
+	}
+	return nil
+}
+
+// MoveDashboardResult is the outcome of moving a single dashboard.
+type MoveDashboardResult struct {
+	UID           string `json:"uid"`
+	Title         string `json:"title,omitempty"`
+	FromFolderUID string `json:"fromFolderUid,omitempty"`
+	Moved         bool   `json:"moved"`
+	Error         string `json:"error,omitempty"`
+}
+
+func moveDashboards(ctx context.Context, args MoveDashboardsParams) ([]MoveDashboardResult, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("move dashboards: %w", err)
+	}
+
+	results := make([]MoveDashboardResult, 0, len(args.UIDs))
+	for _, uid := range args.UIDs {
+		result := MoveDashboardResult{UID: uid}
+		if err := moveDashboard(ctx, uid, args.FolderUID, &result); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// moveDashboard saves an unchanged copy of the dashboard into the destination
+// folder, which is how Grafana moves dashboards between folders.
+func moveDashboard(ctx context.Context, uid, folderUID string, result *MoveDashboardResult) error {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err != nil {
+		return err
+	}
+	db, ok := dashboard.Dashboard.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("dashboard is not a JSON object")
+	}
+	result.Title = dashboardTitle(db)
+	if dashboard.Meta != nil {
+		result.FromFolderUID = dashboard.Meta.FolderUID
+		if dashboard.Meta.FolderUID == folderUID {
+			return nil
+		}
+	}
+	// v2 dashboards keep their folder in an annotation, which is only
+	// overwritten when a folder is given.
+	if folderUID == "" && isDashboardV2(db) {
+		delete(safeObject(safeObject(db, "metadata"), "annotations"), dashboardFolderAnnotation)
+	}
+
+	_, err = updateDashboardWithFullJSON(ctx, UpdateDashboardParams{
+		Dashboard: db,
+		FolderUID: folderUID,
+		Message:   "Moved dashboard to another folder",
+		Overwrite: true,
+	})
+	if err != nil {
+		return err
+	}
+	result.Moved = true
+	return nil
+}
+
+var MoveDashboards = mcpgrafana.MustTool(
+	"move_dashboards",
+	"Moves one or more dashboards to another folder. Dashboard content is not changed. Returns the outcome for each dashboard; failures for individual dashboards do not stop the others from being moved.",
+	moveDashboards,
+	mcp.WithTitleAnnotation("Move dashboards"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+)
+
+type CloneDashboardParams struct {
+	UID           string            `json:"uid" jsonschema:"required,description=The UID of the dashboard to clone"`
+	Title         string            `json:"title" jsonschema:"required,description=Title of the new dashboard"`
+	NewUID        string            `json:"newUid,omitempty" jsonschema:"description=UID of the new dashboard. Generated by Grafana if omitted."`
+	FolderUID     string            `json:"folderUid,omitempty" jsonschema:"description=Folder of the new dashboard. Defaults to the folder of the source dashboard."`
+	DatasourceMap map[string]string `json:"datasourceMap,omitempty" jsonschema:"description=Datasource remapping from source datasource UID (or name) to target datasource UID\\, e.g. {\"prom-staging\": \"prom-prod\"}. Applies to panels\\, targets\\, annotations and datasource variables."`
+}
+
+func (p CloneDashboardParams) validate() error {
+	if p.UID == "" {
+		return fmt.Errorf("uid is required")
+	}
+	if p.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if p.NewUID != "" && p.NewUID == p.UID {
+		return fmt.Errorf("newUid must differ from the source uid")
+	}
+	return nil
+}
+
+// CloneDashboardResult describes a cloned dashboard.
+type CloneDashboardResult struct {
+	UID                 string `json:"uid"`
+	Title               string `json:"title"`
+	URL                 string `json:"url"`
+	FolderUID           string `json:"folderUid,omitempty"`
+	DatasourcesRemapped int    `json:"datasourcesRemapped"`
+}
+
+func cloneDashboard(ctx context.Context, args CloneDashboardParams) (*CloneDashboardResult, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("clone dashboard: %w", err)
+	}
+
+	source, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, fmt.Errorf("clone dashboard: %w", err)
+	}
+	db, ok := source.Dashboard.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("clone dashboard: dashboard is not a JSON object")
+	}
+
+	var remapped int
+	if isDashboardV2(db) {
+		if len(args.DatasourceMap) > 0 {
+			return nil, fmt.Errorf("clone dashboard: datasourceMap is not supported for v2 dashboards")
+		}
+		db = newDashboardV2Copy(db, args.NewUID, args.Title)
+	} else {
+		mapping, err := resolveDatasourceMapping(ctx, args.DatasourceMap)
+		if err != nil {
+			return nil, fmt.Errorf("clone dashboard: %w", err)
+		}
+		delete(db, "id")
+		delete(db, "version")
+		db["uid"] = args.NewUID
+		db["title"] = args.Title
+		remapped = remapDashboardDatasources(db, mapping)
+	}
+
+	folderUID := args.FolderUID
+	if folderUID == "" && source.Meta != nil {
+		folderUID = source.Meta.FolderUID
+	}
+	saved, err := updateDashboardWithFullJSON(ctx, UpdateDashboardParams{
+		Dashboard: db,
+		FolderUID: folderUID,
+		Message:   fmt.Sprintf("Cloned from dashboard %s", args.UID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("clone dashboard: %w", err)
+	}
+
+	result := &CloneDashboardResult{Title: args.Title, FolderUID: folderUID, DatasourcesRemapped: remapped}
+	if saved.UID != nil {
+		result.UID = *saved.UID
+	}
+	if saved.URL != nil {
+		result.URL = *saved.URL
+	}
+	return result, nil
+}
+
+// resolveDatasourceMapping looks up the target datasources of a remapping so
+// that references can be rewritten with both UID and type.
+func resolveDatasourceMapping(ctx context.Context, mapping map[string]string) (map[string]datasourceInfo, error) {
+	result := make(map[string]datasourceInfo, len(mapping))
+	for from, to := range mapping {
+		ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: to})
+		if err != nil {
+			return nil, fmt.Errorf("target datasource %s: %w", to, err)
+		}
+		result[from] = datasourceInfo{UID: ds.UID, Type: ds.Type}
+	}
+	return result, nil
+}
+
+// remapDashboardDatasources rewrites every datasource reference in the
+// dashboard whose UID (or, for legacy string references, name) is in the
+// mapping, including the current value of datasource variables. It returns
+// the number of references changed.
+func remapDashboardDatasources(db map[string]interface{}, mapping map[string]datasourceInfo) int {
+	if len(mapping) == 0 {
+		return 0
+	}
+	count := 0
+	for _, v := range safeArray(safeObject(db, "templating"), "list") {
+		variable, ok := v.(map[string]interface{})
+		if !ok || safeString(variable, "type") != "datasource" {
+			continue
+		}
+		current := safeObject(variable, "current")
+		if value, ok := current["value"].(string); ok {
+			if target, ok := mapping[value]; ok {
+				current["value"] = target.UID
+				delete(current, "text")
+				count++
+			}
+		}
+	}
+	return count + remapDatasourceRefs(db, mapping)
+}
+
+func remapDatasourceRefs(node interface{}, mapping map[string]datasourceInfo) int {
+	count := 0
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			if key == "datasource" {
+				if ref, ok := remapDatasourceRef(value, mapping); ok {
+					n[key] = ref
+					count++
+					continue
+				}
+			}
+			count += remapDatasourceRefs(value, mapping)
+		}
+	case []interface{}:
+		for _, item := range n {
+			count += remapDatasourceRefs(item, mapping)
+		}
+	}
+	return count
+}
+
+// remapDatasourceRef returns the rewritten form of a single datasource
+// reference, if it is part of the mapping.
+func remapDatasourceRef(ref interface{}, mapping map[string]datasourceInfo) (interface{}, bool) {
+	switch r := ref.(type) {
+	case string:
+		if target, ok := mapping[r]; ok {
+			return map[string]interface{}{"uid": target.UID, "type": target.Type}, true
+		}
+	case map[string]interface{}:
+		uid, _ := r["uid"].(string)
+		if target, ok := mapping[uid]; ok {
+			r["uid"] = target.UID
+			r["type"] = target.Type
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+var CloneDashboard = mcpgrafana.MustTool(
+	"clone_dashboard",
+	"Creates a copy of a dashboard with a new title and UID\\, optionally in a different folder. Datasource references can be remapped during the copy\\, e.g. to create a production version of a staging dashboard (not supported for v2 dashboards). Returns the UID and URL of the new dashboard.",
+	cloneDashboard,
+	mcp.WithTitleAnnotation("Clone dashboard"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+)
@@ -0,0 +1,116 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemapDashboardDatasources(t *testing.T) {
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"annotations": {"list": [{"datasource": {"type": "prometheus", "uid": "staging"}}]},
+		"templating": {"list": [
+			{"name": "ds", "type": "datasource", "query": "prometheus", "current": {"text": "Staging", "value": "staging"}},
+			{"name": "job", "type": "query", "datasource": {"uid": "staging"}}
+		]},
+		"panels": [
+			{"datasource": "Staging Loki", "targets": [{"datasource": {"uid": "other"}}]},
+			{"type": "row", "panels": [{"datasource": {"uid": "staging"}, "targets": [{"datasource": {"uid": "staging"}}]}]}
+		]
+	}`), &db))
+
+	count := remapDashboardDatasources(db, map[string]datasourceInfo{
+		"staging":      {UID: "prod", Type: "prometheus"},
+		"Staging Loki": {UID: "loki-prod", Type: "loki"},
+	})
+	assert.Equal(t, 6, count)
+
+	out, err := json.Marshal(db)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), `"staging"`)
+	assert.NotContains(t, string(out), "Staging")
+	assert.Contains(t, string(out), `{"type":"loki","uid":"loki-prod"}`)
+	assert.Contains(t, string(out), `"uid":"other"`)
+	assert.Equal(t, "prod", safeObject(safeArray(safeObject(db, "templating"), "list")[0].(map[string]interface{}), "current")["value"])
+}
+
+func TestCloneDashboard(t *testing.T) {
+	var saved map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/src":
+			_, _ = w.Write([]byte(`{"meta": {"folderUid": "team"}, "dashboard": {"id": 7, "uid": "src", "title": "Source", "version": 3,
+				"panels": [{"datasource": {"uid": "staging"}}]}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/datasources/uid/prod":
+			_, _ = w.Write([]byte(`{"uid": "prod", "name": "Prod", "type": "prometheus"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+			_, _ = w.Write([]byte(`{"uid": "copy", "url": "/d/copy/copy", "status": "success", "id": 8, "version": 1}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	result, err := cloneDashboard(ctx, CloneDashboardParams{UID: "src", Title: "Copy", DatasourceMap: map[string]string{"staging": "prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, "copy", result.UID)
+	assert.Equal(t, "/d/copy/copy", result.URL)
+	assert.Equal(t, "team", result.FolderUID)
+	assert.Equal(t, 1, result.DatasourcesRemapped)
+
+	assert.Equal(t, "team", saved["folderUid"])
+	assert.NotEqual(t, true, saved["overwrite"])
+	dashboard := saved["dashboard"].(map[string]interface{})
+	assert.NotContains(t, dashboard, "id")
+	assert.NotContains(t, dashboard, "version")
+	assert.Equal(t, "", dashboard["uid"])
+	assert.Equal(t, "Copy", dashboard["title"])
+	assert.Equal(t, "prod", dashboard["panels"].([]interface{})[0].(map[string]interface{})["datasource"].(map[string]interface{})["uid"])
+
+	_, err = cloneDashboard(ctx, CloneDashboardParams{UID: "src", Title: "Copy", NewUID: "src"})
+	assert.Error(t, err)
+}
+
+func TestMoveDashboards(t *testing.T) {
+	var savedFolders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/a":
+			_, _ = w.Write([]byte(`{"meta": {"folderUid": "old"}, "dashboard": {"uid": "a", "title": "A"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/b":
+			_, _ = w.Write([]byte(`{"meta": {"folderUid": "new"}, "dashboard": {"uid": "b", "title": "B"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, true, body["overwrite"])
+			savedFolders = append(savedFolders, body["folderUid"].(string))
+			_, _ = w.Write([]byte(`{"uid": "a", "status": "success"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Dashboard not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	results, err := moveDashboards(mockCtxWithClient(server), MoveDashboardsParams{UIDs: []string{"a", "b", "missing"}, FolderUID: "new"})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.True(t, results[0].Moved)
+	assert.Equal(t, "old", results[0].FromFolderUID)
+	assert.False(t, results[1].Moved)
+	assert.Empty(t, results[1].Error)
+	assert.NotEmpty(t, results[2].Error)
+	assert.Equal(t, []string{"new"}, savedFolders)
+}
@@ -0,0 +1,430 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	lintSeverityError   = "error"
+	lintSeverityWarning = "warning"
+	lintSeverityInfo    = "info"
+)
+
+// Rule IDs reported by lint_dashboard.
+const (
+	lintRuleHardcodedDatasource = "hardcoded-datasource"
+	lintRuleMissingRateInterval = "missing-rate-interval"
+	lintRulePanelTitle          = "panel-title"
+	lintRulePanelUnit           = "panel-unit"
+	lintRuleOverlappingGridPos  = "overlapping-gridpos"
+	lintRuleUnusedVariable      = "unused-variable"
+	lintRuleDeprecatedPanelType = "deprecated-panel-type"
+	lintRuleInvalidPromQL       = "invalid-promql"
+	lintRuleInvalidLogQL        = "invalid-logql"
+)
+
+// deprecatedPanelTypes maps deprecated panel plugin IDs to their replacements.
+var deprecatedPanelTypes = map[string]string{
+	"graph":                    "timeseries",
+	"singlestat":               "stat",
+	"table-old":                "table",
+	"grafana-piechart-panel":   "piechart",
+	"grafana-worldmap-panel":   "geomap",
+	"grafana-singlestat-panel": "stat",
+	"natel-discrete-panel":     "state-timeline",
+}
+
+// unitPanelTypes are the panel types that display numeric values and should
+// declare a unit.
+var unitPanelTypes = map[string]bool{
+	"timeseries": true,
+	"stat":       true,
+	"gauge":      true,
+	"bargauge":   true,
+	"barchart":   true,
+	"graph":      true,
+}
+
+// builtinDatasourceUIDs are datasource references that are not tied to a
+// concrete datasource instance and are fine to hard-code.
+var builtinDatasourceUIDs = map[string]bool{
+	"":                true,
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+}
+
+var (
+	// templateVariableRegex matches $var, ${var}, ${var:format} and [[var]] references.
+	templateVariableRegex = regexp.MustCompile(`\$\{[^}]+\}|\$[A-Za-z_][A-Za-z0-9_]*|\[\[[^\]]+\]\]`)
+	// legacyVariableRegex matches the deprecated [[var]] variable syntax.
+	legacyVariableRegex = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+	// promQLRangeRegex matches a range or subquery selector, e.g. [5m] or [$__range:$__interval].
+	promQLRangeRegex = regexp.MustCompile(`\[[^\[\]]*\]`)
+	// promQLOffsetVariableRegex matches an offset modifier using a template variable.
+	promQLOffsetVariableRegex = regexp.MustCompile(`offset\s+(\$\{[^}]+\}|\$[A-Za-z_][A-Za-z0-9_]*)`)
+	// rateRangeRegex matches the range selector of rate-like functions.
+	rateRangeRegex = regexp.MustCompile(`\b(rate|irate|increase)\s*\([^\[\]]*\[([^\]]+)\]`)
+)
+
+// interpolatePromQLVariables replaces Grafana template variables in a PromQL
+// expression with placeholders so that it can be parsed locally. Variables
+// used as durations become `5m`; all other variables become `__var__`.
+func interpolatePromQLVariables(expr string) string {
+	expr = legacyVariableRegex.ReplaceAllString(expr, "$${$1}")
+	expr = promQLRangeRegex.ReplaceAllStringFunc(expr, func(s string) string {
+		return templateVariableRegex.ReplaceAllString(s, "5m")
+	})
+	expr = promQLOffsetVariableRegex.ReplaceAllString(expr, "offset 5m")
+	return templateVariableRegex.ReplaceAllString(expr, "__var__")
+}
+
+// validateLogQLSelector performs a local, best-effort syntax check of a LogQL
+// query: brackets must be balanced and the stream selector must be a valid
+// set of label matchers.
+func validateLogQLSelector(expr string) error {
+	expr = interpolatePromQLVariables(expr)
+	var stack []rune
+	inString, escaped := rune(0), false
+	for i, r := range expr {
+		if inString != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && inString != '`':
+				escaped = true
+			case r == inString:
+				inString = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '`':
+			inString = r
+		case '(', '{', '[':
+			stack = append(stack, r)
+		case ')', '}', ']':
+			open := map[rune]rune{')': '(', '}': '{', ']': '['}[r]
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("unexpected %q at position %d", r, i)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if inString != 0 {
+		return fmt.Errorf("unterminated string")
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1])
+	}
+
+	start := strings.Index(expr, "{")
+	if start < 0 {
+		return fmt.Errorf("missing stream selector")
+	}
+	end := strings.Index(expr[start:], "}")
+	if _, err := parser.ParseMetricSelector(expr[start : start+end+1]); err != nil {
+		return fmt.Errorf("invalid stream selector: %w", err)
+	}
+	return nil
+}
+
+type LintDashboardParams struct {
+	UID       string                 `json:"uid,omitempty" jsonschema:"description=The UID of the dashboard to lint. Either uid or dashboard must be provided."`
+	Dashboard map[string]interface{} `json:"dashboard,omitempty" jsonschema:"description=The dashboard JSON to lint. Use this to check a dashboard before saving it."`
+}
+
+// LintFinding is a single issue reported by lint_dashboard.
+type LintFinding struct {
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Panel    string `json:"panel,omitempty"`
+	Message  string `json:"message"`
+}
+
+// DashboardLintResult is the result of linting a dashboard.
+type DashboardLintResult struct {
+	UID      string         `json:"uid,omitempty"`
+	Title    string         `json:"title"`
+	Findings []LintFinding  `json:"findings"`
+	Counts   map[string]int `json:"counts"`
+}
+
+func lintDashboard(ctx context.Context, args LintDashboardParams) (*DashboardLintResult, error) {
+	db := args.Dashboard
+	if db == nil {
+		if args.UID == "" {
+			return nil, fmt.Errorf("either uid or dashboard must be provided")
+		}
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+		if err != nil {
+			return nil, fmt.Errorf("get dashboard by uid: %w", err)
+		}
+		var ok bool
+		db, ok = dashboard.Dashboard.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("dashboard is not a JSON object")
+		}
+	}
+
+	if isDashboardV2(db) {
+		return nil, fmt.Errorf("linting v2 dashboards is not supported")
+	}
+
+	result := &DashboardLintResult{
+		UID:      safeString(db, "uid"),
+		Title:    safeString(db, "title"),
+		Findings: lintDashboardJSON(db),
+		Counts:   map[string]int{},
+	}
+	for _, f := range result.Findings {
+		result.Counts[f.Severity]++
+	}
+	return result, nil
+}
+
+// lintDashboardJSON runs all lint rules against a classic dashboard JSON model.
+func lintDashboardJSON(db map[string]interface{}) []LintFinding {
+	findings := []LintFinding{}
+	dsVariables := datasourceVariables(db)
+
+	panels := collectPanels(db)
+	for _, p := range panels {
+		findings = append(findings, lintPanel(p, dsVariables)...)
+	}
+	findings = append(findings, lintOverlappingPanels(panels)...)
+	findings = append(findings, lintUnusedVariables(db)...)
+	sortLintFindings(findings)
+	return findings
+}
+
+func lintPanel(p dashboardPanel, dsVariables map[string]datasourceInfo) []LintFinding {
+	var findings []LintFinding
+	panelType := safeString(p.Panel, "type")
+	title := safeString(p.Panel, "title")
+	add := func(rule, severity, path, msg string) {
+		findings = append(findings, LintFinding{RuleID: rule, Severity: severity, Path: path, Panel: title, Message: msg})
+	}
+
+	if replacement, ok := deprecatedPanelTypes[panelType]; ok {
+		add(lintRuleDeprecatedPanelType, lintSeverityWarning, p.Path+".type",
+			fmt.Sprintf("panel type '%s' is deprecated; use '%s' instead", panelType, replacement))
+	}
+	if panelType == "row" {
+		return findings
+	}
+	if strings.TrimSpace(title) == "" && safeObject(p.Panel, "libraryPanel") == nil {
+		add(lintRulePanelTitle, lintSeverityWarning, p.Path+".title", "panel has no title")
+	}
+	if unitPanelTypes[panelType] && panelUnit(p.Panel) == "" {
+		add(lintRulePanelUnit, lintSeverityInfo, p.Path+".fieldConfig.defaults.unit", "panel does not declare a unit")
+	}
+
+	panelDS := parseDatasourceRef(p.Panel["datasource"])
+	if isHardcodedDatasource(panelDS) {
+		add(lintRuleHardcodedDatasource, lintSeverityWarning, p.Path+".datasource",
+			fmt.Sprintf("datasource '%s' is hard-coded; use a datasource variable instead", panelDS.UID))
+	}
+
+	for i, t := range safeArray(p.Panel, "targets") {
+		target, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		targetPath := fmt.Sprintf("%s.targets[%d]", p.Path, i)
+		targetDS := panelDS
+		if ds, ok := target["datasource"]; ok && ds != nil {
+			targetDS = parseDatasourceRef(ds)
+			if targetDS.UID != panelDS.UID && isHardcodedDatasource(targetDS) {
+				add(lintRuleHardcodedDatasource, lintSeverityWarning, targetPath+".datasource",
+					fmt.Sprintf("datasource '%s' is hard-coded; use a datasource variable instead", targetDS.UID))
+			}
+		}
+
+		expr := safeString(target, "expr")
+		if expr == "" {
+			continue
+		}
+		exprPath := targetPath + ".expr"
+		dsType := resolveDatasource(targetDS, dsVariables).Type
+		if dsType == "" {
+			dsType = resolveDatasource(panelDS, dsVariables).Type
+		}
+		switch {
+		case strings.Contains(dsType, "loki"):
+			if err := validateLogQLSelector(expr); err != nil {
+				add(lintRuleInvalidLogQL, lintSeverityError, exprPath, err.Error())
+			}
+		case dsType == "" || strings.Contains(dsType, "prometheus"):
+			if _, err := parser.ParseExpr(interpolatePromQLVariables(expr)); err != nil {
+				add(lintRuleInvalidPromQL, lintSeverityError, exprPath, err.Error())
+			}
+			for _, m := range rateRangeRegex.FindAllStringSubmatch(expr, -1) {
+				if r := strings.TrimSpace(m[2]); r != "$__rate_interval" && r != "${__rate_interval}" {
+					add(lintRuleMissingRateInterval, lintSeverityInfo, exprPath,
+						fmt.Sprintf("%s() uses range [%s]; prefer [$__rate_interval]", m[1], r))
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// panelUnit returns the unit configured for a panel, falling back to the
+// left Y axis format of legacy graph panels.
+func panelUnit(panel map[string]interface{}) string {
+	if unit := safeString(safeObject(safeObject(panel, "fieldConfig"), "defaults"), "unit"); unit != "" {
+		return unit
+	}
+	if yaxes := safeArray(panel, "yaxes"); len(yaxes) > 0 {
+		if axis, ok := yaxes[0].(map[string]interface{}); ok {
+			if format := safeString(axis, "format"); format != "short" {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+func isHardcodedDatasource(ds datasourceInfo) bool {
+	return !builtinDatasourceUIDs[ds.UID] && !isTemplateVariable(ds.UID)
+}
+
+type gridPos struct {
+	X, Y, W, H int
+}
+
+func (g gridPos) overlaps(o gridPos) bool {
+	return g.X < o.X+o.W && o.X < g.X+g.W && g.Y < o.Y+o.H && o.Y < g.Y+g.H
+}
+
+func panelGridPos(panel map[string]interface{}) (gridPos, bool) {
+	pos := safeObject(panel, "gridPos")
+	if pos == nil {
+		return gridPos{}, false
+	}
+	return gridPos{X: safeInt(pos, "x"), Y: safeInt(pos, "y"), W: safeInt(pos, "w"), H: safeInt(pos, "h")}, true
+}
+
+// lintOverlappingPanels reports panels whose gridPos overlap. Panels are only
+// compared against other panels in the same layout scope: the top level, or
+// the panels of a single collapsed row.
+func lintOverlappingPanels(panels []dashboardPanel) []LintFinding {
+	type placed struct {
+		dashboardPanel
+		pos gridPos
+	}
+	scopes := map[string][]placed{}
+	var order []string
+	for _, p := range panels {
+		pos, ok := panelGridPos(p.Panel)
+		if !ok {
+			continue
+		}
+		scope := ""
+		if idx := strings.LastIndex(p.Path, ".panels["); idx > 0 {
+			scope = p.Path[:idx]
+		}
+		if _, ok := scopes[scope]; !ok {
+			order = append(order, scope)
+		}
+		scopes[scope] = append(scopes[scope], placed{p, pos})
+	}
+
+	var findings []LintFinding
+	for _, scope := range order {
+		items := scopes[scope]
+		for i := 0; i < len(items); i++ {
+			for j := i + 1; j < len(items); j++ {
+				if items[i].pos.overlaps(items[j].pos) {
+					findings = append(findings, LintFinding{
+						RuleID:   lintRuleOverlappingGridPos,
+						Severity: lintSeverityWarning,
+						Path:     items[j].Path + ".gridPos",
+						Panel:    safeString(items[j].Panel, "title"),
+						Message:  fmt.Sprintf("panel overlaps with '%s' at %s", safeString(items[i].Panel, "title"), items[i].Path),
+					})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// lintUnusedVariables reports template variables that are not referenced
+// anywhere else in the dashboard, including other variables' definitions.
+func lintUnusedVariables(db map[string]interface{}) []LintFinding {
+	variables := safeArray(safeObject(db, "templating"), "list")
+	if len(variables) == 0 {
+		return nil
+	}
+
+	rest := make(map[string]interface{}, len(db))
+	for k, v := range db {
+		if k != "templating" {
+			rest[k] = v
+		}
+	}
+	restJSON, _ := json.Marshal(rest)
+
+	var findings []LintFinding
+	for i, v := range variables {
+		variable, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := safeString(variable, "name")
+		// Ad hoc filters are applied implicitly and never referenced by name.
+		if name == "" || safeString(variable, "type") == "adhoc" {
+			continue
+		}
+
+		others := make([]interface{}, 0, len(variables)-1)
+		others = append(others, variables[:i]...)
+		others = append(others, variables[i+1:]...)
+		othersJSON, _ := json.Marshal(others)
+
+		ref := regexp.MustCompile(`\$` + regexp.QuoteMeta(name) + `\b|\$\{` + regexp.QuoteMeta(name) + `[}:.]|\[\[` + regexp.QuoteMeta(name) + `[\]:]`)
+		if !ref.Match(restJSON) && !ref.Match(othersJSON) {
+			findings = append(findings, LintFinding{
+				RuleID:   lintRuleUnusedVariable,
+				Severity: lintSeverityInfo,
+				Path:     fmt.Sprintf("$.templating.list[%d]", i),
+				Message:  fmt.Sprintf("variable '%s' is not used by any panel or variable", name),
+			})
+		}
+	}
+	return findings
+}
+
+// sortLintFindings orders findings by severity, then path.
+func sortLintFindings(findings []LintFinding) {
+	rank := map[string]int{lintSeverityError: 0, lintSeverityWarning: 1, lintSeverityInfo: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if rank[findings[i].Severity] != rank[findings[j].Severity] {
+			return rank[findings[i].Severity] < rank[findings[j].Severity]
+		}
+		return findings[i].Path < findings[j].Path
+	})
+}
+
+var LintDashboard = mcpgrafana.MustTool(
+	"lint_dashboard",
+	"Check a dashboard for common problems and best-practice violations before it ships. Accepts a dashboard UID or dashboard JSON. Reports hard-coded datasource UIDs instead of variables\\, rate functions not using $__rate_interval\\, panels without titles or units\\, overlapping gridPos\\, unused variables\\, deprecated panel types and invalid PromQL/LogQL (parsed locally). Each finding includes a rule ID\\, severity and the JSONPath of the offending element. Only classic (non-v2) dashboards are supported.",
+	lintDashboard,
+	mcp.WithTitleAnnotation("Lint dashboard"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,131 @@
+//go:build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintTestDashboard = `{
+	"uid": "lint-test",
+	"title": "Lint test",
+	"templating": {
+		"list": [
+			{"name": "datasource", "type": "datasource", "query": "prometheus"},
+			{"name": "logs", "type": "datasource", "query": "loki"},
+			{"name": "job", "type": "query", "query": "label_values(up, job)"},
+			{"name": "unused", "type": "custom", "query": "a,b"},
+			{"name": "filters", "type": "adhoc"}
+		]
+	},
+	"panels": [
+		{
+			"id": 1, "type": "timeseries", "title": "Requests",
+			"gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+			"datasource": {"type": "prometheus", "uid": "$datasource"},
+			"fieldConfig": {"defaults": {"unit": "reqps"}},
+			"targets": [{"expr": "sum(rate(http_requests_total{job=\"$job\"}[$__rate_interval]))"}]
+		},
+		{
+			"id": 2, "type": "graph", "title": "",
+			"gridPos": {"x": 6, "y": 4, "w": 12, "h": 8},
+			"datasource": {"type": "prometheus", "uid": "P1809F7CD0C75ACF3"},
+			"targets": [{"expr": "rate(http_requests_total[5m])"}]
+		},
+		{
+			"id": 3, "type": "row", "title": "Logs", "collapsed": true,
+			"gridPos": {"x": 0, "y": 12, "w": 24, "h": 1},
+			"panels": [
+				{
+					"id": 4, "type": "logs", "title": "Errors",
+					"gridPos": {"x": 0, "y": 13, "w": 24, "h": 8},
+					"datasource": {"uid": "${logs}"},
+					"targets": [{"expr": "{app=\"api\" |= \"error\""}]
+				}
+			]
+		},
+		{
+			"id": 5, "type": "stat", "title": "Broken",
+			"gridPos": {"x": 0, "y": 21, "w": 6, "h": 4},
+			"datasource": {"uid": "$datasource"},
+			"fieldConfig": {"defaults": {"unit": "short"}},
+			"targets": [{"expr": "sum(rate(foo[5m])"}]
+		}
+	]
+}`
+
+func findingsByRule(findings []LintFinding) map[string][]LintFinding {
+	result := map[string][]LintFinding{}
+	for _, f := range findings {
+		result[f.RuleID] = append(result[f.RuleID], f)
+	}
+	return result
+}
+
+func TestLintDashboardJSON(t *testing.T) {
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lintTestDashboard), &db))
+
+	findings := findingsByRule(lintDashboardJSON(db))
+
+	require.Len(t, findings[lintRuleHardcodedDatasource], 1)
+	assert.Equal(t, "$.panels[1].datasource", findings[lintRuleHardcodedDatasource][0].Path)
+
+	require.Len(t, findings[lintRuleMissingRateInterval], 2)
+	assert.Equal(t, "$.panels[1].targets[0].expr", findings[lintRuleMissingRateInterval][0].Path)
+
+	require.Len(t, findings[lintRulePanelTitle], 1)
+	assert.Equal(t, "$.panels[1].title", findings[lintRulePanelTitle][0].Path)
+
+	require.Len(t, findings[lintRulePanelUnit], 1)
+	assert.Equal(t, "$.panels[1].fieldConfig.defaults.unit", findings[lintRulePanelUnit][0].Path)
+
+	require.Len(t, findings[lintRuleOverlappingGridPos], 1)
+	assert.Equal(t, "$.panels[1].gridPos", findings[lintRuleOverlappingGridPos][0].Path)
+
+	require.Len(t, findings[lintRuleUnusedVariable], 1)
+	assert.Equal(t, "$.templating.list[3]", findings[lintRuleUnusedVariable][0].Path)
+
+	require.Len(t, findings[lintRuleDeprecatedPanelType], 1)
+	assert.Contains(t, findings[lintRuleDeprecatedPanelType][0].Message, "timeseries")
+
+	require.Len(t, findings[lintRuleInvalidLogQL], 1)
+	assert.Equal(t, "$.panels[2].panels[0].targets[0].expr", findings[lintRuleInvalidLogQL][0].Path)
+
+	require.Len(t, findings[lintRuleInvalidPromQL], 1)
+	assert.Equal(t, "$.panels[3].targets[0].expr", findings[lintRuleInvalidPromQL][0].Path)
+	assert.Equal(t, lintSeverityError, findings[lintRuleInvalidPromQL][0].Severity)
+}
+
+func TestLintDashboard_RequiresInput(t *testing.T) {
+	_, err := lintDashboard(context.Background(), LintDashboardParams{})
+	assert.Error(t, err)
+}
+
+func TestInterpolatePromQLVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`rate(foo{job="$job"}[$__rate_interval])`, `rate(foo{job="__var__"}[5m])`},
+		{`sum by ($groupby) (rate(foo[${__interval}]))`, `sum by (__var__) (rate(foo[5m]))`},
+		{`max_over_time(foo[$__range:$__interval])`, `max_over_time(foo[5m:5m])`},
+		{`foo offset $shift > [[threshold]]`, `foo offset 5m > __var__`},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, interpolatePromQLVariables(tc.input))
+	}
+}
+
+func TestValidateLogQLSelector(t *testing.T) {
+	assert.NoError(t, validateLogQLSelector(`{app="api"} |= "error" | json`))
+	assert.NoError(t, validateLogQLSelector(`sum by (level) (count_over_time({app="$app"} |~ "[a-z]+" [$__interval]))`))
+	assert.Error(t, validateLogQLSelector(`{app="api" |= "error"`))
+	assert.Error(t, validateLogQLSelector(`count_over_time([5m])`))
+	assert.Error(t, validateLogQLSelector(`{app=api}`))
+}
@@ -0,0 +1,556 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/client/dashboards"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type CreateDashboardSnapshotParams struct {
+	DashboardUID string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard to snapshot"`
+	Name         string `json:"name,omitempty" jsonschema:"description=Name of the snapshot. Defaults to the dashboard title."`
+	From         string `json:"from,omitempty" jsonschema:"default=now-6h,description=Start of the snapshot time range. Supports RFC3339 and relative times like 'now-6h'."`
+	To           string `json:"to,omitempty" jsonschema:"default=now,description=End of the snapshot time range. Supports RFC3339 and relative times like 'now'."`
+	Expires      string `json:"expires,omitempty" jsonschema:"description=How long the snapshot is kept\\, e.g. '1h'\\, '7d'. Leave empty to keep it forever."`
+}
+
+func (p CreateDashboardSnapshotParams) validate() error {
+	if p.DashboardUID == "" {
+		return fmt.Errorf("dashboardUid is required")
+	}
+	return nil
+}
+
+// CreateDashboardSnapshotResult describes a created snapshot.
+type CreateDashboardSnapshotResult struct {
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	DeleteKey string     `json:"deleteKey,omitempty"`
+	DeleteURL string     `json:"deleteUrl,omitempty"`
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	Panels    int        `json:"panels"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// snapshotDataFrame is the DataFrameDTO shape Grafana stores in a panel's
+// snapshotData.
+type snapshotDataFrame struct {
+	RefID  string               `json:"refId,omitempty"`
+	Name   string               `json:"name,omitempty"`
+	Meta   *data.FrameMeta      `json:"meta,omitempty"`
+	Fields []snapshotFieldValue `json:"fields"`
+}
+
+type snapshotFieldValue struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Config *data.FieldConfig `json:"config,omitempty"`
+	Labels data.Labels       `json:"labels,omitempty"`
+	Values []interface{}     `json:"values"`
+}
+
+// snapshotFieldType maps SDK field types to Grafana frontend field types.
+func snapshotFieldType(t data.FieldType) string {
+	switch {
+	case t.Time():
+		return "time"
+	case t.Numeric():
+		return "number"
+	case t.NonNullableType() == data.FieldTypeString:
+		return "string"
+	case t.NonNullableType() == data.FieldTypeBool:
+		return "boolean"
+	default:
+		return "other"
+	}
+}
+
+// toSnapshotFrame converts a data frame to the shape used by snapshotData.
+// Time values are stored as epoch milliseconds.
+func toSnapshotFrame(refID string, frame *data.Frame) snapshotDataFrame {
+	out := snapshotDataFrame{RefID: refID, Name: frame.Name, Meta: frame.Meta, Fields: make([]snapshotFieldValue, 0, len(frame.Fields))}
+	for _, f := range frame.Fields {
+		field := snapshotFieldValue{Name: f.Name, Type: snapshotFieldType(f.Type()), Config: f.Config, Labels: f.Labels, Values: make([]interface{}, f.Len())}
+		for i := 0; i < f.Len(); i++ {
+			v, ok := f.ConcreteAt(i)
+			if !ok {
+				continue
+			}
+			if t, isTime := v.(time.Time); isTime {
+				v = t.UnixMilli()
+			}
+			field.Values[i] = v
+		}
+		out.Fields = append(out.Fields, field)
+	}
+	return out
+}
+
+// snapshotPanelData executes the visible queries of a panel and returns its
+// snapshotData. Datasources referenced by name are resolved to their UID.
+func snapshotPanelData(ctx context.Context, p dashboardPanel, variables map[string]datasourceInfo, values map[string][]string, datasources datasourceIndex, defaultDS *models.DataSourceListItemDTO, from, to time.Time) ([]snapshotDataFrame, error) {
+	panelQueries := extractPanelQueries(p, variables)
+	takenRefIDs := map[string]bool{}
+	for _, q := range panelQueries {
+		if refID, _ := q.Target["refId"].(string); refID != "" {
+			takenRefIDs[refID] = true
+		}
+	}
+	nextRefID := 0
+
+	var queries []map[string]interface{}
+	for _, q := range panelQueries {
+		if hidden, _ := q.Target["hide"].(bool); hidden {
+			continue
+		}
+		ref := q.Datasource
+		if ref.UID == "" || ref.UID == "default" {
+			if defaultDS == nil {
+				return nil, fmt.Errorf("panel uses the default datasource but no default datasource is configured")
+			}
+			ref = datasourceInfo{UID: defaultDS.UID, Type: defaultDS.Type}
+		}
+		if isTemplateVariable(ref.UID) {
+			return nil, fmt.Errorf("datasource variable %s has no current value", ref.UID)
+		}
+		if builtinDatasourceUIDs[ref.UID] || ref.Type == "datasource" {
+			continue
+		}
+		if ds, ok := datasources[ref.UID]; ok {
+			ref = datasourceInfo{UID: ds.UID, Type: ds.Type}
+		} else if ref.UID != "__expr__" {
+			return nil, fmt.Errorf("datasource %s does not exist", ref.UID)
+		}
+		query := make(map[string]interface{}, len(q.Target)+2)
+		for k, v := range q.Target {
+			if s, ok := v.(string); ok {
+				v = interpolateDashboardVariables(s, values)
+			}
+			query[k] = v
+		}
+		if refID, _ := query["refId"].(string); refID == "" {
+			for takenRefIDs[refIDForIndex(nextRefID)] {
+				nextRefID++
+			}
+			query["refId"] = refIDForIndex(nextRefID)
+			takenRefIDs[query["refId"].(string)] = true
+		}
+		query["datasource"] = map[string]string{"uid": ref.UID, "type": ref.Type}
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	resp, err := queryDatasources(ctx, dsQueryRequest{
+		From:    fmt.Sprintf("%d", from.UnixMilli()),
+		To:      fmt.Sprintf("%d", to.UnixMilli()),
+		Queries: queries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var frames []snapshotDataFrame
+	var errs []string
+	for _, q := range queries {
+		refID := q["refId"].(string)
+		result := resp.Results[refID]
+		if result.Error != "" {
+			errs = append(errs, fmt.Sprintf("%s: %s", refID, result.Error))
+		}
+		for _, frame := range result.Frames {
+			frames = append(frames, toSnapshotFrame(refID, frame))
+		}
+	}
+	if len(errs) > 0 {
+		return frames, errors.New(strings.Join(errs, "; "))
+	}
+	return frames, nil
+}
+
+func createDashboardSnapshot(ctx context.Context, args CreateDashboardSnapshotParams) (*CreateDashboardSnapshotResult, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: %w", err)
+	}
+	if args.From == "" {
+		args.From = "now-6h"
+	}
+	if args.To == "" {
+		args.To = "now"
+	}
+	from, err := parseTime(args.From)
+	if err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: parsing start time: %w", err)
+	}
+	to, err := parseTime(args.To)
+	if err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: parsing end time: %w", err)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("create dashboard snapshot: start time must be before end time")
+	}
+	var expires time.Duration
+	if args.Expires != "" && args.Expires != "0" {
+		expires, err = gtime.ParseDuration(args.Expires)
+		if err != nil || expires <= 0 {
+			return nil, fmt.Errorf("create dashboard snapshot: invalid expires %q", args.Expires)
+		}
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: %w", err)
+	}
+	db, ok := dashboard.Dashboard.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("create dashboard snapshot: dashboard is not a JSON object")
+	}
+	if isDashboardV2(db) {
+		return nil, fmt.Errorf("create dashboard snapshot: dashboard %s uses the v2 schema, which cannot be snapshotted", args.DashboardUID)
+	}
+	if err := newLibraryPanelResolver().resolveDashboard(ctx, db); err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: %w", err)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	dsResp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: list datasources: %w", err)
+	}
+	datasources := indexDatasources(dsResp.Payload)
+	var defaultDS *models.DataSourceListItemDTO
+	for _, ds := range dsResp.Payload {
+		if ds.IsDefault {
+			defaultDS = ds
+		}
+	}
+
+	result := &CreateDashboardSnapshotResult{From: from, To: to}
+	variables := datasourceVariables(db)
+	values := dashboardVariableValues(db)
+	for _, p := range collectPanels(db) {
+		if safeString(p.Panel, "type") == "row" {
+			continue
+		}
+		frames, err := snapshotPanelData(ctx, p, variables, values, datasources, defaultDS, from, to)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("panel %d (%s): %s", safeInt(p.Panel, "id"), safeString(p.Panel, "title"), err))
+		}
+		if frames == nil {
+			frames = []snapshotDataFrame{}
+		}
+		// Snapshots are rendered from the stored data only.
+		p.Panel["snapshotData"] = frames
+		delete(p.Panel, "targets")
+		delete(p.Panel, "libraryPanel")
+		result.Panels++
+	}
+	db["time"] = map[string]interface{}{"from": from.UTC().Format(time.RFC3339), "to": to.UTC().Format(time.RFC3339)}
+	db["snapshot"] = map[string]interface{}{"timestamp": time.Now().UTC().Format(time.RFC3339)}
+
+	name := args.Name
+	if name == "" {
+		name = safeString(db, "title")
+	}
+	resp, err := c.Dashboards.CreateDashboardSnapshot(&models.CreateDashboardSnapshotCommand{
+		Dashboard: db,
+		Name:      name,
+		Expires:   int64(expires.Seconds()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create dashboard snapshot: %w", err)
+	}
+	result.Key = resp.Payload.Key
+	result.URL = resp.Payload.URL
+	result.DeleteKey = resp.Payload.DeleteKey
+	result.DeleteURL = resp.Payload.DeleteURL
+	if expires > 0 {
+		t := time.Now().Add(expires).UTC()
+		result.Expires = &t
+	}
+	return result, nil
+}
+
+var CreateDashboardSnapshot = mcpgrafana.MustTool(
+	"create_dashboard_snapshot",
+	"Creates a snapshot of a dashboard for a fixed time range. The queries of every panel are executed and their results are stored in the snapshot, so it can be shared without access to the underlying datasources. Library panels are resolved. Dashboards using the v2 schema are not supported. Returns the snapshot key, URL and delete key.",
+	createDashboardSnapshot,
+	mcp.WithTitleAnnotation("Create dashboard snapshot"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+)
+
+type ListSnapshotsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Only return snapshots whose name contains this string"`
+	Limit int    `json:"limit,omitempty" jsonschema:"default=100,description=Maximum number of snapshots to return"`
+}
+
+// SnapshotSummary describes a dashboard snapshot.
+type SnapshotSummary struct {
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url,omitempty"`
+	External    bool       `json:"external,omitempty"`
+	ExternalURL string     `json:"externalUrl,omitempty"`
+	Created     time.Time  `json:"created"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+func listSnapshots(ctx context.Context, args ListSnapshotsParams) ([]SnapshotSummary, error) {
+	if args.Limit <= 0 {
+		args.Limit = 100
+	}
+	limit := int64(args.Limit)
+	params := dashboards.NewSearchDashboardSnapshotsParams().WithContext(ctx).WithLimit(&limit)
+	if args.Query != "" {
+		params.SetQuery(&args.Query)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Dashboards.SearchDashboardSnapshots(params)
+	if err != nil {
+		return nil, fmt.Errorf("list snapshots: %w", err)
+	}
+
+	baseURL := strings.TrimRight(mcpgrafana.GrafanaConfigFromContext(ctx).URL, "/")
+	snapshots := make([]SnapshotSummary, 0, len(resp.Payload))
+	for _, s := range resp.Payload {
+		summary := SnapshotSummary{
+			Key:         s.Key,
+			Name:        s.Name,
+			External:    s.External,
+			ExternalURL: s.ExternalURL,
+			Created:     time.Time(s.Created),
+		}
+		if !s.External && baseURL != "" {
+			summary.URL = baseURL + "/dashboard/snapshot/" + s.Key
+		}
+		// Snapshots without expiry are stored with an expiry far in the future.
+		if expires := time.Time(s.Expires); !expires.IsZero() && expires.Before(time.Now().AddDate(50, 0, 0)) {
+			summary.Expires = &expires
+		}
+		snapshots = append(snapshots, summary)
+	}
+	return snapshots, nil
+}
+
+var ListSnapshots = mcpgrafana.MustTool(
+	"list_snapshots",
+	"Lists dashboard snapshots, optionally filtered by name. Returns the key, name, URL, creation time and expiry of each snapshot.",
+	listSnapshots,
+	mcp.WithTitleAnnotation("List dashboard snapshots"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type DeleteSnapshotParams struct {
+	Key string `json:"key" jsonschema:"required,description=The key of the snapshot to delete"`
+}
+
+func deleteSnapshot(ctx context.Context, args DeleteSnapshotParams) (string, error) {
+	if args.Key == "" {
+		return "", fmt.Errorf("delete snapshot: key is required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.Dashboards.DeleteDashboardSnapshot(args.Key); err != nil {
+		return "", fmt.Errorf("delete snapshot %s: %w", args.Key, err)
+	}
+	return fmt.Sprintf("Snapshot %s deleted successfully", args.Key), nil
+}
+
+var DeleteSnapshot = mcpgrafana.MustTool(
+	"delete_snapshot",
+	"Deletes a dashboard snapshot by its key.",
+	deleteSnapshot,
+	mcp.WithTitleAnnotation("Delete dashboard snapshot"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
+
+type GetPublicDashboardParams struct {
+	DashboardUID string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+}
+
+// PublicDashboardConfig describes the public sharing configuration of a
+// dashboard.
+type PublicDashboardConfig struct {
+	DashboardUID         string `json:"dashboardUid"`
+	Configured           bool   `json:"configured"`
+	UID                  string `json:"uid,omitempty"`
+	Enabled              bool   `json:"enabled"`
+	Share                string `json:"share,omitempty"`
+	TimeSelectionEnabled bool   `json:"timeSelectionEnabled"`
+	AnnotationsEnabled   bool   `json:"annotationsEnabled"`
+	AccessToken          string `json:"accessToken,omitempty"`
+	PublicURL            string `json:"publicUrl,omitempty"`
+}
+
+func newPublicDashboardConfig(ctx context.Context, dashboardUID string, pd *models.PublicDashboard) *PublicDashboardConfig {
+	config := &PublicDashboardConfig{DashboardUID: dashboardUID}
+	if pd == nil || pd.UID == "" {
+		return config
+	}
+	config.Configured = true
+	config.UID = pd.UID
+	config.Enabled = pd.IsEnabled
+	config.Share = string(pd.Share)
+	config.TimeSelectionEnabled = pd.TimeSelectionEnabled
+	config.AnnotationsEnabled = pd.AnnotationsEnabled
+	config.AccessToken = pd.AccessToken
+	if baseURL := strings.TrimRight(mcpgrafana.GrafanaConfigFromContext(ctx).URL, "/"); baseURL != "" && pd.AccessToken != "" {
+		config.PublicURL = baseURL + "/public-dashboards/" + pd.AccessToken
+	}
+	return config
+}
+
+// fetchPublicDashboard returns the public dashboard of a dashboard, or nil if
+// it has never been shared publicly.
+func fetchPublicDashboard(ctx context.Context, dashboardUID string) (*models.PublicDashboard, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Dashboards.GetPublicDashboard(dashboardUID)
+	if err != nil {
+		if notFound := (*dashboards.GetPublicDashboardNotFound)(nil); errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+func getPublicDashboard(ctx context.Context, args GetPublicDashboardParams) (*PublicDashboardConfig, error) {
+	if args.DashboardUID == "" {
+		return nil, fmt.Errorf("get public dashboard: dashboardUid is required")
+	}
+	pd, err := fetchPublicDashboard(ctx, args.DashboardUID)
+	if err != nil {
+		return nil, fmt.Errorf("get public dashboard %s: %w", args.DashboardUID, err)
+	}
+	return newPublicDashboardConfig(ctx, args.DashboardUID, pd), nil
+}
+
+var GetPublicDashboard = mcpgrafana.MustTool(
+	"get_public_dashboard",
+	"Returns the public sharing configuration of a dashboard: whether it is enabled, the share type, whether viewers can change the time range or see annotations, and the public URL. Returns configured=false if the dashboard has never been shared publicly.",
+	getPublicDashboard,
+	mcp.WithTitleAnnotation("Get public dashboard"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListPublicDashboardsParams struct{}
+
+// PublicDashboardSummary describes a publicly shared dashboard.
+type PublicDashboardSummary struct {
+	UID          string `json:"uid"`
+	DashboardUID string `json:"dashboardUid"`
+	Title        string `json:"title"`
+	Enabled      bool   `json:"enabled"`
+	PublicURL    string `json:"publicUrl,omitempty"`
+}
+
+func listPublicDashboards(ctx context.Context, args ListPublicDashboardsParams) ([]PublicDashboardSummary, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Dashboards.ListPublicDashboards()
+	if err != nil {
+		return nil, fmt.Errorf("list public dashboards: %w", err)
+	}
+	baseURL := strings.TrimRight(mcpgrafana.GrafanaConfigFromContext(ctx).URL, "/")
+	summaries := []PublicDashboardSummary{}
+	if resp.Payload == nil {
+		return summaries, nil
+	}
+	for _, pd := range resp.Payload.PublicDashboards {
+		summary := PublicDashboardSummary{UID: pd.UID, DashboardUID: pd.DashboardUID, Title: pd.Title, Enabled: pd.IsEnabled}
+		if baseURL != "" && pd.AccessToken != "" {
+			summary.PublicURL = baseURL + "/public-dashboards/" + pd.AccessToken
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+var ListPublicDashboards = mcpgrafana.MustTool(
+	"list_public_dashboards",
+	"Lists all dashboards that have public sharing configured, with their public URLs.",
+	listPublicDashboards,
+	mcp.WithTitleAnnotation("List public dashboards"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type SetPublicDashboardParams struct {
+	DashboardUID         string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	Enabled              *bool  `json:"enabled,omitempty" jsonschema:"description=Whether the public dashboard is accessible. Defaults to true when sharing is first configured."`
+	TimeSelectionEnabled *bool  `json:"timeSelectionEnabled,omitempty" jsonschema:"description=Whether viewers can change the time range"`
+	AnnotationsEnabled   *bool  `json:"annotationsEnabled,omitempty" jsonschema:"description=Whether annotations are shown"`
+	Share                string `json:"share,omitempty" jsonschema:"enum=public,enum=email,description=Who can access the dashboard: 'public' (anyone with the link) or 'email' (invited users only\\, Grafana Cloud/Enterprise)"`
+}
+
+func setPublicDashboard(ctx context.Context, args SetPublicDashboardParams) (*PublicDashboardConfig, error) {
+	if args.DashboardUID == "" {
+		return nil, fmt.Errorf("set public dashboard: dashboardUid is required")
+	}
+	existing, err := fetchPublicDashboard(ctx, args.DashboardUID)
+	if err != nil {
+		return nil, fmt.Errorf("set public dashboard %s: %w", args.DashboardUID, err)
+	}
+
+	body := &models.PublicDashboardDTO{IsEnabled: true, Share: "public"}
+	if existing != nil && existing.UID != "" {
+		body.IsEnabled = existing.IsEnabled
+		body.Share = existing.Share
+		body.TimeSelectionEnabled = existing.TimeSelectionEnabled
+		body.AnnotationsEnabled = existing.AnnotationsEnabled
+	}
+	if args.Enabled != nil {
+		body.IsEnabled = *args.Enabled
+	}
+	if args.TimeSelectionEnabled != nil {
+		body.TimeSelectionEnabled = *args.TimeSelectionEnabled
+	}
+	if args.AnnotationsEnabled != nil {
+		body.AnnotationsEnabled = *args.AnnotationsEnabled
+	}
+	if args.Share != "" {
+		body.Share = models.ShareType(args.Share)
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var pd *models.PublicDashboard
+	if existing == nil || existing.UID == "" {
+		resp, err := c.Dashboards.CreatePublicDashboard(args.DashboardUID, body)
+		if err != nil {
+			return nil, fmt.Errorf("create public dashboard %s: %w", args.DashboardUID, err)
+		}
+		pd = resp.Payload
+	} else {
+		params := dashboards.NewUpdatePublicDashboardParams().WithContext(ctx).
+			WithDashboardUID(args.DashboardUID).WithUID(existing.UID).WithBody(body)
+		resp, err := c.Dashboards.UpdatePublicDashboard(params)
+		if err != nil {
+			return nil, fmt.Errorf("update public dashboard %s: %w", args.DashboardUID, err)
+		}
+		pd = resp.Payload
+	}
+	return newPublicDashboardConfig(ctx, args.DashboardUID, pd), nil
+}
+
+var SetPublicDashboard = mcpgrafana.MustTool(
+	"set_public_dashboard",
+	"Enables, disables or updates public sharing of a dashboard. Creates the public dashboard if it does not exist yet (enabled by default); otherwise only the given settings are changed. Returns the resulting configuration and public URL.",
+	setPublicDashboard,
+	mcp.WithTitleAnnotation("Configure public dashboard"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+)
@@ -0,0 +1,191 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDashboardSnapshot(t *testing.T) {
+	var snapshot map[string]interface{}
+	var dsQueries []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/dashboards/uid/db-1":
+			_, _ = w.Write([]byte(`{"dashboard": {"uid": "db-1", "title": "API",
+				"templating": {"list": [{"name": "job", "type": "query", "current": {"value": "api"}}]},
+				"panels": [
+					{"id": 1, "type": "timeseries", "title": "Requests", "datasource": {"uid": "prom", "type": "prometheus"},
+						"targets": [{"refId": "A", "expr": "rate(requests_total{job=\"$job\"}[5m])"}, {"refId": "B", "expr": "hidden", "hide": true}]},
+					{"id": 2, "type": "row", "collapsed": true, "panels": [
+						{"id": 3, "type": "stat", "title": "Broken", "targets": [{"refId": "A", "expr": "broken"}]}
+					]},
+					{"id": 4, "type": "text", "title": "Notes"}
+				]}}`))
+		case r.URL.Path == "/api/datasources":
+			_, _ = w.Write([]byte(`[{"uid": "prom", "name": "Prometheus", "type": "prometheus", "isDefault": true}]`))
+		case r.URL.Path == "/api/ds/query":
+			var body dsQueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			dsQueries = append(dsQueries, body.Queries...)
+			if body.Queries[0]["expr"] == "broken" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"results": {"A": {"status": 400, "error": "parse error"}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"results": {"A": {"frames": [{
+				"schema": {"fields": [{"name": "Time", "type": "time", "typeInfo": {"frame": "time.Time"}},
+					{"name": "Value", "type": "number", "typeInfo": {"frame": "float64", "nullable": true}, "labels": {"job": "api"}}]},
+				"data": {"values": [[1700000000000, 1700000060000], [1.5, null]]}}]}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/snapshots":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&snapshot))
+			_, _ = w.Write([]byte(`{"key": "snap-1", "deleteKey": "del-1", "url": "http://grafana/dashboard/snapshot/snap-1", "deleteUrl": "http://grafana/api/snapshots-delete/del-1"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	result, err := createDashboardSnapshot(mockCtxWithGrafanaURL(server), CreateDashboardSnapshotParams{
+		DashboardUID: "db-1",
+		From:         "2023-11-14T00:00:00Z",
+		To:           "2023-11-15T00:00:00Z",
+		Expires:      "7d",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "snap-1", result.Key)
+	assert.Equal(t, "del-1", result.DeleteKey)
+	assert.Equal(t, 3, result.Panels)
+	require.NotNil(t, result.Expires)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), *result.Expires, time.Minute)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "panel 3 (Broken): A: parse error")
+
+	require.Len(t, dsQueries, 2)
+	assert.Equal(t, `rate(requests_total{job="api"}[5m])`, dsQueries[0]["expr"])
+
+	assert.Equal(t, "API", snapshot["name"])
+	assert.EqualValues(t, 7*24*3600, snapshot["expires"])
+	dashboard := snapshot["dashboard"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"from": "2023-11-14T00:00:00Z", "to": "2023-11-15T00:00:00Z"}, dashboard["time"])
+	panel := dashboard["panels"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, panel, "targets")
+	frames := panel["snapshotData"].([]interface{})
+	require.Len(t, frames, 1)
+	fields := frames[0].(map[string]interface{})["fields"].([]interface{})
+	assert.Equal(t, "time", fields[0].(map[string]interface{})["type"])
+	assert.Equal(t, []interface{}{1700000000000.0, 1700000060000.0}, fields[0].(map[string]interface{})["values"])
+	assert.Equal(t, []interface{}{1.5, nil}, fields[1].(map[string]interface{})["values"])
+	assert.Equal(t, map[string]interface{}{"job": "api"}, fields[1].(map[string]interface{})["labels"])
+
+	_, err = createDashboardSnapshot(mockCtxWithGrafanaURL(server), CreateDashboardSnapshotParams{DashboardUID: "db-1", Expires: "soon"})
+	assert.ErrorContains(t, err, "invalid expires")
+}
+
+func TestSnapshotPanelData_ResolvesLegacyDatasourceAndRefIDs(t *testing.T) {
+	var queries []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/ds/query", r.URL.Path)
+		var body dsQueryRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		queries = body.Queries
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results": {}}`))
+	}))
+	defer server.Close()
+
+	prom := &models.DataSourceListItemDTO{UID: "prom", Name: "Prometheus", Type: "prometheus"}
+	panel := dashboardPanel{Panel: map[string]interface{}{
+		"id":         1,
+		"datasource": "Prometheus",
+		"targets": []interface{}{
+			map[string]interface{}{"expr": "first"},
+			map[string]interface{}{"refId": "A", "expr": "second"},
+		},
+	}}
+	from := time.Unix(1700000000, 0)
+	_, err := snapshotPanelData(mockCtxWithGrafanaURL(server), panel, nil, nil, indexDatasources([]*models.DataSourceListItemDTO{prom}), nil, from, from.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "B", queries[0]["refId"], "generated refIds skip explicit ones")
+	assert.Equal(t, "A", queries[1]["refId"])
+	assert.Equal(t, map[string]interface{}{"uid": "prom", "type": "prometheus"}, queries[0]["datasource"])
+
+	panel.Panel["datasource"] = "Gone"
+	_, err = snapshotPanelData(mockCtxWithGrafanaURL(server), panel, nil, nil, indexDatasources([]*models.DataSourceListItemDTO{prom}), nil, from, from.Add(time.Hour))
+	assert.ErrorContains(t, err, "datasource Gone does not exist")
+}
+
+func TestListSnapshots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/dashboard/snapshots", r.URL.Path)
+		assert.Equal(t, "api", r.URL.Query().Get("query"))
+		assert.Equal(t, "100", r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"key": "snap-1", "name": "API", "created": "2023-11-14T00:00:00Z", "expires": "2023-11-21T00:00:00Z"},
+			{"key": "snap-2", "name": "API forever", "created": "2023-11-14T00:00:00Z", "expires": "2123-11-14T00:00:00Z"}
+		]`))
+	}))
+	defer server.Close()
+
+	snapshots, err := listSnapshots(mockCtxWithGrafanaURL(server), ListSnapshotsParams{Query: "api"})
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, server.URL+"/dashboard/snapshot/snap-1", snapshots[0].URL)
+	require.NotNil(t, snapshots[0].Expires)
+	assert.Nil(t, snapshots[1].Expires)
+}
+
+func TestSetPublicDashboard(t *testing.T) {
+	var created, updated map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/new/public-dashboards":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Public dashboard not found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/uid/new/public-dashboards":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			_, _ = w.Write([]byte(`{"uid": "pd-new", "dashboardUid": "new", "accessToken": "token-new", "isEnabled": true, "share": "public"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/shared/public-dashboards":
+			_, _ = w.Write([]byte(`{"uid": "pd-1", "dashboardUid": "shared", "accessToken": "token-1", "isEnabled": true, "share": "public", "annotationsEnabled": true}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/dashboards/uid/shared/public-dashboards/pd-1":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&updated))
+			_, _ = w.Write([]byte(`{"uid": "pd-1", "dashboardUid": "shared", "accessToken": "token-1", "isEnabled": false, "share": "public", "annotationsEnabled": true}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	config, err := getPublicDashboard(ctx, GetPublicDashboardParams{DashboardUID: "new"})
+	require.NoError(t, err)
+	assert.False(t, config.Configured)
+
+	config, err = setPublicDashboard(ctx, SetPublicDashboardParams{DashboardUID: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, true, created["isEnabled"])
+	assert.Equal(t, "public", created["share"])
+	assert.True(t, config.Configured)
+	assert.Equal(t, server.URL+"/public-dashboards/token-new", config.PublicURL)
+
+	disabled := false
+	config, err = setPublicDashboard(ctx, SetPublicDashboardParams{DashboardUID: "shared", Enabled: &disabled})
+	require.NoError(t, err)
+	assert.NotEqual(t, true, updated["isEnabled"])
+	assert.Equal(t, true, updated["annotationsEnabled"], "unchanged settings are kept")
+	assert.False(t, config.Enabled)
+}
@@ -0,0 +1,468 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	dashboardAPIGroup            = "dashboard.grafana.app"
+	defaultDashboardV2APIVersion = "v2beta1"
+	dashboardFolderAnnotation    = "grafana.app/folder"
+	dashboardMessageAnnotation   = "grafana.app/message"
+)
+
+// dashboardV2APIVersions are tried in order when fetching a v2 dashboard
+// without knowing which version the server serves.
+var dashboardV2APIVersions = []string{"v2beta1", "v2alpha1"}
+
+// dashboardV2APIPathRegex extracts the API version and namespace from the
+// error Grafana returns when a v2 dashboard is requested through /api/dashboards.
+var dashboardV2APIPathRegex = regexp.MustCompile(`/apis/dashboard\.grafana\.app/(v2[a-z0-9]*)/namespaces/([^/\s]+)/dashboards`)
+
+// isDashboardV2 reports whether a dashboard uses the v2 schema, either as a
+// full dashboard.grafana.app resource or as a bare v2 spec.
+func isDashboardV2(db map[string]interface{}) bool {
+	if strings.HasPrefix(safeString(db, "apiVersion"), dashboardAPIGroup+"/v2") {
+		return true
+	}
+	_, ok := db["elements"].(map[string]interface{})
+	return ok
+}
+
+// dashboardTitle returns the title of a classic or v2 dashboard.
+func dashboardTitle(db map[string]interface{}) string {
+	if isDashboardV2(db) {
+		spec, _ := dashboardV2Spec(db)
+		return safeString(spec, "title")
+	}
+	return safeString(db, "title")
+}
+
+// newDashboardV2Copy turns a fetched v2 dashboard resource into a new
+// resource with the given name and title. Server-managed metadata such as
+// the resource version is dropped; an empty name lets Grafana generate one.
+func newDashboardV2Copy(resource map[string]interface{}, name, title string) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if namespace := safeString(safeObject(resource, "metadata"), "namespace"); namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	if name != "" {
+		metadata["name"] = name
+	}
+	spec, _ := dashboardV2Spec(resource)
+	copied := make(map[string]interface{}, len(spec))
+	for k, v := range spec {
+		copied[k] = v
+	}
+	copied["title"] = title
+	return map[string]interface{}{
+		"apiVersion": safeString(resource, "apiVersion"),
+		"kind":       "Dashboard",
+		"metadata":   metadata,
+		"spec":       copied,
+	}
+}
+
+// dashboardV2Spec returns the spec of a v2 dashboard and the JSONPath that
+// addresses it.
+func dashboardV2Spec(db map[string]interface{}) (map[string]interface{}, string) {
+	if spec := safeObject(db, "spec"); spec != nil {
+		return spec, "$.spec"
+	}
+	return db, "$"
+}
+
+// isDashboardV2Element reports whether a panel is a v2 `elements` entry.
+func isDashboardV2Element(panel map[string]interface{}) bool {
+	kind := safeString(panel, "kind")
+	return (kind == "Panel" || kind == "LibraryPanel") && safeObject(panel, "spec") != nil
+}
+
+// collectDashboardPanels returns every panel of a classic or v2 dashboard.
+func collectDashboardPanels(db map[string]interface{}) []dashboardPanel {
+	if isDashboardV2(db) {
+		return collectV2Panels(db)
+	}
+	return collectPanels(db)
+}
+
+// collectV2Panels returns the elements of a v2 dashboard in layout order.
+// Panels in rows and tabs, including collapsed rows, are attributed to their
+// row. Elements that are not placed in the layout are returned last.
+func collectV2Panels(db map[string]interface{}) []dashboardPanel {
+	spec, prefix := dashboardV2Spec(db)
+	elements := safeObject(spec, "elements")
+	seen := map[string]bool{}
+	var result []dashboardPanel
+
+	add := func(ref interface{}, row string) {
+		refObj, _ := ref.(map[string]interface{})
+		name := safeString(refObj, "name")
+		element, ok := elements[name].(map[string]interface{})
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		result = append(result, dashboardPanel{
+			Panel: element,
+			Path:  fmt.Sprintf("%s.elements[%q]", prefix, name),
+			Row:   row,
+		})
+	}
+	joinRow := func(parent, title string) string {
+		if parent == "" {
+			return title
+		}
+		return parent + " / " + title
+	}
+
+	var walk func(layout map[string]interface{}, row string)
+	walk = func(layout map[string]interface{}, row string) {
+		layoutSpec := safeObject(layout, "spec")
+		switch safeString(layout, "kind") {
+		case "GridLayout", "AutoGridLayout":
+			for _, i := range safeArray(layoutSpec, "items") {
+				item, _ := i.(map[string]interface{})
+				itemSpec := safeObject(item, "spec")
+				// v2alpha1 grids hold rows as GridLayoutRow items.
+				if safeString(item, "kind") == "GridLayoutRow" {
+					title := joinRow(row, safeString(itemSpec, "title"))
+					for _, e := range safeArray(itemSpec, "elements") {
+						nested, _ := e.(map[string]interface{})
+						add(safeObject(nested, "spec")["element"], title)
+					}
+					continue
+				}
+				add(itemSpec["element"], row)
+			}
+		case "RowsLayout":
+			for _, r := range safeArray(layoutSpec, "rows") {
+				rowObj, _ := r.(map[string]interface{})
+				rowSpec := safeObject(rowObj, "spec")
+				walk(safeObject(rowSpec, "layout"), joinRow(row, safeString(rowSpec, "title")))
+			}
+		case "TabsLayout":
+			for _, t := range safeArray(layoutSpec, "tabs") {
+				tab, _ := t.(map[string]interface{})
+				tabSpec := safeObject(tab, "spec")
+				walk(safeObject(tabSpec, "layout"), joinRow(row, safeString(tabSpec, "title")))
+			}
+		}
+	}
+	walk(safeObject(spec, "layout"), "")
+
+	names := make([]string, 0, len(elements))
+	for name := range elements {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(map[string]interface{}{"name": name}, "")
+	}
+	return result
+}
+
+// v2PanelQueries returns the PanelQuery entries of a v2 panel element.
+func v2PanelQueries(element map[string]interface{}) []interface{} {
+	return safeArray(safeObject(safeObject(safeObject(element, "spec"), "data"), "spec"), "queries")
+}
+
+// v2QueryDatasource returns the datasource of a v2 PanelQuery spec. v2beta1
+// stores it on the DataQuery (with the plugin type as group); v2alpha1
+// stores it on the PanelQuery with the plugin type as the query kind.
+func v2QueryDatasource(panelQuerySpec map[string]interface{}) datasourceInfo {
+	query := safeObject(panelQuerySpec, "query")
+	if ds := safeObject(query, "datasource"); ds != nil {
+		return datasourceInfo{UID: safeString(ds, "name"), Type: safeString(query, "group")}
+	}
+	info := parseDatasourceRef(panelQuerySpec["datasource"])
+	if info.Type == "" {
+		info.Type = safeString(query, "group")
+		if kind := safeString(query, "kind"); info.Type == "" && kind != "DataQuery" {
+			info.Type = kind
+		}
+	}
+	return info
+}
+
+// extractV2PanelQueries returns the queries of a v2 panel element. Targets
+// are classic-shaped copies of the query model with refId and hide set.
+func extractV2PanelQueries(p dashboardPanel, variables map[string]datasourceInfo) []dashboardQuery {
+	spec := safeObject(p.Panel, "spec")
+	var result []dashboardQuery
+	for i, q := range v2PanelQueries(p.Panel) {
+		panelQuery, _ := q.(map[string]interface{})
+		panelQuerySpec := safeObject(panelQuery, "spec")
+		model := safeObject(safeObject(panelQuerySpec, "query"), "spec")
+		target := make(map[string]interface{}, len(model)+2)
+		for k, v := range model {
+			target[k] = v
+		}
+		if refID := safeString(panelQuerySpec, "refId"); refID != "" {
+			target["refId"] = refID
+		}
+		if hidden, _ := panelQuerySpec["hidden"].(bool); hidden {
+			target["hide"] = true
+		}
+		result = append(result, dashboardQuery{
+			PanelID:    safeInt(spec, "id"),
+			PanelTitle: safeString(spec, "title"),
+			Path:       fmt.Sprintf("%s.spec.data.spec.queries[%d].spec.query.spec", p.Path, i),
+			Query:      targetQueryText(model),
+			Datasource: resolveDatasource(v2QueryDatasource(panelQuerySpec), variables),
+			Target:     target,
+		})
+	}
+	return result
+}
+
+// extractV2PanelSummary creates a panel summary from a v2 panel element.
+func extractV2PanelSummary(element map[string]interface{}) PanelSummary {
+	spec := safeObject(element, "spec")
+	summary := PanelSummary{
+		ID:          safeInt(spec, "id"),
+		Title:       safeString(spec, "title"),
+		Description: safeString(spec, "description"),
+		QueryCount:  len(v2PanelQueries(element)),
+	}
+	// v2beta1 stores the panel plugin as the VizConfig group, v2alpha1 as
+	// its kind.
+	vizConfig := safeObject(spec, "vizConfig")
+	summary.Type = safeString(vizConfig, "group")
+	if summary.Type == "" {
+		summary.Type = safeString(vizConfig, "kind")
+	}
+	if safeString(element, "kind") == "LibraryPanel" {
+		libraryPanel := safeObject(spec, "libraryPanel")
+		summary.LibraryPanelUID = safeString(libraryPanel, "uid")
+		if summary.Title == "" {
+			summary.Title = safeString(libraryPanel, "name")
+		}
+	}
+	return summary
+}
+
+// v2LibraryPanelReference converts a v2 LibraryPanel element to a classic
+// library panel reference that libraryPanelResolver can resolve.
+func v2LibraryPanelReference(element map[string]interface{}) map[string]interface{} {
+	spec := safeObject(element, "spec")
+	return map[string]interface{}{
+		"id":           spec["id"],
+		"title":        spec["title"],
+		"libraryPanel": spec["libraryPanel"],
+	}
+}
+
+// v2VariableTypes maps v2 variable kinds to classic variable types.
+var v2VariableTypes = map[string]string{
+	"QueryVariable":      "query",
+	"DatasourceVariable": "datasource",
+	"CustomVariable":     "custom",
+	"ConstantVariable":   "constant",
+	"IntervalVariable":   "interval",
+	"TextVariable":       "textbox",
+	"AdhocVariable":      "adhoc",
+	"GroupByVariable":    "groupby",
+	"SwitchVariable":     "switch",
+}
+
+// dashboardVariableList returns the template variables of a classic or v2
+// dashboard in the classic shape (name, type, label, current, ...). For
+// datasource variables of v2 dashboards, the plugin ID is returned as query.
+func dashboardVariableList(db map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	if !isDashboardV2(db) {
+		for _, v := range safeArray(safeObject(db, "templating"), "list") {
+			if variable, ok := v.(map[string]interface{}); ok {
+				result = append(result, variable)
+			}
+		}
+		return result
+	}
+	spec, _ := dashboardV2Spec(db)
+	for _, v := range safeArray(spec, "variables") {
+		variable, _ := v.(map[string]interface{})
+		variableSpec := safeObject(variable, "spec")
+		if variableSpec == nil {
+			continue
+		}
+		kind := safeString(variable, "kind")
+		converted := make(map[string]interface{}, len(variableSpec)+2)
+		for k, val := range variableSpec {
+			converted[k] = val
+		}
+		converted["type"] = v2VariableTypes[kind]
+		if converted["type"] == "" {
+			converted["type"] = strings.ToLower(strings.TrimSuffix(kind, "Variable"))
+		}
+		if kind == "DatasourceVariable" {
+			converted["query"] = safeString(variableSpec, "pluginId")
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// grafanaAPIError is returned by grafanaJSONRequest for non-2xx responses.
+type grafanaAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *grafanaAPIError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// grafanaJSONRequest sends a JSON request to a Grafana API path and decodes
+// the JSON response into out, if not nil.
+func grafanaJSONRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	status, respBody, err := doGrafanaRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return &grafanaAPIError{StatusCode: status, Body: strings.TrimSpace(string(respBody))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// grafanaNamespace returns the API namespace of the current org, as reported
+// by the frontend settings. It falls back to the on-prem naming scheme.
+func grafanaNamespace(ctx context.Context) string {
+	var settings struct {
+		Namespace string `json:"namespace"`
+	}
+	if err := grafanaJSONRequest(ctx, http.MethodGet, "/api/frontend/settings", nil, &settings); err == nil && settings.Namespace != "" {
+		return settings.Namespace
+	}
+	if orgID := mcpgrafana.GrafanaConfigFromContext(ctx).OrgID; orgID > 1 {
+		return fmt.Sprintf("org-%d", orgID)
+	}
+	return "default"
+}
+
+// getDashboardV2ByUID fetches a dashboard from the dashboard.grafana.app API.
+// hint is the error message returned by /api/dashboards, which names the API
+// version and namespace to use.
+func getDashboardV2ByUID(ctx context.Context, uid, hint string) (*models.DashboardFullWithMeta, error) {
+	versions := dashboardV2APIVersions
+	var namespace string
+	if m := dashboardV2APIPathRegex.FindStringSubmatch(hint); m != nil {
+		versions = []string{m[1]}
+		namespace = m[2]
+	} else {
+		namespace = grafanaNamespace(ctx)
+	}
+
+	var resource map[string]interface{}
+	var err error
+	for _, version := range versions {
+		path := fmt.Sprintf("/apis/%s/%s/namespaces/%s/dashboards/%s", dashboardAPIGroup, version, namespace, uid)
+		resource = nil
+		err = grafanaJSONRequest(ctx, http.MethodGet, path, nil, &resource)
+		var apiErr *grafanaAPIError
+		if err == nil || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get v2 dashboard %s: %w", uid, err)
+	}
+
+	metadata := safeObject(resource, "metadata")
+	return &models.DashboardFullWithMeta{
+		Dashboard: resource,
+		Meta: &models.DashboardMeta{
+			FolderUID: safeString(safeObject(metadata, "annotations"), dashboardFolderAnnotation),
+			URL:       "/d/" + uid,
+			Version:   int64(safeFloat64(metadata, "generation")),
+		},
+	}, nil
+}
+
+// saveDashboardV2 creates or updates a v2 dashboard through the
+// dashboard.grafana.app API. Bare v2 specs are wrapped in a resource.
+func saveDashboardV2(ctx context.Context, db map[string]interface{}, folderUID, message string, overwrite bool) (*models.PostDashboardOKBody, error) {
+	resource := db
+	if safeObject(db, "spec") == nil {
+		resource = map[string]interface{}{
+			"apiVersion": dashboardAPIGroup + "/" + defaultDashboardV2APIVersion,
+			"kind":       "Dashboard",
+			"spec":       db,
+		}
+	}
+	if safeString(resource, "apiVersion") == "" {
+		resource["apiVersion"] = dashboardAPIGroup + "/" + defaultDashboardV2APIVersion
+	}
+	if safeString(resource, "kind") == "" {
+		resource["kind"] = "Dashboard"
+	}
+	metadata := safeObject(resource, "metadata")
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+		resource["metadata"] = metadata
+	}
+	annotations := safeObject(metadata, "annotations")
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	if folderUID != "" {
+		annotations[dashboardFolderAnnotation] = folderUID
+	}
+	if message != "" {
+		annotations[dashboardMessageAnnotation] = message
+	}
+
+	namespace := safeString(metadata, "namespace")
+	if namespace == "" {
+		namespace = grafanaNamespace(ctx)
+	}
+	_, version, _ := strings.Cut(safeString(resource, "apiVersion"), "/")
+	basePath := fmt.Sprintf("/apis/%s/%s/namespaces/%s/dashboards", dashboardAPIGroup, version, namespace)
+
+	name := safeString(metadata, "name")
+	method, path := http.MethodPost, basePath
+	if name == "" {
+		metadata["generateName"] = "d"
+	} else if overwrite {
+		method, path = http.MethodPut, basePath+"/"+name
+	}
+
+	var saved map[string]interface{}
+	if err := grafanaJSONRequest(ctx, method, path, resource, &saved); err != nil {
+		return nil, fmt.Errorf("unable to save dashboard: %w", err)
+	}
+	savedMetadata := safeObject(saved, "metadata")
+	uid := safeString(savedMetadata, "name")
+	title := safeString(safeObject(saved, "spec"), "title")
+	url := "/d/" + uid
+	status := "success"
+	generation := int64(safeFloat64(savedMetadata, "generation"))
+	return &models.PostDashboardOKBody{
+		FolderUID: safeString(safeObject(savedMetadata, "annotations"), dashboardFolderAnnotation),
+		Status:    &status,
+		Title:     &title,
+		UID:       &uid,
+		URL:       &url,
+		Version:   &generation,
+	}, nil
+}
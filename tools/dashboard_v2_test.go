@@ -0,0 +1,314 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const v2DashboardResource = `{
+	"apiVersion": "dashboard.grafana.app/v2beta1",
+	"kind": "Dashboard",
+	"metadata": {"name": "v2db", "namespace": "default", "resourceVersion": "42", "generation": 3,
+		"annotations": {"grafana.app/folder": "team"}},
+	"spec": {
+		"title": "Service",
+		"tags": ["api"],
+		"timeSettings": {"from": "now-1h", "to": "now", "autoRefresh": "30s"},
+		"variables": [
+			{"kind": "DatasourceVariable", "spec": {"name": "ds", "pluginId": "prometheus", "current": {"text": "Prod", "value": "prom-prod"}}},
+			{"kind": "QueryVariable", "spec": {"name": "job", "label": "Job", "current": {"value": ["api", "web"]}}}
+		],
+		"elements": {
+			"panel-1": {"kind": "Panel", "spec": {"id": 1, "title": "Requests",
+				"data": {"kind": "QueryGroup", "spec": {"queries": [
+					{"kind": "PanelQuery", "spec": {"refId": "A", "hidden": false, "query": {"kind": "DataQuery", "group": "prometheus", "version": "v0",
+						"datasource": {"name": "${ds}"}, "spec": {"expr": "sum(rate(requests_total{job=~\"$job\"}[5m]))"}}}},
+					{"kind": "PanelQuery", "spec": {"refId": "B", "hidden": true, "query": {"kind": "DataQuery", "group": "prometheus", "version": "v0",
+						"datasource": {"name": "prom-prod"}, "spec": {"expr": "up"}}}}
+				]}},
+				"vizConfig": {"kind": "VizConfig", "group": "timeseries", "spec": {}}}},
+			"panel-2": {"kind": "Panel", "spec": {"id": 2, "title": "Errors",
+				"data": {"kind": "QueryGroup", "spec": {"queries": [
+					{"kind": "PanelQuery", "spec": {"refId": "A", "query": {"kind": "DataQuery", "group": "loki", "version": "v0",
+						"datasource": {"name": "loki"}, "spec": {"expr": "sum(count_over_time({app=\"api\"} |= \"error\" [5m]))"}}}}
+				]}},
+				"vizConfig": {"kind": "VizConfig", "group": "stat", "spec": {}}}},
+			"panel-3": {"kind": "LibraryPanel", "spec": {"id": 3, "title": "", "libraryPanel": {"uid": "lib-1", "name": "Shared requests"}}},
+			"panel-9": {"kind": "Panel", "spec": {"id": 9, "title": "Unplaced", "vizConfig": {"kind": "VizConfig", "group": "text", "spec": {}}}}
+		},
+		"layout": {"kind": "RowsLayout", "spec": {"rows": [
+			{"kind": "RowsLayoutRow", "spec": {"title": "Overview", "layout": {"kind": "GridLayout", "spec": {"items": [
+				{"kind": "GridLayoutItem", "spec": {"x": 0, "y": 0, "width": 12, "height": 8, "element": {"kind": "ElementReference", "name": "panel-1"}}}
+			]}}}},
+			{"kind": "RowsLayoutRow", "spec": {"title": "Details", "collapse": true, "layout": {"kind": "TabsLayout", "spec": {"tabs": [
+				{"kind": "TabsLayoutTab", "spec": {"title": "Logs", "layout": {"kind": "AutoGridLayout", "spec": {"items": [
+					{"kind": "AutoGridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-2"}}},
+					{"kind": "AutoGridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-3"}}}
+				]}}}}
+			]}}}}
+		]}}
+	}
+}`
+
+// newV2DashboardServer serves v2DashboardResource the way Grafana does: the
+// classic API rejects it and points to the dashboard.grafana.app API.
+func newV2DashboardServer(t *testing.T, onSave func(r *http.Request, body map[string]interface{})) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/dashboards/uid/v2db":
+			w.WriteHeader(http.StatusNotAcceptable)
+			_, _ = w.Write([]byte(`{"message": "dashboard api version not supported, use /apis/dashboard.grafana.app/v2beta1/namespaces/default/dashboards/v2db instead"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/dashboard.grafana.app/v2beta1/namespaces/default/dashboards/v2db":
+			_, _ = w.Write([]byte(v2DashboardResource))
+		case r.Method == http.MethodPut && r.URL.Path == "/apis/dashboard.grafana.app/v2beta1/namespaces/default/dashboards/v2db":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			onSave(r, body)
+			metadata := body["metadata"].(map[string]interface{})
+			metadata["generation"] = 4
+			_ = json.NewEncoder(w).Encode(body)
+		case r.Method == http.MethodPost && r.URL.Path == "/apis/dashboard.grafana.app/v2beta1/namespaces/default/dashboards":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			onSave(r, body)
+			metadata := body["metadata"].(map[string]interface{})
+			if metadata["name"] == nil {
+				metadata["name"] = "generated"
+			}
+			metadata["generation"] = 1
+			_ = json.NewEncoder(w).Encode(body)
+		case r.URL.Path == "/api/library-elements/lib-1":
+			_, _ = w.Write([]byte(libraryPanelResponse))
+		case r.URL.Path == "/api/datasources":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestParseJSONPath_QuotedKeys(t *testing.T) {
+	segments := parseJSONPath(`spec.elements["panel-1"].spec.data.spec.queries[0]`)
+	require.Len(t, segments, 7)
+	assert.Equal(t, "panel-1", segments[2].Key)
+	assert.Equal(t, JSONPathSegment{Key: "queries", Index: 0, IsArray: true}, segments[6])
+
+	segments = parseJSONPath(`elements['panel-2'].spec.title`)
+	require.Len(t, segments, 4)
+	assert.Equal(t, "panel-2", segments[1].Key)
+}
+
+func TestGetDashboardSummary_V2(t *testing.T) {
+	server := newV2DashboardServer(t, nil)
+	defer server.Close()
+
+	summary, err := getDashboardSummary(mockCtxWithGrafanaURL(server), GetDashboardSummaryParams{UID: "v2db"})
+	require.NoError(t, err)
+	assert.Equal(t, "Service", summary.Title)
+	assert.Equal(t, []string{"api"}, summary.Tags)
+	assert.Equal(t, TimeRangeSummary{From: "now-1h", To: "now"}, summary.TimeRange)
+	assert.Equal(t, "30s", summary.Refresh)
+	assert.Equal(t, "team", summary.Meta.FolderUID)
+	assert.Equal(t, []VariableSummary{{Name: "ds", Type: "datasource"}, {Name: "job", Type: "query", Label: "Job"}}, summary.Variables)
+
+	assert.Equal(t, 4, summary.PanelCount)
+	assert.Equal(t, []PanelSummary{
+		{ID: 1, Title: "Requests", Type: "timeseries", QueryCount: 2, Row: "Overview", Path: `$.spec.elements["panel-1"]`},
+		{ID: 2, Title: "Errors", Type: "stat", QueryCount: 1, Row: "Details / Logs", Path: `$.spec.elements["panel-2"]`},
+		{ID: 3, Title: "Shared requests", LibraryPanelUID: "lib-1", Row: "Details / Logs", Path: `$.spec.elements["panel-3"]`},
+		{ID: 9, Title: "Unplaced", Type: "text", Path: `$.spec.elements["panel-9"]`},
+	}, summary.Panels)
+}
+
+func TestGetDashboardPanelQueries_V2(t *testing.T) {
+	server := newV2DashboardServer(t, nil)
+	defer server.Close()
+
+	queries, err := GetDashboardPanelQueriesTool(mockCtxWithGrafanaURL(server), DashboardPanelQueriesParams{UID: "v2db"})
+	require.NoError(t, err)
+	require.Len(t, queries, 4)
+	assert.Equal(t, panelQuery{Title: "Requests", Query: `sum(rate(requests_total{job=~"$job"}[5m]))`, Datasource: datasourceInfo{UID: "${ds}", Type: "prometheus"}}, queries[0])
+	assert.Equal(t, datasourceInfo{UID: "loki", Type: "loki"}, queries[2].Datasource)
+	assert.Equal(t, "Shared requests", queries[3].Title)
+	assert.Equal(t, "lib-1", queries[3].LibraryPanelUID)
+}
+
+func TestGetDashboardProperty_V2(t *testing.T) {
+	server := newV2DashboardServer(t, nil)
+	defer server.Close()
+
+	title, err := getDashboardProperty(mockCtxWithGrafanaURL(server), GetDashboardPropertyParams{UID: "v2db", JSONPath: `$.spec.elements["panel-2"].spec.title`})
+	require.NoError(t, err)
+	assert.Equal(t, "Errors", title)
+}
+
+func TestUpdateDashboard_V2Patch(t *testing.T) {
+	var saved map[string]interface{}
+	server := newV2DashboardServer(t, func(r *http.Request, body map[string]interface{}) { saved = body })
+	defer server.Close()
+
+	result, err := updateDashboard(mockCtxWithGrafanaURL(server), UpdateDashboardParams{
+		UID:     "v2db",
+		Message: "rename panel",
+		Operations: []PatchOperation{
+			{Op: "replace", Path: `$.spec.elements["panel-1"].spec.title`, Value: "Request rate"},
+			{Op: "replace", Path: `$.spec.elements["panel-1"].spec.data.spec.queries[0].spec.query.spec.expr`, Value: "sum(rate(requests_total[1m]))"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "v2db", *result.UID)
+	assert.EqualValues(t, 4, *result.Version)
+	assert.Equal(t, "team", result.FolderUID)
+
+	metadata := saved["metadata"].(map[string]interface{})
+	assert.Equal(t, "42", metadata["resourceVersion"])
+	annotations := metadata["annotations"].(map[string]interface{})
+	assert.Equal(t, "team", annotations[dashboardFolderAnnotation])
+	assert.Equal(t, "rename panel", annotations[dashboardMessageAnnotation])
+
+	element := saved["spec"].(map[string]interface{})["elements"].(map[string]interface{})["panel-1"].(map[string]interface{})
+	assert.Equal(t, "Request rate", element["spec"].(map[string]interface{})["title"])
+	queries := extractDashboardQueries(saved)
+	assert.Equal(t, "sum(rate(requests_total[1m]))", queries[0].Query)
+}
+
+func TestMoveDashboards_V2(t *testing.T) {
+	var saved map[string]interface{}
+	server := newV2DashboardServer(t, func(r *http.Request, body map[string]interface{}) { saved = body })
+	defer server.Close()
+
+	results, err := moveDashboards(mockCtxWithGrafanaURL(server), MoveDashboardsParams{UIDs: []string{"v2db"}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Error)
+	assert.True(t, results[0].Moved)
+	assert.Equal(t, "Service", results[0].Title)
+	assert.Equal(t, "team", results[0].FromFolderUID)
+	annotations := saved["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	assert.NotContains(t, annotations, dashboardFolderAnnotation, "moving to the root folder removes the folder annotation")
+}
+
+func TestCloneDashboard_V2(t *testing.T) {
+	var saved map[string]interface{}
+	server := newV2DashboardServer(t, func(r *http.Request, body map[string]interface{}) { saved = body })
+	defer server.Close()
+
+	result, err := cloneDashboard(mockCtxWithGrafanaURL(server), CloneDashboardParams{UID: "v2db", Title: "Service (copy)"})
+	require.NoError(t, err)
+	assert.Equal(t, "generated", result.UID)
+	assert.Equal(t, "team", result.FolderUID)
+
+	metadata := saved["metadata"].(map[string]interface{})
+	assert.NotContains(t, metadata, "resourceVersion")
+	assert.Equal(t, "d", metadata["generateName"])
+	assert.Equal(t, "team", metadata["annotations"].(map[string]interface{})[dashboardFolderAnnotation])
+	spec := saved["spec"].(map[string]interface{})
+	assert.Equal(t, "Service (copy)", spec["title"])
+	assert.Contains(t, spec["elements"], "panel-1")
+
+	_, err = cloneDashboard(mockCtxWithGrafanaURL(server), CloneDashboardParams{UID: "v2db", Title: "Copy", DatasourceMap: map[string]string{"prom-prod": "prom-staging"}})
+	assert.ErrorContains(t, err, "not supported for v2 dashboards")
+}
+
+func TestExportAndLint_RejectV2(t *testing.T) {
+	server := newV2DashboardServer(t, nil)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	_, err := lintDashboard(ctx, LintDashboardParams{UID: "v2db"})
+	assert.ErrorContains(t, err, "v2 dashboards")
+
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(v2DashboardResource), &db))
+	_, err = lintDashboard(ctx, LintDashboardParams{Dashboard: db})
+	assert.ErrorContains(t, err, "v2 dashboards")
+
+	_, err = exportDashboards(ctx, ExportDashboardsParams{UID: "v2db"})
+	assert.ErrorContains(t, err, "uses the v2 schema")
+}
+
+func TestCreateDashboardSnapshot_RejectsV2(t *testing.T) {
+	// The mock server fails the test if a snapshot is saved.
+	server := newV2DashboardServer(t, nil)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	_, err := createDashboardSnapshot(ctx, CreateDashboardSnapshotParams{DashboardUID: "v2db"})
+	assert.ErrorContains(t, err, "uses the v2 schema")
+}
+
+func TestExtractDashboardQueries_V2(t *testing.T) {
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(v2DashboardResource), &db))
+
+	queries := extractDashboardQueries(db)
+	require.Len(t, queries, 3)
+	assert.Equal(t, datasourceInfo{UID: "prom-prod", Type: "prometheus"}, queries[0].Datasource, "datasource variables are resolved")
+	assert.Equal(t, `$.spec.elements["panel-1"].spec.data.spec.queries[0].spec.query.spec`, queries[0].Path)
+	assert.Equal(t, "A", queries[0].Target["refId"])
+	assert.Equal(t, true, queries[1].Target["hide"])
+	assert.Equal(t, map[string][]string{"ds": {"prom-prod"}, "job": {"api", "web"}}, dashboardVariableValues(db))
+
+	// v2alpha1 stores rows as grid items and the datasource on the PanelQuery.
+	var alpha map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"elements": {"panel-1": {"kind": "Panel", "spec": {"id": 1, "title": "CPU",
+			"data": {"kind": "QueryGroup", "spec": {"queries": [{"kind": "PanelQuery", "spec": {"refId": "A",
+				"datasource": {"type": "prometheus", "uid": "prom"}, "query": {"kind": "prometheus", "spec": {"expr": "cpu"}}}}]}},
+			"vizConfig": {"kind": "timeseries", "spec": {}}}}},
+		"layout": {"kind": "GridLayout", "spec": {"items": [
+			{"kind": "GridLayoutRow", "spec": {"title": "Hosts", "collapsed": true, "elements": [
+				{"kind": "GridLayoutItem", "spec": {"element": {"kind": "ElementReference", "name": "panel-1"}}}
+			]}}
+		]}}
+	}`), &alpha))
+	require.True(t, isDashboardV2(alpha))
+	panels := collectDashboardPanels(alpha)
+	require.Len(t, panels, 1)
+	assert.Equal(t, "Hosts", panels[0].Row)
+	assert.Equal(t, `$.elements["panel-1"]`, panels[0].Path)
+	assert.Equal(t, "timeseries", extractV2PanelSummary(panels[0].Panel).Type)
+	queries = extractDashboardQueries(alpha)
+	require.Len(t, queries, 1)
+	assert.Equal(t, datasourceInfo{UID: "prom", Type: "prometheus"}, queries[0].Datasource)
+}
+
+func TestGetDashboardSummary_ClassicRows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"dashboard": {"uid": "db-1", "title": "Classic", "time": {"from": "now-6h", "to": "now"}, "panels": [
+			{"id": 1, "type": "stat", "title": "Top"},
+			{"id": 2, "type": "row", "title": "Expanded", "collapsed": false, "panels": []},
+			{"id": 3, "type": "timeseries", "title": "Under expanded", "targets": [{"expr": "a"}, {"expr": "b"}]},
+			{"id": 4, "type": "row", "title": "Collapsed", "collapsed": true, "panels": [
+				{"id": 5, "type": "table", "title": "Hidden", "targets": [{"expr": "c"}]}
+			]}
+		]}}`))
+	}))
+	defer server.Close()
+
+	summary, err := getDashboardSummary(mockCtxWithClient(server), GetDashboardSummaryParams{UID: "db-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, summary.PanelCount)
+	assert.Equal(t, []PanelSummary{
+		{ID: 1, Title: "Top", Type: "stat", Path: "$.panels[0]"},
+		{ID: 2, Title: "Expanded", Type: "row", Path: "$.panels[1]"},
+		{ID: 3, Title: "Under expanded", Type: "timeseries", QueryCount: 2, Row: "Expanded", Path: "$.panels[2]"},
+		{ID: 4, Title: "Collapsed", Type: "row", Path: "$.panels[3]"},
+		{ID: 5, Title: "Hidden", Type: "table", QueryCount: 1, Row: "Collapsed", Path: "$.panels[3].panels[0]"},
+	}, summary.Panels)
+
+	queries, err := GetDashboardPanelQueriesTool(mockCtxWithClient(server), DashboardPanelQueriesParams{UID: "db-1"})
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+	assert.Equal(t, "Hidden", queries[2].Title)
+}
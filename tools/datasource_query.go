@@ -0,0 +1,459 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// dsQueryRequest is the request body of Grafana's /api/ds/query endpoint.
+type dsQueryRequest struct {
+	From    string                   `json:"from"`
+	To      string                   `json:"to"`
+	Queries []map[string]interface{} `json:"queries"`
+}
+
+// dsQueryResult is the result of a single query (refId) returned by
+// /api/ds/query.
+type dsQueryResult struct {
+	Status int           `json:"status,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Frames []*data.Frame `json:"frames,omitempty"`
+}
+
+// dsQueryResponse is the response body of /api/ds/query, keyed by refId.
+type dsQueryResponse struct {
+	Results map[string]dsQueryResult `json:"results"`
+}
+
+// rawDSQueryResponse is used to decode frames one at a time, so that a
+// malformed frame is reported as an error for its refId only.
+type rawDSQueryResponse struct {
+	Results map[string]struct {
+		Status int               `json:"status,omitempty"`
+		Error  string            `json:"error,omitempty"`
+		Frames []json.RawMessage `json:"frames,omitempty"`
+	} `json:"results"`
+}
+
+// unmarshalFrame decodes a data frame. The SDK panics on some malformed
+// frames, which is converted to an error here.
+func unmarshalFrame(raw json.RawMessage) (frame *data.Frame, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decoding frame: %v", r)
+		}
+	}()
+	frame = &data.Frame{}
+	if err := json.Unmarshal(raw, frame); err != nil {
+		return nil, fmt.Errorf("decoding frame: %w", err)
+	}
+	return frame, nil
+}
+
+// newGrafanaHTTPClient returns an HTTP client for calling Grafana APIs that
+// are not covered by the OpenAPI client, using the auth, TLS and org settings
+// from the context.
+func newGrafanaHTTPClient(ctx context.Context) (*http.Client, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	var transport = http.DefaultTransport
+	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
+		var err error
+		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create custom transport: %w", err)
+		}
+	}
+	transport = NewAuthRoundTripper(transport, cfg.AccessToken, cfg.IDToken, cfg.APIKey, cfg.BasicAuth)
+	transport = mcpgrafana.NewOrgIDRoundTripper(transport, cfg.OrgID)
+	return &http.Client{Transport: mcpgrafana.NewUserAgentTransport(transport)}, nil
+}
+
+// doGrafanaRequest sends a request to a Grafana API path, with body encoded
+// as JSON if not nil, and returns the response status code and body.
+func doGrafanaRequest(ctx context.Context, method, path string, body interface{}) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("marshaling request body: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(cfg.URL, "/")+path, reqBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48))
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading response body: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// queryDatasources executes queries through Grafana's /api/ds/query endpoint
+// and returns the results of every refId. Per-query errors are reported in
+// the results rather than as an error, so callers can inspect each query.
+func queryDatasources(ctx context.Context, req dsQueryRequest) (*dsQueryResponse, error) {
+	status, respBody, err := doGrafanaRequest(ctx, http.MethodPost, "/api/ds/query", req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grafana returns a non-200 status when queries fail, but still includes
+	// the per-refId errors in the body.
+	var raw rawDSQueryResponse
+	if err := json.Unmarshal(respBody, &raw); err != nil || raw.Results == nil {
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("query failed with status %d: %s", status, strings.TrimSpace(string(respBody)))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	result := &dsQueryResponse{Results: make(map[string]dsQueryResult, len(raw.Results))}
+	for refID, r := range raw.Results {
+		res := dsQueryResult{Status: r.Status, Error: r.Error}
+		for _, rawFrame := range r.Frames {
+			frame, err := unmarshalFrame(rawFrame)
+			if err != nil {
+				if res.Error == "" {
+					res.Error = err.Error()
+				}
+				continue
+			}
+			res.Frames = append(res.Frames, frame)
+		}
+		result.Results[refID] = res
+	}
+	return result, nil
+}
+
+// frameRowCount returns the number of rows in a frame.
+func frameRowCount(frame *data.Frame) int {
+	if frame == nil || len(frame.Fields) == 0 {
+		return 0
+	}
+	return frame.Fields[0].Len()
+}
+
+const (
+	defaultQueryMaxDataPoints = 1000
+	defaultQueryMaxRows       = 1000
+)
+
+type QueryDatasourceParams struct {
+	DatasourceUID string                   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query. Queries that set their own 'datasource' are sent to that datasource instead"`
+	Queries       []map[string]interface{} `json:"queries" jsonschema:"required,description=The datasource-specific query models\\, as stored in panel targets. For example {\"expr\": \"up\"} for Prometheus or {\"rawSql\": \"SELECT 1\"\\, \"format\": \"table\"} for SQL datasources. refIds default to A\\, B\\, C..."`
+	From          string                   `json:"from,omitempty" jsonschema:"description=Start of the time range. Relative ('now-1h') or RFC3339. Defaults to now-1h"`
+	To            string                   `json:"to,omitempty" jsonschema:"description=End of the time range. Relative ('now') or RFC3339. Defaults to now"`
+	MaxDataPoints int                      `json:"maxDataPoints,omitempty" jsonschema:"description=Maximum number of data points per series (default 1000)"`
+	MaxRows       int                      `json:"maxRows,omitempty" jsonschema:"description=Maximum number of rows returned per frame (default 1000)"`
+}
+
+// QuerySeries is a single series of a time series frame. Points are
+// [unix milliseconds, value] pairs.
+type QuerySeries struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Points [][2]interface{}  `json:"points"`
+}
+
+// QueryFrame is a compact representation of a data frame. Frames with a
+// time field and numeric fields are returned as series, other frames as a
+// table of columns and rows.
+type QueryFrame struct {
+	Name      string          `json:"name,omitempty"`
+	Columns   []string        `json:"columns,omitempty"`
+	Types     []string        `json:"types,omitempty"`
+	Rows      [][]interface{} `json:"rows,omitempty"`
+	Series    []QuerySeries   `json:"series,omitempty"`
+	TotalRows int             `json:"totalRows"`
+	Truncated bool            `json:"truncated,omitempty"`
+	Notices   []string        `json:"notices,omitempty"`
+}
+
+type QueryDatasourceRefResult struct {
+	Error  string       `json:"error,omitempty"`
+	Frames []QueryFrame `json:"frames"`
+}
+
+type QueryDatasourceResult struct {
+	From    time.Time                           `json:"from"`
+	To      time.Time                           `json:"to"`
+	Results map[string]QueryDatasourceRefResult `json:"results"`
+}
+
+// frameValue returns the value of a field at a row in a JSON-friendly form.
+// Times are returned as unix milliseconds, and NaN and infinite values as
+// null.
+func frameValue(field *data.Field, row int) interface{} {
+	v, ok := field.ConcreteAt(row)
+	if !ok {
+		return nil
+	}
+	switch v := v.(type) {
+	case time.Time:
+		return v.UnixMilli()
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil
+		}
+	case json.RawMessage:
+		return v
+	}
+	return v
+}
+
+// compactFrame converts a frame to its compact representation, keeping at
+// most maxRows rows.
+func compactFrame(frame *data.Frame, maxRows int) QueryFrame {
+	rows := frameRowCount(frame)
+	out := QueryFrame{Name: frame.Name, TotalRows: rows}
+	if rows > maxRows {
+		rows = maxRows
+		out.Truncated = true
+	}
+	if frame.Meta != nil {
+		for _, n := range frame.Meta.Notices {
+			out.Notices = append(out.Notices, n.Text)
+		}
+	}
+
+	if frame.TimeSeriesSchema().Type == data.TimeSeriesTypeWide {
+		timeIdx := frame.TimeSeriesSchema().TimeIndex
+		for i, field := range frame.Fields {
+			if i == timeIdx || !field.Type().Numeric() {
+				continue
+			}
+			series := QuerySeries{Name: field.Name, Points: make([][2]interface{}, 0, rows)}
+			if field.Config != nil && field.Config.DisplayNameFromDS != "" {
+				series.Name = field.Config.DisplayNameFromDS
+			}
+			if len(field.Labels) > 0 {
+				series.Labels = map[string]string(field.Labels)
+			}
+			for row := 0; row < rows; row++ {
+				series.Points = append(series.Points, [2]interface{}{frameValue(frame.Fields[timeIdx], row), frameValue(field, row)})
+			}
+			out.Series = append(out.Series, series)
+		}
+		return out
+	}
+
+	for _, field := range frame.Fields {
+		out.Columns = append(out.Columns, field.Name)
+		out.Types = append(out.Types, snapshotFieldType(field.Type()))
+	}
+	out.Rows = make([][]interface{}, 0, rows)
+	for row := 0; row < rows; row++ {
+		values := make([]interface{}, len(frame.Fields))
+		for i, field := range frame.Fields {
+			values[i] = frameValue(field, row)
+		}
+		out.Rows = append(out.Rows, values)
+	}
+	return out
+}
+
+func queryDatasource(ctx context.Context, args QueryDatasourceParams) (*QueryDatasourceResult, error) {
+	if args.DatasourceUID == "" {
+		return nil, fmt.Errorf("query datasource: datasourceUid is required")
+	}
+	if len(args.Queries) == 0 {
+		return nil, fmt.Errorf("query datasource: at least one query is required")
+	}
+	if args.From == "" {
+		args.From = "now-1h"
+	}
+	if args.To == "" {
+		args.To = "now"
+	}
+	from, err := parseTime(args.From)
+	if err != nil {
+		return nil, fmt.Errorf("parsing from: %w", err)
+	}
+	to, err := parseTime(args.To)
+	if err != nil {
+		return nil, fmt.Errorf("parsing to: %w", err)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("query datasource: from must be before to")
+	}
+	maxDataPoints := args.MaxDataPoints
+	if maxDataPoints <= 0 {
+		maxDataPoints = defaultQueryMaxDataPoints
+	}
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultQueryMaxRows
+	}
+
+	// Explicit refIds are reserved first so that generated ones cannot
+	// collide with them.
+	refIDs := map[string]bool{}
+	for _, q := range args.Queries {
+		if refID, _ := q["refId"].(string); refID != "" {
+			if refIDs[refID] {
+				return nil, fmt.Errorf("query datasource: duplicate refId %q", refID)
+			}
+			refIDs[refID] = true
+		}
+	}
+	nextRefID := 0
+
+	queries := make([]map[string]interface{}, 0, len(args.Queries))
+	for _, q := range args.Queries {
+		query := make(map[string]interface{}, len(q)+3)
+		for k, v := range q {
+			query[k] = v
+		}
+		if refID, _ := query["refId"].(string); refID == "" {
+			for refIDs[refIDForIndex(nextRefID)] {
+				nextRefID++
+			}
+			refID = refIDForIndex(nextRefID)
+			refIDs[refID] = true
+			query["refId"] = refID
+		}
+		if _, ok := query["datasource"]; !ok {
+			query["datasource"] = map[string]string{"uid": args.DatasourceUID}
+		}
+		if _, ok := query["maxDataPoints"]; !ok {
+			query["maxDataPoints"] = maxDataPoints
+		}
+		if _, ok := query["intervalMs"]; !ok {
+			query["intervalMs"] = max(to.Sub(from).Milliseconds()/int64(maxDataPoints), 1)
+		}
+		queries = append(queries, query)
+	}
+
+	resp, err := queryDatasources(ctx, dsQueryRequest{
+		From:    fmt.Sprintf("%d", from.UnixMilli()),
+		To:      fmt.Sprintf("%d", to.UnixMilli()),
+		Queries: queries,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query datasource %s: %w", args.DatasourceUID, err)
+	}
+
+	result := &QueryDatasourceResult{From: from, To: to, Results: make(map[string]QueryDatasourceRefResult, len(resp.Results))}
+	for refID, r := range resp.Results {
+		ref := QueryDatasourceRefResult{Error: r.Error, Frames: make([]QueryFrame, 0, len(r.Frames))}
+		for _, frame := range r.Frames {
+			ref.Frames = append(ref.Frames, compactFrame(frame, maxRows))
+		}
+		result.Results[refID] = ref
+	}
+	return result, nil
+}
+
+// guardSQLQueries applies the read-only guard of the SQL tools to the raw SQL
+// of queries against SQL datasources, and limits their rows in the database.
+// Datasource types are looked up by UID, since the type in a query is not
+// what Grafana routes on. It returns copies of the queries.
+func guardSQLQueries(ctx context.Context, args QueryDatasourceParams) ([]map[string]interface{}, error) {
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultQueryMaxRows
+	}
+	dsTypes := map[string]string{}
+	queries := make([]map[string]interface{}, 0, len(args.Queries))
+	for _, q := range args.Queries {
+		query := make(map[string]interface{}, len(q))
+		for k, v := range q {
+			query[k] = v
+		}
+		queries = append(queries, query)
+
+		uid := parseDatasourceRef(query["datasource"]).UID
+		if uid == "" {
+			uid = args.DatasourceUID
+		}
+		if uid == "__expr__" {
+			continue
+		}
+		dsType, ok := dsTypes[uid]
+		if !ok {
+			ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+			if err != nil {
+				return nil, err
+			}
+			dsType = ds.Type
+			dsTypes[uid] = dsType
+		}
+		dialect := sqlDialectForType(dsType)
+		rawSQL, _ := query["rawSql"].(string)
+		if dialect == nil || rawSQL == "" {
+			continue
+		}
+		stmt, err := checkReadOnlySQL(rawSQL, dialect)
+		if err != nil {
+			return nil, fmt.Errorf("%w (use %s for %s datasources)", err, dialect.tool, dialect.name)
+		}
+		// One extra row is fetched to tell whether the result was truncated.
+		if limited, ok := limitSQL(stmt, dialect, maxRows+1, defaultSQLTimeoutSeconds); ok {
+			query["rawSql"] = limited
+		}
+	}
+	return queries, nil
+}
+
+const queryDatasourceDescription = "Run one or more queries against any datasource through Grafana's query API, using the datasource's own query model (the same JSON as a panel target). Returns every frame of every refId in a compact form: time series frames as series of [unix ms, value] points with labels, other frames as tables of columns and rows. Prefer the dedicated Prometheus, Loki and SQL tools when they apply."
+
+// newQueryDatasourceTool creates the query_datasource tool. Unless writes are
+// enabled, queries against SQL datasources must be single read-only
+// statements, as with the SQL tools.
+func newQueryDatasourceTool(enableWriteTools bool) mcpgrafana.Tool {
+	if enableWriteTools {
+		return mcpgrafana.MustTool("query_datasource", queryDatasourceDescription+" SQL statements that modify data are allowed.", queryDatasource,
+			mcp.WithTitleAnnotation("Query datasource"),
+			mcp.WithDestructiveHintAnnotation(true),
+		)
+	}
+	handler := func(ctx context.Context, args QueryDatasourceParams) (*QueryDatasourceResult, error) {
+		queries, err := guardSQLQueries(ctx, args)
+		if err != nil {
+			return nil, fmt.Errorf("query datasource: %w", err)
+		}
+		args.Queries = queries
+		return queryDatasource(ctx, args)
+	}
+	return mcpgrafana.MustTool("query_datasource", queryDatasourceDescription+" Queries against SQL datasources must be single read-only statements.", handler,
+		mcp.WithTitleAnnotation("Query datasource"),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
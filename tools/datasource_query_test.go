@@ -0,0 +1,127 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDatasource(t *testing.T) {
+	var request dsQueryRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/ds/query", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results": {
+			"B": {"frames": [{
+				"schema": {"fields": [{"name": "Time", "type": "time", "typeInfo": {"frame": "time.Time"}},
+					{"name": "Value", "type": "number", "typeInfo": {"frame": "float64", "nullable": true}, "labels": {"job": "api"}}]},
+				"data": {"values": [[1700000000000, 1700000060000, 1700000120000], [1.5, null, 2]], "entities": [null, {"NaN": [2]}]}}]},
+			"A": {"frames": [{
+				"schema": {"name": "hosts", "fields": [{"name": "host", "type": "string", "typeInfo": {"frame": "string"}},
+					{"name": "count", "type": "number", "typeInfo": {"frame": "int64"}}]},
+				"data": {"values": [["a", "b", "c"], [3, 2, 1]]}}]},
+			"C": {"status": 400, "error": "bad query"}
+		}}`))
+	}))
+	defer server.Close()
+
+	result, err := queryDatasource(mockCtxWithGrafanaURL(server), QueryDatasourceParams{
+		DatasourceUID: "ds-1",
+		Queries: []map[string]interface{}{
+			{"expr": "up"},
+			{"refId": "A", "rawSql": "SELECT host, count(*) FROM t GROUP BY host", "format": "table"},
+			{"refId": "C", "expr": "bad", "datasource": map[string]interface{}{"uid": "other"}},
+		},
+		From:    "2023-11-14T00:00:00Z",
+		To:      "2023-11-15T00:00:00Z",
+		MaxRows: 2,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, request.Queries, 3)
+	assert.Equal(t, "1699920000000", request.From)
+	assert.Equal(t, "B", request.Queries[0]["refId"], "generated refIds skip explicit ones")
+	assert.Equal(t, "A", request.Queries[1]["refId"])
+	assert.Equal(t, map[string]interface{}{"uid": "ds-1"}, request.Queries[0]["datasource"])
+	assert.Equal(t, map[string]interface{}{"uid": "other"}, request.Queries[2]["datasource"])
+	assert.EqualValues(t, 1000, request.Queries[0]["maxDataPoints"])
+	assert.EqualValues(t, 86400, request.Queries[0]["intervalMs"])
+
+	series := result.Results["B"].Frames[0].Series
+	require.Len(t, series, 1)
+	assert.Equal(t, map[string]string{"job": "api"}, series[0].Labels)
+	assert.Equal(t, [][2]interface{}{{int64(1700000000000), 1.5}, {int64(1700000060000), nil}}, series[0].Points)
+	assert.True(t, result.Results["B"].Frames[0].Truncated)
+	assert.Equal(t, 3, result.Results["B"].Frames[0].TotalRows)
+
+	table := result.Results["A"].Frames[0]
+	assert.Equal(t, "hosts", table.Name)
+	assert.Equal(t, []string{"host", "count"}, table.Columns)
+	assert.Equal(t, []string{"string", "number"}, table.Types)
+	assert.Equal(t, [][]interface{}{{"a", int64(3)}, {"b", int64(2)}}, table.Rows)
+
+	assert.Equal(t, "bad query", result.Results["C"].Error)
+
+	_, err = queryDatasource(mockCtxWithGrafanaURL(server), QueryDatasourceParams{DatasourceUID: "ds-1"})
+	assert.ErrorContains(t, err, "at least one query")
+
+	_, err = queryDatasource(mockCtxWithGrafanaURL(server), QueryDatasourceParams{
+		DatasourceUID: "ds-1",
+		Queries:       []map[string]interface{}{{"refId": "A", "expr": "up"}, {"refId": "A", "expr": "down"}},
+	})
+	assert.ErrorContains(t, err, "duplicate refId")
+}
+
+func TestGuardSQLQueries(t *testing.T) {
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/datasources/uid/pg":
+			lookups++
+			_, _ = w.Write([]byte(`{"uid": "pg", "type": "grafana-postgresql-datasource"}`))
+		case "/api/datasources/uid/prom":
+			lookups++
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	original := map[string]interface{}{"refId": "A", "rawSql": "SELECT * FROM orders", "format": "table"}
+	queries, err := guardSQLQueries(ctx, QueryDatasourceParams{
+		DatasourceUID: "pg",
+		MaxRows:       10,
+		Queries: []map[string]interface{}{
+			original,
+			// The type in the query is ignored in favour of the datasource's.
+			{"refId": "B", "rawSql": "SELECT 1", "datasource": map[string]interface{}{"uid": "pg", "type": "prometheus"}},
+			{"refId": "C", "expr": "up", "datasource": map[string]interface{}{"uid": "prom"}},
+			{"refId": "D", "type": "math", "expression": "$A * 2", "datasource": map[string]interface{}{"uid": "__expr__"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, queries, 4)
+	assert.Equal(t, "SELECT * FROM orders\nLIMIT 11", queries[0]["rawSql"])
+	assert.Equal(t, "SELECT * FROM orders", original["rawSql"], "queries are copied")
+	assert.Contains(t, queries[1]["rawSql"], "LIMIT 11")
+	assert.Equal(t, "up", queries[2]["expr"])
+	assert.Equal(t, 2, lookups, "datasource types are looked up once per UID")
+
+	_, err = guardSQLQueries(ctx, QueryDatasourceParams{
+		DatasourceUID: "pg",
+		Queries:       []map[string]interface{}{{"refId": "A", "rawSql": "DELETE FROM orders"}},
+	})
+	assert.ErrorContains(t, err, "DELETE statements are not allowed")
+	assert.ErrorContains(t, err, "use query_postgres")
+}
@@ -3,10 +3,13 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/grafana/grafana-openapi-client-go/client/folders"
 	"github.com/grafana/grafana-openapi-client-go/models"
 	mcpgrafana "github.com/grafana/mcp-grafana"
 )
@@ -46,8 +49,431 @@ var CreateFolder = mcpgrafana.MustTool(
 	mcp.WithIdempotentHintAnnotation(false),
 )
 
+const (
+	defaultFolderTreeDepth = 5
+	maxFolderTreeDepth     = 10
+	maxFolderTreeFolders   = 1000
+	folderPageSize         = 1000
+	// maxFolderContentsListed caps the dashboards and alert rules listed by
+	// the delete_folder safety check.
+	maxFolderContentsListed = 100
+)
+
+// listChildFolders returns the direct children of a folder, or the top-level
+// folders if parentUID is empty.
+func listChildFolders(ctx context.Context, parentUID string) ([]*models.FolderSearchHit, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var result []*models.FolderSearchHit
+	for page := int64(1); ; page++ {
+		limit := int64(folderPageSize)
+		params := folders.NewGetFoldersParamsWithContext(ctx).WithLimit(&limit).WithPage(&page)
+		if parentUID != "" {
+			params.SetParentUID(&parentUID)
+		}
+		resp, err := c.Folders.GetFolders(params)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.Payload...)
+		if len(resp.Payload) < folderPageSize {
+			return result, nil
+		}
+	}
+}
+
+type ListFolderTreeParams struct {
+	ParentUID string `json:"parentUid,omitempty" jsonschema:"description=UID of the folder to start from. Leave empty to list the whole tree from the root."`
+	MaxDepth  int    `json:"maxDepth,omitempty" jsonschema:"default=5,description=Maximum nesting depth to descend (1 lists only direct children\\, max 10)"`
+}
+
+// FolderTreeNode is a folder in a folder tree listing.
+type FolderTreeNode struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUID string `json:"parentUid,omitempty"`
+	// Depth is 1 for children of the starting folder.
+	Depth int `json:"depth"`
+	// Path is the slash-separated titles from the starting folder.
+	Path string `json:"path"`
+}
+
+// FolderTreeResult is a depth-first listing of a folder tree.
+type FolderTreeResult struct {
+	Folders   []FolderTreeNode `json:"folders"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+// walkFolderTree lists the folders below parentUID depth-first, up to
+// maxDepth levels and maxFolderTreeFolders folders.
+func walkFolderTree(ctx context.Context, parentUID string, maxDepth int) (*FolderTreeResult, error) {
+	result := &FolderTreeResult{Folders: []FolderTreeNode{}}
+	var walk func(parentUID, parentPath string, depth int) error
+	walk = func(parentUID, parentPath string, depth int) error {
+		children, err := listChildFolders(ctx, parentUID)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if len(result.Folders) >= maxFolderTreeFolders {
+				result.Truncated = true
+				return nil
+			}
+			path := child.Title
+			if parentPath != "" {
+				path = parentPath + "/" + child.Title
+			}
+			result.Folders = append(result.Folders, FolderTreeNode{
+				UID:       child.UID,
+				Title:     child.Title,
+				ParentUID: parentUID,
+				Depth:     depth,
+				Path:      path,
+			})
+			if depth >= maxDepth {
+				continue
+			}
+			if err := walk(child.UID, path, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(parentUID, "", 1); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func listFolderTree(ctx context.Context, args ListFolderTreeParams) (*FolderTreeResult, error) {
+	if args.MaxDepth <= 0 {
+		args.MaxDepth = defaultFolderTreeDepth
+	}
+	if args.MaxDepth > maxFolderTreeDepth {
+		args.MaxDepth = maxFolderTreeDepth
+	}
+	result, err := walkFolderTree(ctx, args.ParentUID, args.MaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("list folder tree: %w", err)
+	}
+	return result, nil
+}
+
+var ListFolderTree = mcpgrafana.MustTool(
+	"list_folder_tree",
+	"List the folder tree, including nested folders, depth-first from the root or from a given folder. Each folder is returned with its depth and its path of titles. Nested folders are only returned when the Grafana instance has nested folders enabled.",
+	listFolderTree,
+	mcp.WithTitleAnnotation("List folder tree"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type GetFolderParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the folder"`
+}
+
+// FolderDetails describes a folder, its position in the tree and how much
+// content it holds.
+type FolderDetails struct {
+	UID         string `json:"uid"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	ParentUID   string `json:"parentUid,omitempty"`
+	// Path is the slash-separated titles from the root to the folder.
+	Path      string     `json:"path"`
+	Version   int64      `json:"version,omitempty"`
+	CanEdit   bool       `json:"canEdit"`
+	CanAdmin  bool       `json:"canAdmin"`
+	CanDelete bool       `json:"canDelete"`
+	ManagedBy string     `json:"managedBy,omitempty"`
+	Created   *time.Time `json:"created,omitempty"`
+	CreatedBy string     `json:"createdBy,omitempty"`
+	Updated   *time.Time `json:"updated,omitempty"`
+	UpdatedBy string     `json:"updatedBy,omitempty"`
+	// Counts of everything below the folder, including nested folders, by
+	// kind (folder, dashboard, librarypanel, alertrule).
+	DescendantCounts map[string]int64 `json:"descendantCounts,omitempty"`
+}
+
+func getFolder(ctx context.Context, args GetFolderParams) (*FolderDetails, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("get folder: uid is required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Folders.GetFolderByUID(args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("get folder %s: %w", args.UID, err)
+	}
+	folder := resp.Payload
+	titles := make([]string, 0, len(folder.Parents)+1)
+	for _, parent := range folder.Parents {
+		titles = append(titles, parent.Title)
+	}
+	titles = append(titles, folder.Title)
+
+	details := &FolderDetails{
+		UID:       folder.UID,
+		Title:     folder.Title,
+		URL:       folder.URL,
+		ParentUID: folder.ParentUID,
+		Path:      strings.Join(titles, "/"),
+		Version:   folder.Version,
+		CanEdit:   folder.CanEdit,
+		CanAdmin:  folder.CanAdmin,
+		CanDelete: folder.CanDelete,
+		ManagedBy: string(folder.ManagedBy),
+		CreatedBy: folder.CreatedBy,
+		UpdatedBy: folder.UpdatedBy,
+	}
+	// Older Grafana versions do not report these timestamps.
+	if created := time.Time(folder.Created); !created.IsZero() {
+		details.Created = &created
+	}
+	if updated := time.Time(folder.Updated); !updated.IsZero() {
+		details.Updated = &updated
+	}
+	// Descendant counts are not available on older Grafana versions.
+	if counts, err := c.Folders.GetFolderDescendantCounts(args.UID); err == nil {
+		details.DescendantCounts = counts.Payload
+	}
+	return details, nil
+}
+
+var GetFolder = mcpgrafana.MustTool(
+	"get_folder",
+	"Get the details of a folder by UID: title, URL, parent folder, path from the root, version, the caller's permissions and counts of the folders, dashboards, library panels and alert rules it contains.",
+	getFolder,
+	mcp.WithTitleAnnotation("Get folder"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type RenameFolderParams struct {
+	UID         string `json:"uid" jsonschema:"required,description=The UID of the folder"`
+	Title       string `json:"title" jsonschema:"required,description=The new title of the folder"`
+	Description string `json:"description,omitempty" jsonschema:"description=Optional new description of the folder"`
+}
+
+func renameFolder(ctx context.Context, args RenameFolderParams) (*models.Folder, error) {
+	if args.UID == "" || args.Title == "" {
+		return nil, fmt.Errorf("rename folder: uid and title are required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	current, err := c.Folders.GetFolderByUID(args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("rename folder %s: %w", args.UID, err)
+	}
+	resp, err := c.Folders.UpdateFolder(args.UID, &models.UpdateFolderCommand{
+		Title:       args.Title,
+		Description: args.Description,
+		Version:     current.Payload.Version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rename folder %s: %w", args.UID, err)
+	}
+	return resp.Payload, nil
+}
+
+var RenameFolder = mcpgrafana.MustTool(
+	"rename_folder",
+	"Rename a folder and optionally update its description. Returns the updated folder.",
+	renameFolder,
+	mcp.WithTitleAnnotation("Rename folder"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+)
+
+type MoveFolderParams struct {
+	UID       string `json:"uid" jsonschema:"required,description=The UID of the folder to move"`
+	ParentUID string `json:"parentUid,omitempty" jsonschema:"description=UID of the new parent folder. Leave empty to move the folder to the root."`
+}
+
+func moveFolder(ctx context.Context, args MoveFolderParams) (*models.Folder, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("move folder: uid is required")
+	}
+	if args.UID == args.ParentUID {
+		return nil, fmt.Errorf("move folder: a folder cannot be moved into itself")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Folders.MoveFolder(args.UID, &models.MoveFolderCommand{ParentUID: args.ParentUID})
+	if err != nil {
+		return nil, fmt.Errorf("move folder %s: %w", args.UID, err)
+	}
+	return resp.Payload, nil
+}
+
+var MoveFolder = mcpgrafana.MustTool(
+	"move_folder",
+	"Move a folder, with everything in it, under a new parent folder or to the root. Requires nested folders to be enabled. Returns the moved folder.",
+	moveFolder,
+	mcp.WithTitleAnnotation("Move folder"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(false),
+)
+
+type DeleteFolderParams struct {
+	UID   string `json:"uid" jsonschema:"required,description=The UID of the folder to delete"`
+	Force bool   `json:"force,omitempty" jsonschema:"description=Delete the folder even if it contains dashboards or alert rules. These are deleted together with the folder."`
+}
+
+// FolderContentRef is a dashboard or alert rule found in a folder.
+type FolderContentRef struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	FolderUID string `json:"folderUid"`
+}
+
+// DeleteFolderResult is the outcome of delete_folder, including what the
+// folder contained.
+type DeleteFolderResult struct {
+	UID                 string             `json:"uid"`
+	Title               string             `json:"title"`
+	Deleted             bool               `json:"deleted"`
+	Message             string             `json:"message"`
+	Subfolders          []FolderTreeNode   `json:"subfolders,omitempty"`
+	SubfoldersTruncated bool               `json:"subfoldersTruncated,omitempty"`
+	Dashboards          []FolderContentRef `json:"dashboards,omitempty"`
+	DashboardsTruncated bool               `json:"dashboardsTruncated,omitempty"`
+	AlertRules          []FolderContentRef `json:"alertRules,omitempty"`
+}
+
+func deleteFolder(ctx context.Context, args DeleteFolderParams) (*DeleteFolderResult, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("delete folder: uid is required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	folder, err := c.Folders.GetFolderByUID(args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder %s: %w", args.UID, err)
+	}
+	result := &DeleteFolderResult{UID: args.UID, Title: folder.Payload.Title}
+
+	// Deleting a folder deletes everything below it, so check the whole
+	// subtree.
+	tree, err := walkFolderTree(ctx, args.UID, maxFolderTreeDepth)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder %s: list subfolders: %w", args.UID, err)
+	}
+	result.Subfolders = tree.Folders
+	result.SubfoldersTruncated = tree.Truncated
+	folderUIDs := map[string]bool{args.UID: true}
+	for _, f := range tree.Folders {
+		folderUIDs[f.UID] = true
+	}
+
+	hits, truncated, err := searchAllDashboards(ctx, sortedKeys(folderUIDs), maxFolderContentsListed)
+	if err != nil {
+		return nil, fmt.Errorf("delete folder %s: %w", args.UID, err)
+	}
+	result.DashboardsTruncated = truncated
+	for _, hit := range hits {
+		result.Dashboards = append(result.Dashboards, FolderContentRef{UID: hit.UID, Title: hit.Title, FolderUID: hit.FolderUID})
+	}
+
+	rules, err := c.Provisioning.GetAlertRules()
+	if err != nil {
+		return nil, fmt.Errorf("delete folder %s: list alert rules: %w", args.UID, err)
+	}
+	for _, rule := range rules.Payload {
+		if rule.FolderUID == nil || !folderUIDs[*rule.FolderUID] {
+			continue
+		}
+		ref := FolderContentRef{UID: rule.UID, FolderUID: *rule.FolderUID}
+		if rule.Title != nil {
+			ref.Title = *rule.Title
+		}
+		result.AlertRules = append(result.AlertRules, ref)
+	}
+
+	if result.SubfoldersTruncated && !args.Force {
+		result.Message = fmt.Sprintf("Folder not deleted: it has more than %d subfolders, so its contents could not be fully checked. Review them and set force=true to delete the folder with its contents.", maxFolderTreeFolders)
+		return result, nil
+	}
+	if (len(result.Dashboards) > 0 || len(result.AlertRules) > 0) && !args.Force {
+		result.Message = fmt.Sprintf("Folder not deleted: it contains %d dashboard(s) and %d alert rule(s). Review them and set force=true to delete the folder with its contents.", len(result.Dashboards), len(result.AlertRules))
+		if result.DashboardsTruncated {
+			result.Message = fmt.Sprintf("Folder not deleted: it contains more than %d dashboards and %d alert rule(s). Review them and set force=true to delete the folder with its contents.", maxFolderContentsListed, len(result.AlertRules))
+		}
+		return result, nil
+	}
+
+	forceDeleteRules := true
+	params := folders.NewDeleteFolderParamsWithContext(ctx).WithFolderUID(args.UID).WithForceDeleteRules(&forceDeleteRules)
+	if _, err := c.Folders.DeleteFolder(params); err != nil {
+		return nil, fmt.Errorf("delete folder %s: %w", args.UID, err)
+	}
+	result.Deleted = true
+	result.Message = fmt.Sprintf("Folder '%s' deleted successfully", result.Title)
+	return result, nil
+}
+
+var DeleteFolder = mcpgrafana.MustTool(
+	"delete_folder",
+	"Delete a folder and everything below it. As a safety check, the folder is only deleted if it contains no dashboards or alert rules (including in nested folders) unless force is set; otherwise the contained subfolders, dashboards and alert rules are returned so they can be reviewed first.",
+	deleteFolder,
+	mcp.WithTitleAnnotation("Delete folder"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
+
+type GetFolderPermissionsParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the folder"`
+}
+
+// FolderPermission is a single permission entry of a folder. Exactly one of
+// role, user or team identifies who the permission is granted to.
+type FolderPermission struct {
+	Permission string `json:"permission"`
+	Role       string `json:"role,omitempty"`
+	UserLogin  string `json:"userLogin,omitempty"`
+	UserEmail  string `json:"userEmail,omitempty"`
+	Team       string `json:"team,omitempty"`
+	TeamUID    string `json:"teamUid,omitempty"`
+	// Inherited is true for permissions inherited from a parent folder.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+func getFolderPermissions(ctx context.Context, args GetFolderPermissionsParams) ([]FolderPermission, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("get folder permissions: uid is required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Folders.GetFolderPermissionList(args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("get folder permissions %s: %w", args.UID, err)
+	}
+	permissions := make([]FolderPermission, 0, len(resp.Payload))
+	for _, p := range resp.Payload {
+		permissions = append(permissions, FolderPermission{
+			Permission: p.PermissionName,
+			Role:       p.Role,
+			UserLogin:  p.UserLogin,
+			UserEmail:  p.UserEmail,
+			Team:       p.Team,
+			TeamUID:    p.TeamUID,
+			Inherited:  p.Inherited,
+		})
+	}
+	return permissions, nil
+}
+
+var GetFolderPermissions = mcpgrafana.MustTool(
+	"get_folder_permissions",
+	"List the permissions of a folder: the permission level (View, Edit, Admin) granted to each role, user and team, and whether it is inherited from a parent folder.",
+	getFolderPermissions,
+	mcp.WithTitleAnnotation("Get folder permissions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 func AddFolderTools(mcp *server.MCPServer, enableWriteTools bool) {
 	if enableWriteTools {
 		CreateFolder.Register(mcp)
+		RenameFolder.Register(mcp)
+		MoveFolder.Register(mcp)
+		DeleteFolder.Register(mcp)
 	}
+	ListFolderTree.Register(mcp)
+	GetFolder.Register(mcp)
+	GetFolderPermissions.Register(mcp)
 }
@@ -0,0 +1,156 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFolderTreeServer serves the tree team > (api > db, web) with one
+// dashboard and one alert rule in "api".
+func newFolderTreeServer(t *testing.T, deleted *[]string) *httptest.Server {
+	children := map[string]string{
+		"":     `[{"uid": "team", "title": "Team"}, {"uid": "other", "title": "Other"}]`,
+		"team": `[{"uid": "api", "title": "API"}, {"uid": "web", "title": "Web"}]`,
+		"api":  `[{"uid": "db", "title": "Database"}]`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders":
+			body, ok := children[r.URL.Query().Get("parentUid")]
+			if !ok {
+				body = `[]`
+			}
+			_, _ = w.Write([]byte(body))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders/team":
+			_, _ = w.Write([]byte(`{"uid": "team", "title": "Team", "version": 2}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders/web":
+			_, _ = w.Write([]byte(`{"uid": "web", "title": "Web", "parentUid": "team", "url": "/dashboards/f/web/", "canEdit": true, "updated": "2024-03-01T10:00:00Z",
+				"parents": [{"uid": "team", "title": "Team"}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/folders/web/counts":
+			_, _ = w.Write([]byte(`{"folder": 0, "dashboard": 0, "librarypanel": 1, "alertrule": 0}`))
+		case r.URL.Path == "/api/search":
+			folderUIDs := r.URL.Query()["folderUIDs"]
+			assert.ElementsMatch(t, []string{"team", "api", "web", "db"}, folderUIDs)
+			_, _ = w.Write([]byte(`[{"uid": "d1", "title": "API overview", "folderUid": "api", "type": "dash-db"}]`))
+		case r.URL.Path == "/api/v1/provisioning/alert-rules":
+			_, _ = w.Write([]byte(`[
+				{"uid": "r1", "title": "API errors", "folderUID": "db", "ruleGroup": "g", "condition": "A", "data": [], "noDataState": "OK", "execErrState": "OK", "for": "5m", "orgID": 1},
+				{"uid": "r2", "title": "Elsewhere", "folderUID": "other", "ruleGroup": "g", "condition": "A", "data": [], "noDataState": "OK", "execErrState": "OK", "for": "5m", "orgID": 1}
+			]`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/folders/team":
+			assert.Equal(t, "true", r.URL.Query().Get("forceDeleteRules"))
+			*deleted = append(*deleted, "team")
+			_, _ = w.Write([]byte(`{"message": "Folder deleted", "title": "Team"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestListFolderTree(t *testing.T) {
+	server := newFolderTreeServer(t, nil)
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	result, err := listFolderTree(ctx, ListFolderTreeParams{})
+	require.NoError(t, err)
+	assert.Equal(t, []FolderTreeNode{
+		{UID: "team", Title: "Team", Depth: 1, Path: "Team"},
+		{UID: "api", Title: "API", ParentUID: "team", Depth: 2, Path: "Team/API"},
+		{UID: "db", Title: "Database", ParentUID: "api", Depth: 3, Path: "Team/API/Database"},
+		{UID: "web", Title: "Web", ParentUID: "team", Depth: 2, Path: "Team/Web"},
+		{UID: "other", Title: "Other", Depth: 1, Path: "Other"},
+	}, result.Folders)
+
+	result, err = listFolderTree(ctx, ListFolderTreeParams{ParentUID: "team", MaxDepth: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []FolderTreeNode{
+		{UID: "api", Title: "API", ParentUID: "team", Depth: 1, Path: "API"},
+		{UID: "web", Title: "Web", ParentUID: "team", Depth: 1, Path: "Web"},
+	}, result.Folders)
+}
+
+func TestGetFolder(t *testing.T) {
+	server := newFolderTreeServer(t, nil)
+	defer server.Close()
+
+	folder, err := getFolder(mockCtxWithClient(server), GetFolderParams{UID: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "Team/Web", folder.Path)
+	assert.Equal(t, "team", folder.ParentUID)
+	assert.True(t, folder.CanEdit)
+	assert.Equal(t, map[string]int64{"folder": 0, "dashboard": 0, "librarypanel": 1, "alertrule": 0}, folder.DescendantCounts)
+	assert.Nil(t, folder.Created)
+	require.NotNil(t, folder.Updated)
+	assert.Equal(t, 2024, folder.Updated.Year())
+
+	out, err := json.Marshal(folder)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), `"created"`)
+}
+
+func TestDeleteFolder_SafetyCheck(t *testing.T) {
+	var deleted []string
+	server := newFolderTreeServer(t, &deleted)
+	defer server.Close()
+	ctx := mockCtxWithClient(server)
+
+	result, err := deleteFolder(ctx, DeleteFolderParams{UID: "team"})
+	require.NoError(t, err)
+	assert.False(t, result.Deleted)
+	assert.Empty(t, deleted)
+	assert.Len(t, result.Subfolders, 3)
+	assert.Equal(t, []FolderContentRef{{UID: "d1", Title: "API overview", FolderUID: "api"}}, result.Dashboards)
+	assert.Equal(t, []FolderContentRef{{UID: "r1", Title: "API errors", FolderUID: "db"}}, result.AlertRules)
+	assert.Contains(t, result.Message, "1 dashboard(s) and 1 alert rule(s)")
+
+	result, err = deleteFolder(ctx, DeleteFolderParams{UID: "team", Force: true})
+	require.NoError(t, err)
+	assert.True(t, result.Deleted)
+	assert.Equal(t, []string{"team"}, deleted)
+}
+
+func TestDeleteFolder_RefusesTruncatedTree(t *testing.T) {
+	subfolders := make([]map[string]string, maxFolderTreeFolders+1)
+	for i := range subfolders {
+		subfolders[i] = map[string]string{"uid": fmt.Sprintf("sub-%d", i), "title": fmt.Sprintf("Sub %d", i)}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/folders/big":
+			_, _ = w.Write([]byte(`{"uid": "big", "title": "Big"}`))
+		case r.URL.Path == "/api/folders" && r.URL.Query().Get("parentUid") == "big":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			start := min((page-1)*folderPageSize, len(subfolders))
+			_ = json.NewEncoder(w).Encode(subfolders[start:min(start+folderPageSize, len(subfolders))])
+		case r.URL.Path == "/api/folders":
+			_, _ = w.Write([]byte(`[]`))
+		case r.URL.Path == "/api/search":
+			_, _ = w.Write([]byte(`[]`))
+		case r.URL.Path == "/api/v1/provisioning/alert-rules":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	result, err := deleteFolder(mockCtxWithClient(server), DeleteFolderParams{UID: "big"})
+	require.NoError(t, err)
+	assert.False(t, result.Deleted)
+	assert.True(t, result.SubfoldersTruncated)
+	assert.Contains(t, result.Message, "could not be fully checked")
+}
@@ -0,0 +1,461 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/grafana/grafana-openapi-client-go/client/library_elements"
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// libraryElementKindPanel is the library element kind of library panels.
+const libraryElementKindPanel = 1
+
+type ListLibraryPanelsParams struct {
+	Query     string `json:"query,omitempty" jsonschema:"description=Search string matched against the library panel name and description"`
+	PanelType string `json:"panelType,omitempty" jsonschema:"description=Only return library panels of this panel type (e.g. 'timeseries')"`
+	Page      int    `json:"page,omitempty" jsonschema:"default=1,description=The page number to return"`
+	PerPage   int    `json:"perPage,omitempty" jsonschema:"default=100,description=The number of library panels per page"`
+}
+
+// LibraryPanelSummary is a compact description of a library panel.
+type LibraryPanelSummary struct {
+	UID                 string `json:"uid"`
+	Name                string `json:"name"`
+	Type                string `json:"type"`
+	Description         string `json:"description,omitempty"`
+	FolderUID           string `json:"folderUid,omitempty"`
+	FolderName          string `json:"folderName,omitempty"`
+	ConnectedDashboards int64  `json:"connectedDashboards"`
+	Version             int64  `json:"version"`
+}
+
+// LibraryPanelList is a page of library panels.
+type LibraryPanelList struct {
+	LibraryPanels []LibraryPanelSummary `json:"libraryPanels"`
+	TotalCount    int64                 `json:"totalCount"`
+	Page          int64                 `json:"page"`
+	PerPage       int64                 `json:"perPage"`
+}
+
+func summarizeLibraryPanel(e *models.LibraryElementDTO) LibraryPanelSummary {
+	summary := LibraryPanelSummary{
+		UID:         e.UID,
+		Name:        e.Name,
+		Type:        e.Type,
+		Description: e.Description,
+		FolderUID:   e.FolderUID,
+		Version:     e.Version,
+	}
+	if e.Meta != nil {
+		summary.FolderName = e.Meta.FolderName
+		summary.ConnectedDashboards = e.Meta.ConnectedDashboards
+	}
+	return summary
+}
+
+func listLibraryPanels(ctx context.Context, args ListLibraryPanelsParams) (*LibraryPanelList, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := library_elements.NewGetLibraryElementsParamsWithContext(ctx)
+	kind := int64(libraryElementKindPanel)
+	params.SetKind(&kind)
+	if args.Query != "" {
+		params.SetSearchString(&args.Query)
+	}
+	if args.PanelType != "" {
+		params.SetTypeFilter(&args.PanelType)
+	}
+	if args.Page > 0 {
+		page := int64(args.Page)
+		params.SetPage(&page)
+	}
+	if args.PerPage > 0 {
+		perPage := int64(args.PerPage)
+		params.SetPerPage(&perPage)
+	}
+
+	resp, err := c.LibraryElements.GetLibraryElements(params)
+	if err != nil {
+		return nil, fmt.Errorf("list library panels: %w", err)
+	}
+	result := &LibraryPanelList{LibraryPanels: []LibraryPanelSummary{}}
+	if resp.Payload == nil || resp.Payload.Result == nil {
+		return result, nil
+	}
+	for _, e := range resp.Payload.Result.Elements {
+		result.LibraryPanels = append(result.LibraryPanels, summarizeLibraryPanel(e))
+	}
+	result.TotalCount = resp.Payload.Result.TotalCount
+	result.Page = resp.Payload.Result.Page
+	result.PerPage = resp.Payload.Result.PerPage
+	return result, nil
+}
+
+var ListLibraryPanels = mcpgrafana.MustTool(
+	"list_library_panels",
+	"List or search library panels (reusable panels shared between dashboards). Returns the UID\\, name\\, panel type\\, folder and number of connected dashboards of each library panel.",
+	listLibraryPanels,
+	mcp.WithTitleAnnotation("List library panels"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type GetLibraryPanelParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the library panel"`
+}
+
+// ConnectedDashboard is a dashboard that uses a library panel.
+type ConnectedDashboard struct {
+	UID         string `json:"uid"`
+	Title       string `json:"title,omitempty"`
+	URL         string `json:"url,omitempty"`
+	FolderTitle string `json:"folderTitle,omitempty"`
+}
+
+// LibraryPanelDetails is a library panel with its model and the dashboards
+// that use it.
+type LibraryPanelDetails struct {
+	LibraryPanelSummary
+	Model               interface{}          `json:"model"`
+	ConnectedDashboards []ConnectedDashboard `json:"connectedDashboardList"`
+}
+
+func getLibraryPanelByUID(ctx context.Context, uid string) (*models.LibraryElementDTO, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := library_elements.NewGetLibraryElementByUIDParamsWithContext(ctx).WithLibraryElementUID(uid)
+	resp, err := c.LibraryElements.GetLibraryElementByUIDWithParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("get library panel %s: %w", uid, err)
+	}
+	if resp.Payload == nil || resp.Payload.Result == nil {
+		return nil, fmt.Errorf("get library panel %s: empty response", uid)
+	}
+	return resp.Payload.Result, nil
+}
+
+func getLibraryPanel(ctx context.Context, args GetLibraryPanelParams) (*LibraryPanelDetails, error) {
+	element, err := getLibraryPanelByUID(ctx, args.UID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := library_elements.NewGetLibraryElementConnectionsParamsWithContext(ctx).WithLibraryElementUID(args.UID)
+	connections, err := c.LibraryElements.GetLibraryElementConnectionsWithParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("get library panel connections %s: %w", args.UID, err)
+	}
+
+	result := &LibraryPanelDetails{
+		LibraryPanelSummary: summarizeLibraryPanel(element),
+		Model:               element.Model,
+		ConnectedDashboards: []ConnectedDashboard{},
+	}
+	if connections.Payload == nil {
+		return result, nil
+	}
+	var uids []string
+	for _, conn := range connections.Payload.Result {
+		if conn.ConnectionUID != "" {
+			uids = append(uids, conn.ConnectionUID)
+		}
+	}
+	if len(uids) == 0 {
+		return result, nil
+	}
+
+	// Look up dashboard titles in a single search request. Dashboards the
+	// user cannot read are still listed by UID.
+	hits := map[string]*models.Hit{}
+	searchParams := search.NewSearchParamsWithContext(ctx)
+	searchParams.SetType(&dashboardTypeStr)
+	if resp, err := c.Search.Search(searchParams, repeatedQueryParams(map[string][]string{"dashboardUIDs": uids})); err == nil {
+		for _, hit := range resp.Payload {
+			hits[hit.UID] = hit
+		}
+	}
+	for _, uid := range uids {
+		dashboard := ConnectedDashboard{UID: uid}
+		if hit, ok := hits[uid]; ok {
+			dashboard.Title = hit.Title
+			dashboard.URL = hit.URL
+			dashboard.FolderTitle = hit.FolderTitle
+		}
+		result.ConnectedDashboards = append(result.ConnectedDashboards, dashboard)
+	}
+	return result, nil
+}
+
+var GetLibraryPanel = mcpgrafana.MustTool(
+	"get_library_panel",
+	"Get a library panel by UID\\, including its full panel model (queries\\, field config\\, options) and the dashboards that use it.",
+	getLibraryPanel,
+	mcp.WithTitleAnnotation("Get library panel"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateLibraryPanelParams struct {
+	DashboardUID string `json:"dashboardUid" jsonschema:"required,description=UID of the dashboard containing the panel"`
+	PanelID      int    `json:"panelId" jsonschema:"required,description=ID of the panel to turn into a library panel"`
+	Name         string `json:"name,omitempty" jsonschema:"description=Name of the library panel. Defaults to the panel title."`
+	FolderUID    string `json:"folderUid,omitempty" jsonschema:"description=Folder to store the library panel in. Defaults to the folder of the dashboard."`
+	UID          string `json:"uid,omitempty" jsonschema:"description=UID of the new library panel. Generated by Grafana if omitted."`
+	LinkPanel    bool   `json:"linkPanel,omitempty" jsonschema:"description=Replace the dashboard panel with a reference to the new library panel and save the dashboard"`
+}
+
+func (p CreateLibraryPanelParams) validate() error {
+	if p.DashboardUID == "" {
+		return fmt.Errorf("dashboardUid is required")
+	}
+	if p.PanelID == 0 {
+		return fmt.Errorf("panelId is required")
+	}
+	return nil
+}
+
+// findPanelByID returns the panel with the given ID, including panels nested
+// in collapsed rows.
+func findPanelByID(db map[string]interface{}, id int) (dashboardPanel, bool) {
+	for _, p := range collectPanels(db) {
+		if safeInt(p.Panel, "id") == id {
+			return p, true
+		}
+	}
+	return dashboardPanel{}, false
+}
+
+func createLibraryPanel(ctx context.Context, args CreateLibraryPanelParams) (*LibraryPanelSummary, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("create library panel: %w", err)
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, fmt.Errorf("create library panel: %w", err)
+	}
+	db, ok := dashboard.Dashboard.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("create library panel: dashboard is not a JSON object")
+	}
+	found, ok := findPanelByID(db, args.PanelID)
+	if !ok {
+		return nil, fmt.Errorf("create library panel: panel %d not found in dashboard %s", args.PanelID, args.DashboardUID)
+	}
+	if safeObject(found.Panel, "libraryPanel") != nil {
+		return nil, fmt.Errorf("create library panel: panel %d is already a library panel", args.PanelID)
+	}
+	if safeString(found.Panel, "type") == "row" {
+		return nil, fmt.Errorf("create library panel: rows cannot be library panels")
+	}
+
+	name := args.Name
+	if name == "" {
+		name = safeString(found.Panel, "title")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("create library panel: name is required for panels without a title")
+	}
+	folderUID := args.FolderUID
+	if folderUID == "" && dashboard.Meta != nil {
+		folderUID = dashboard.Meta.FolderUID
+	}
+
+	// The library panel model does not include the dashboard-specific
+	// panel ID and position.
+	model := make(map[string]interface{}, len(found.Panel))
+	for k, v := range found.Panel {
+		if k != "id" && k != "gridPos" {
+			model[k] = v
+		}
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.CreateLibraryElement(&models.CreateLibraryElementCommand{
+		FolderUID: folderUID,
+		Kind:      libraryElementKindPanel,
+		Model:     model,
+		Name:      name,
+		UID:       args.UID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create library panel: %w", err)
+	}
+	if resp.Payload == nil || resp.Payload.Result == nil {
+		return nil, fmt.Errorf("create library panel: empty response")
+	}
+	created := resp.Payload.Result
+
+	if args.LinkPanel {
+		for k := range found.Panel {
+			if k != "id" && k != "gridPos" {
+				delete(found.Panel, k)
+			}
+		}
+		found.Panel["title"] = name
+		found.Panel["libraryPanel"] = map[string]interface{}{"uid": created.UID, "name": created.Name}
+		_, err := updateDashboardWithFullJSON(ctx, UpdateDashboardParams{
+			Dashboard: db,
+			FolderUID: folderUID,
+			Message:   fmt.Sprintf("Linked panel %d to library panel %s", args.PanelID, created.Name),
+			Overwrite: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create library panel: library panel %s created but linking the dashboard panel failed: %w", created.UID, err)
+		}
+	}
+
+	summary := summarizeLibraryPanel(created)
+	return &summary, nil
+}
+
+var CreateLibraryPanel = mcpgrafana.MustTool(
+	"create_library_panel",
+	"Create a library panel from an existing dashboard panel. Optionally replaces the original panel with a reference to the new library panel so that the dashboard uses the shared version.",
+	createLibraryPanel,
+	mcp.WithTitleAnnotation("Create library panel"),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type UpdateLibraryPanelParams struct {
+	UID       string                 `json:"uid" jsonschema:"required,description=The UID of the library panel to update"`
+	Model     map[string]interface{} `json:"model,omitempty" jsonschema:"description=The new panel model. Replaces the existing model; fetch it with get_library_panel first."`
+	Name      string                 `json:"name,omitempty" jsonschema:"description=New name of the library panel"`
+	FolderUID string                 `json:"folderUid,omitempty" jsonschema:"description=Move the library panel to this folder"`
+}
+
+func (p UpdateLibraryPanelParams) validate() error {
+	if p.UID == "" {
+		return fmt.Errorf("uid is required")
+	}
+	if p.Model == nil && p.Name == "" && p.FolderUID == "" {
+		return fmt.Errorf("at least one of model, name or folderUid is required")
+	}
+	return nil
+}
+
+func updateLibraryPanel(ctx context.Context, args UpdateLibraryPanelParams) (*LibraryPanelSummary, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("update library panel: %w", err)
+	}
+
+	current, err := getLibraryPanelByUID(ctx, args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("update library panel: %w", err)
+	}
+
+	cmd := &models.PatchLibraryElementCommand{
+		FolderUID: current.FolderUID,
+		Kind:      libraryElementKindPanel,
+		Model:     current.Model,
+		Name:      current.Name,
+		UID:       current.UID,
+		Version:   current.Version,
+	}
+	if args.Model != nil {
+		cmd.Model = args.Model
+	}
+	if args.Name != "" {
+		cmd.Name = args.Name
+	}
+	if args.FolderUID != "" {
+		cmd.FolderUID = args.FolderUID
+	}
+
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.LibraryElements.UpdateLibraryElement(args.UID, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("update library panel %s: %w", args.UID, err)
+	}
+	if resp.Payload == nil || resp.Payload.Result == nil {
+		return nil, fmt.Errorf("update library panel %s: empty response", args.UID)
+	}
+	summary := summarizeLibraryPanel(resp.Payload.Result)
+	return &summary, nil
+}
+
+var UpdateLibraryPanel = mcpgrafana.MustTool(
+	"update_library_panel",
+	"Update a library panel's model\\, name or folder. Changes to the model apply to every dashboard that uses the library panel.",
+	updateLibraryPanel,
+	mcp.WithTitleAnnotation("Update library panel"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
+
+// libraryPanelResolver replaces library panel references in dashboards with
+// the library panel model, caching models for the lifetime of the resolver.
+type libraryPanelResolver struct {
+	models map[string]map[string]interface{}
+}
+
+func newLibraryPanelResolver() *libraryPanelResolver {
+	return &libraryPanelResolver{models: map[string]map[string]interface{}{}}
+}
+
+// resolve returns the panel as Grafana renders it: the library panel model
+// merged with the dashboard panel's ID and position. Panels that are not
+// library panel references are returned unchanged.
+func (r *libraryPanelResolver) resolve(ctx context.Context, panel map[string]interface{}) (map[string]interface{}, error) {
+	uid := safeString(safeObject(panel, "libraryPanel"), "uid")
+	if uid == "" {
+		return panel, nil
+	}
+	model, ok := r.models[uid]
+	if !ok {
+		element, err := getLibraryPanelByUID(ctx, uid)
+		if err != nil {
+			return panel, err
+		}
+		model, _ = element.Model.(map[string]interface{})
+		r.models[uid] = model
+	}
+	if model == nil {
+		return panel, nil
+	}
+
+	resolved := make(map[string]interface{}, len(model)+3)
+	for k, v := range model {
+		resolved[k] = v
+	}
+	for _, k := range []string{"id", "gridPos", "libraryPanel"} {
+		if v, ok := panel[k]; ok {
+			resolved[k] = v
+		}
+	}
+	return resolved, nil
+}
+
+// resolveDashboard replaces every library panel reference in a dashboard,
+// including panels nested in collapsed rows, with the resolved panel.
+func (r *libraryPanelResolver) resolveDashboard(ctx context.Context, db map[string]interface{}) error {
+	resolveAll := func(panels []interface{}) error {
+		for i, p := range panels {
+			panel, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			resolved, err := r.resolve(ctx, panel)
+			if err != nil {
+				return err
+			}
+			panels[i] = resolved
+		}
+		return nil
+	}
+	panels := safeArray(db, "panels")
+	if err := resolveAll(panels); err != nil {
+		return err
+	}
+	for _, p := range panels {
+		if row, ok := p.(map[string]interface{}); ok && safeString(row, "type") == "row" {
+			if err := resolveAll(safeArray(row, "panels")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
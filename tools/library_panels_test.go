@@ -0,0 +1,161 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const libraryPanelResponse = `{"result": {"uid": "lib-1", "name": "Shared requests", "type": "timeseries", "folderUid": "team", "version": 4,
+	"meta": {"folderName": "Team", "connectedDashboards": 1},
+	"model": {"type": "timeseries", "title": "Shared requests", "datasource": {"uid": "prom", "type": "prometheus"},
+		"targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"}]}}}`
+
+func TestGetDashboardPanelQueries_ResolvesLibraryPanels(t *testing.T) {
+	libraryRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/dashboards/uid/db-1":
+			_, _ = w.Write([]byte(`{"dashboard": {"uid": "db-1", "panels": [
+				{"id": 1, "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}, "libraryPanel": {"uid": "lib-1", "name": "Shared requests"}},
+				{"id": 2, "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}, "libraryPanel": {"uid": "lib-1", "name": "Shared requests"}},
+				{"id": 3, "title": "Local", "datasource": {"uid": "prom"}, "targets": [{"expr": "up"}]},
+				{"id": 4, "gridPos": {"x": 0, "y": 8, "w": 12, "h": 8}, "libraryPanel": {"uid": "lib-gone", "name": "Deleted panel"}}
+			]}}`))
+		case "/api/library-elements/lib-1":
+			libraryRequests++
+			_, _ = w.Write([]byte(libraryPanelResponse))
+		case "/api/library-elements/lib-gone":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "library element could not be found"}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	queries, err := GetDashboardPanelQueriesTool(mockCtxWithClient(server), DashboardPanelQueriesParams{UID: "db-1"})
+	require.NoError(t, err)
+	require.Len(t, queries, 4)
+	assert.Equal(t, "Shared requests", queries[0].Title)
+	assert.Equal(t, "sum(rate(http_requests_total[5m]))", queries[0].Query)
+	assert.Equal(t, datasourceInfo{UID: "prom", Type: "prometheus"}, queries[0].Datasource)
+	assert.Equal(t, "lib-1", queries[0].LibraryPanelUID)
+	assert.Equal(t, "up", queries[2].Query)
+	assert.Empty(t, queries[2].LibraryPanelUID)
+	// A missing library panel is reported without failing the whole tool.
+	assert.Equal(t, "Deleted panel", queries[3].Title)
+	assert.Equal(t, "lib-gone", queries[3].LibraryPanelUID)
+	assert.Empty(t, queries[3].Query)
+	assert.Contains(t, queries[3].Error, "resolve library panel")
+	assert.Equal(t, 1, libraryRequests, "library panel models should be cached")
+}
+
+func TestGetLibraryPanel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/library-elements/lib-1":
+			_, _ = w.Write([]byte(libraryPanelResponse))
+		case "/api/library-elements/lib-1/connections/":
+			_, _ = w.Write([]byte(`{"result": [{"connectionUid": "db-1"}, {"connectionUid": "db-hidden"}]}`))
+		case "/api/search":
+			assert.Equal(t, []string{"db-1", "db-hidden"}, r.URL.Query()["dashboardUIDs"])
+			_, _ = w.Write([]byte(`[{"uid": "db-1", "title": "API", "url": "/d/db-1/api", "folderTitle": "Team"}]`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	result, err := getLibraryPanel(mockCtxWithClient(server), GetLibraryPanelParams{UID: "lib-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "Shared requests", result.Name)
+	assert.Equal(t, "Team", result.FolderName)
+	assert.NotNil(t, result.Model)
+	assert.Equal(t, []ConnectedDashboard{
+		{UID: "db-1", Title: "API", URL: "/d/db-1/api", FolderTitle: "Team"},
+		{UID: "db-hidden"},
+	}, result.ConnectedDashboards)
+}
+
+func TestCreateLibraryPanel_LinksPanel(t *testing.T) {
+	var created, saved map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/db-1":
+			_, _ = w.Write([]byte(`{"meta": {"folderUid": "team"}, "dashboard": {"uid": "db-1", "panels": [
+				{"id": 1, "type": "row", "collapsed": true, "panels": [
+					{"id": 2, "type": "stat", "title": "Errors", "gridPos": {"x": 0, "y": 1, "w": 6, "h": 4}, "targets": [{"expr": "errors"}]}
+				]}
+			]}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/library-elements":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+			_, _ = w.Write([]byte(`{"result": {"uid": "lib-new", "name": "Errors", "type": "stat", "version": 1}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&saved))
+			_, _ = w.Write([]byte(`{"uid": "db-1", "status": "success"}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	result, err := createLibraryPanel(mockCtxWithClient(server), CreateLibraryPanelParams{DashboardUID: "db-1", PanelID: 2, LinkPanel: true})
+	require.NoError(t, err)
+	assert.Equal(t, "lib-new", result.UID)
+
+	assert.Equal(t, "Errors", created["name"])
+	assert.Equal(t, "team", created["folderUid"])
+	assert.EqualValues(t, libraryElementKindPanel, created["kind"])
+	model := created["model"].(map[string]interface{})
+	assert.NotContains(t, model, "id")
+	assert.NotContains(t, model, "gridPos")
+	assert.Equal(t, "stat", model["type"])
+
+	row := saved["dashboard"].(map[string]interface{})["panels"].([]interface{})[0].(map[string]interface{})
+	linked := row["panels"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"uid": "lib-new", "name": "Errors"}, linked["libraryPanel"])
+	assert.NotContains(t, linked, "targets")
+	assert.Contains(t, linked, "gridPos")
+
+	_, err = createLibraryPanel(mockCtxWithClient(server), CreateLibraryPanelParams{DashboardUID: "db-1", PanelID: 99})
+	assert.ErrorContains(t, err, "panel 99 not found")
+}
+
+func TestUpdateLibraryPanel_KeepsUnchangedFields(t *testing.T) {
+	var patch map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(libraryPanelResponse))
+		case http.MethodPatch:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&patch))
+			_, _ = w.Write([]byte(`{"result": {"uid": "lib-1", "name": "Renamed", "version": 5}}`))
+		}
+	}))
+	defer server.Close()
+
+	result, err := updateLibraryPanel(mockCtxWithClient(server), UpdateLibraryPanelParams{UID: "lib-1", Name: "Renamed"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, result.Version)
+	assert.Equal(t, "Renamed", patch["name"])
+	assert.EqualValues(t, 4, patch["version"])
+	assert.Equal(t, "team", patch["folderUid"])
+	assert.Equal(t, "Shared requests", patch["model"].(map[string]interface{})["title"])
+
+	_, err = updateLibraryPanel(mockCtxWithClient(server), UpdateLibraryPanelParams{UID: "lib-1"})
+	assert.Error(t, err)
+}
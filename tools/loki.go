@@ -15,6 +15,8 @@ import (
 	mcpgrafana "github.com/grafana/mcp-grafana"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 )
 
 const (
@@ -378,6 +380,45 @@ func (c *Client) fetchLogs(ctx context.Context, query, startRFC3339, endRFC3339
 	return queryResponse.Data.Result, nil
 }
 
+// fetchMetricRange runs a LogQL metric query over a range and returns the
+// resulting series.
+func (c *Client) fetchMetricRange(ctx context.Context, query string, r promv1.Range) (model.Matrix, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	params.Add("start", fmt.Sprintf("%d", r.Start.UnixNano()))
+	params.Add("end", fmt.Sprintf("%d", r.End.UnixNano()))
+	params.Add("step", strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64))
+
+	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the result type before the result itself: a log query returns
+	// streams, which do not unmarshal into a matrix.
+	var queryResponse struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &queryResponse); err != nil {
+		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	}
+	if queryResponse.Status != "success" {
+		return nil, fmt.Errorf("loki API returned unexpected response format: %s", string(bodyBytes))
+	}
+	if queryResponse.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("expected a metric query returning a matrix, got %s results", queryResponse.Data.ResultType)
+	}
+	var matrix model.Matrix
+	if err := json.Unmarshal(queryResponse.Data.Result, &matrix); err != nil {
+		return nil, fmt.Errorf("unmarshalling matrix result: %w", err)
+	}
+	return matrix, nil
+}
+
 // QueryLokiLogsParams defines the parameters for querying Loki logs
 type QueryLokiLogsParams struct {
 	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
@@ -567,4 +608,5 @@ func AddLokiTools(mcp *server.MCPServer) {
 	ListLokiLabelValues.Register(mcp)
 	QueryLokiStats.Register(mcp)
 	QueryLokiLogs.Register(mcp)
+	registerDetectAnomalies(mcp)
 }
@@ -122,8 +122,10 @@ type QueryPrometheusParams struct {
 	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
 	StartTime     string `json:"startTime" jsonschema:"required,description=The start time. Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
 	EndTime       string `json:"endTime,omitempty" jsonschema:"description=The end time. Required if queryType is 'range'\\, ignored if queryType is 'instant' Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
-	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=The time series step size in seconds. Required if queryType is 'range'\\, ignored if queryType is 'instant'"`
+	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=The time series step size in seconds. If omitted for a range query\\, a step is chosen automatically to return at most maxPoints points per series. Ignored if queryType is 'instant'"`
 	QueryType     string `json:"queryType,omitempty" jsonschema:"description=The type of query to use. Either 'range' or 'instant'"`
+	MaxPoints     int    `json:"maxPoints,omitempty" jsonschema:"description=The maximum number of points per series used to choose the step when stepSeconds is omitted (default 300)"`
+	Output        string `json:"output,omitempty" jsonschema:"enum=summary,enum=full,description=How range query results are returned: 'summary' (default) returns per-series statistics (min\\, max\\, avg\\, last\\, p95)\\, trend\\, gaps and changepoints; 'full' returns every sample of every series. Instant query results are always returned in full"`
 }
 
 func parseTime(timeStr string) (time.Time, error) {
@@ -135,9 +137,17 @@ func parseTime(timeStr string) (time.Time, error) {
 }
 
 func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Value, error) {
+	result, _, err := runPrometheusQuery(ctx, args)
+	return result, err
+}
+
+// runPrometheusQuery runs an instant or range query and returns its result
+// along with the range used for range queries, including the step chosen
+// when none was given.
+func runPrometheusQuery(ctx context.Context, args QueryPrometheusParams) (model.Value, promv1.Range, error) {
 	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
 	if err != nil {
-		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+		return nil, promv1.Range{}, fmt.Errorf("getting Prometheus client: %w", err)
 	}
 
 	queryType := args.QueryType
@@ -148,46 +158,82 @@ func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Val
 	var startTime time.Time
 	startTime, err = parseTime(args.StartTime)
 	if err != nil {
-		return nil, fmt.Errorf("parsing start time: %w", err)
+		return nil, promv1.Range{}, fmt.Errorf("parsing start time: %w", err)
 	}
 
 	switch queryType {
 	case "range":
-		if args.StepSeconds == 0 {
-			return nil, fmt.Errorf("stepSeconds must be provided when queryType is 'range'")
-		}
-
 		var endTime time.Time
 		endTime, err = parseTime(args.EndTime)
 		if err != nil {
-			return nil, fmt.Errorf("parsing end time: %w", err)
+			return nil, promv1.Range{}, fmt.Errorf("parsing end time: %w", err)
 		}
 
 		step := time.Duration(args.StepSeconds) * time.Second
-		result, _, err := promClient.QueryRange(ctx, args.Expr, promv1.Range{
+		if step == 0 {
+			step = autoStep(startTime, endTime, args.MaxPoints)
+		}
+		r := promv1.Range{
 			Start: startTime,
 			End:   endTime,
 			Step:  step,
-		})
+		}
+		result, _, err := promClient.QueryRange(ctx, args.Expr, r)
 		if err != nil {
-			return nil, fmt.Errorf("querying Prometheus range: %w", err)
+			return nil, promv1.Range{}, fmt.Errorf("querying Prometheus range: %w", err)
 		}
-		return result, nil
+		return result, r, nil
 	case "instant":
 		result, _, err := promClient.Query(ctx, args.Expr, startTime)
 		if err != nil {
-			return nil, fmt.Errorf("querying Prometheus instant: %w", err)
+			return nil, promv1.Range{}, fmt.Errorf("querying Prometheus instant: %w", err)
 		}
+		return result, promv1.Range{}, nil
+	}
+
+	return nil, promv1.Range{}, fmt.Errorf("invalid query type: %s", queryType)
+}
+
+// PrometheusQueryResult mirrors the data of a Prometheus query response.
+// Range query results are either summarized per series in Summary or
+// returned in full in Result.
+type PrometheusQueryResult struct {
+	ResultType  string          `json:"resultType"`
+	StepSeconds float64         `json:"stepSeconds,omitempty"`
+	Result      model.Value     `json:"result,omitempty"`
+	Summary     []SeriesSummary `json:"summary,omitempty"`
+}
+
+func queryPrometheusWithOutput(ctx context.Context, args QueryPrometheusParams) (*PrometheusQueryResult, error) {
+	output := args.Output
+	if output == "" {
+		output = "summary"
+	}
+	if output != "summary" && output != "full" {
+		return nil, fmt.Errorf("invalid output %q: must be 'summary' or 'full'", output)
+	}
+	value, r, err := runPrometheusQuery(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	result := &PrometheusQueryResult{ResultType: value.Type().String(), StepSeconds: r.Step.Seconds()}
+	matrix, ok := value.(model.Matrix)
+	if !ok || output == "full" {
+		result.Result = value
 		return result, nil
 	}
 
-	return nil, fmt.Errorf("invalid query type: %s", queryType)
+	result.Summary = make([]SeriesSummary, 0, len(matrix))
+	for _, stream := range matrix {
+		result.Summary = append(result.Summary, summarizeSeries(stream, r.Start, r.End, r.Step))
+	}
+	return result, nil
 }
 
 var QueryPrometheus = mcpgrafana.MustTool(
 	"query_prometheus",
-	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc.",
-	queryPrometheus,
+	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc. Range queries choose a step automatically when stepSeconds is omitted and by default return a compact summary of each series (min, max, avg, last, p95, trend, gaps and changepoints); set output to 'full' to get every sample.",
+	queryPrometheusWithOutput,
 	mcp.WithTitleAnnotation("Query Prometheus metrics"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -433,4 +479,15 @@ func AddPrometheusTools(mcp *server.MCPServer) {
 	ListPrometheusMetricNames.Register(mcp)
 	ListPrometheusLabelNames.Register(mcp)
 	ListPrometheusLabelValues.Register(mcp)
+	ValidatePromQL.Register(mcp)
+	GetPrometheusCardinality.Register(mcp)
+	GetPrometheusMetricCardinality.Register(mcp)
+	ListPrometheusTargets.Register(mcp)
+	ListPrometheusTargetsMetadata.Register(mcp)
+	ListPrometheusAlerts.Register(mcp)
+	QueryPrometheusExemplars.Register(mcp)
+	ComparePrometheusPeriods.Register(mcp)
+	registerDetectAnomalies(mcp)
+	QueryPrometheusHistogram.Register(mcp)
+	QueryPrometheusBatch.Register(mcp)
 }
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type ListPrometheusAlertsParams struct {
+	DatasourceUID  string     `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	State          string     `json:"state,omitempty" jsonschema:"enum=firing,enum=pending,description=Optionally\\, only return alerts in this state"`
+	LabelSelectors []Selector `json:"labelSelectors,omitempty" jsonschema:"description=Optionally\\, label selectors that alert and rule labels must match. An alert or rule is returned if it matches any selector"`
+	RuleType       string     `json:"ruleType,omitempty" jsonschema:"enum=alert,enum=record,description=Optionally\\, only return rules of this type: 'alert' for alerting rules or 'record' for recording rules"`
+	UnhealthyOnly  bool       `json:"unhealthyOnly,omitempty" jsonschema:"description=Only return rules whose last evaluation failed or hasn't happened yet"`
+}
+
+// PrometheusAlert is an alert that is currently pending or firing.
+type PrometheusAlert struct {
+	Name        string            `json:"name"`
+	State       string            `json:"state"`
+	ActiveAt    time.Time         `json:"activeAt"`
+	Value       string            `json:"value"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PrometheusRuleStatus is the evaluation status of an alerting or recording
+// rule.
+type PrometheusRuleStatus struct {
+	Name                  string            `json:"name"`
+	Type                  string            `json:"type"`
+	Group                 string            `json:"group"`
+	File                  string            `json:"file,omitempty"`
+	Query                 string            `json:"query"`
+	Labels                map[string]string `json:"labels,omitempty"`
+	State                 string            `json:"state,omitempty"`
+	Health                string            `json:"health"`
+	LastError             string            `json:"lastError,omitempty"`
+	LastEvaluation        time.Time         `json:"lastEvaluation"`
+	EvaluationTimeSeconds float64           `json:"evaluationTimeSeconds"`
+}
+
+type PrometheusAlertsResult struct {
+	Alerts []PrometheusAlert      `json:"alerts"`
+	Rules  []PrometheusRuleStatus `json:"rules"`
+}
+
+func labelSetMap(ls model.LabelSet) map[string]string {
+	m := make(map[string]string, len(ls))
+	for k, v := range ls {
+		m[string(k)] = string(v)
+	}
+	return m
+}
+
+func listPrometheusAlerts(ctx context.Context, args ListPrometheusAlertsParams) (*PrometheusAlertsResult, error) {
+	switch args.State {
+	case "", string(promv1.AlertStateFiring), string(promv1.AlertStatePending):
+	default:
+		return nil, fmt.Errorf("invalid state %q: must be firing or pending", args.State)
+	}
+	switch args.RuleType {
+	case "", "alert", "record":
+	default:
+		return nil, fmt.Errorf("invalid ruleType %q: must be alert or record", args.RuleType)
+	}
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	alerts, err := promClient.Alerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus alerts: %w", err)
+	}
+	rules, err := promClient.Rules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus rules: %w", err)
+	}
+
+	result := &PrometheusAlertsResult{Alerts: []PrometheusAlert{}, Rules: []PrometheusRuleStatus{}}
+	for _, a := range alerts.Alerts {
+		if args.State != "" && string(a.State) != args.State {
+			continue
+		}
+		lbls := labelSetMap(a.Labels)
+		ok, err := labelsMatchSelectors(lbls, args.LabelSelectors)
+		if err != nil {
+			return nil, fmt.Errorf("filtering alerts: %w", err)
+		}
+		if !ok {
+			continue
+		}
+		result.Alerts = append(result.Alerts, PrometheusAlert{
+			Name:        lbls[model.AlertNameLabel],
+			State:       string(a.State),
+			ActiveAt:    a.ActiveAt,
+			Value:       a.Value,
+			Labels:      lbls,
+			Annotations: labelSetMap(a.Annotations),
+		})
+	}
+	// Firing alerts first, then the longest active.
+	sort.SliceStable(result.Alerts, func(i, j int) bool {
+		a, b := result.Alerts[i], result.Alerts[j]
+		if a.State != b.State {
+			return a.State == string(promv1.AlertStateFiring)
+		}
+		return a.ActiveAt.Before(b.ActiveAt)
+	})
+
+	for _, group := range rules.Groups {
+		for _, rule := range group.Rules {
+			var status PrometheusRuleStatus
+			switch r := rule.(type) {
+			case promv1.AlertingRule:
+				status = PrometheusRuleStatus{
+					Name: r.Name, Type: "alert", Query: r.Query, Labels: labelSetMap(r.Labels), State: r.State,
+					Health: string(r.Health), LastError: r.LastError, LastEvaluation: r.LastEvaluation, EvaluationTimeSeconds: r.EvaluationTime,
+				}
+			case promv1.RecordingRule:
+				status = PrometheusRuleStatus{
+					Name: r.Name, Type: "record", Query: r.Query, Labels: labelSetMap(r.Labels),
+					Health: string(r.Health), LastError: r.LastError, LastEvaluation: r.LastEvaluation, EvaluationTimeSeconds: r.EvaluationTime,
+				}
+			default:
+				continue
+			}
+			if args.RuleType != "" && status.Type != args.RuleType {
+				continue
+			}
+			if args.UnhealthyOnly && status.Health == string(promv1.RuleHealthGood) {
+				continue
+			}
+			ok, err := labelsMatchSelectors(status.Labels, args.LabelSelectors)
+			if err != nil {
+				return nil, fmt.Errorf("filtering rules: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			status.Group, status.File = group.Name, group.File
+			result.Rules = append(result.Rules, status)
+		}
+	}
+	return result, nil
+}
+
+var ListPrometheusAlerts = mcpgrafana.MustTool(
+	"list_prometheus_alerts",
+	"List the alerts and rules of a Prometheus-compatible datasource (Prometheus, Mimir, Cortex, Thanos) using its own /api/v1/alerts and /api/v1/rules endpoints, whether or not the rules are managed through Grafana. Returns pending and firing alerts with their labels, annotations, value and active-since time, firing first, and alerting and recording rules with their evaluation health, last error and evaluation time. Use unhealthyOnly to find rules that fail to evaluate.",
+	listPrometheusAlerts,
+	mcp.WithTitleAnnotation("List Prometheus alerts and rules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
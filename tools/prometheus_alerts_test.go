@@ -0,0 +1,93 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPrometheusAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/alerts"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"alerts": [
+				{"labels": {"alertname": "HighLatency", "severity": "warning"}, "annotations": {"summary": "Latency is high"},
+					"state": "pending", "activeAt": "2024-01-01T00:10:00Z", "value": "0.7"},
+				{"labels": {"alertname": "APIDown", "severity": "critical"}, "annotations": {},
+					"state": "firing", "activeAt": "2024-01-01T00:20:00Z", "value": "0"},
+				{"labels": {"alertname": "DBDown", "severity": "critical"}, "annotations": {},
+					"state": "firing", "activeAt": "2024-01-01T00:05:00Z", "value": "0"}
+			]}}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/rules"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"groups": [
+				{"name": "api", "file": "api.yml", "interval": 60, "rules": [
+					{"type": "alerting", "name": "APIDown", "query": "up{job=\"api\"} == 0", "duration": 300,
+						"labels": {"severity": "critical"}, "annotations": {}, "alerts": [], "health": "ok",
+						"evaluationTime": 0.001, "lastEvaluation": "2024-01-01T00:30:00Z", "state": "firing"},
+					{"type": "recording", "name": "job:requests:rate5m", "query": "sum by (job) (rate(requests_total[5m]))",
+						"health": "err", "lastError": "many-to-many matching not allowed",
+						"evaluationTime": 0.02, "lastEvaluation": "2024-01-01T00:30:00Z"},
+					{"type": "recording", "name": "job:errors:rate5m", "query": "sum by (job) (rate(errors_total[5m]))",
+						"health": "ok", "evaluationTime": 0.01, "lastEvaluation": "2024-01-01T00:30:00Z"}
+				]}
+			]}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	t.Run("all", func(t *testing.T) {
+		result, err := listPrometheusAlerts(ctx, ListPrometheusAlertsParams{DatasourceUID: "prom"})
+		require.NoError(t, err)
+		require.Len(t, result.Alerts, 3)
+		// Firing alerts first, longest active first.
+		assert.Equal(t, []string{"DBDown", "APIDown", "HighLatency"}, []string{result.Alerts[0].Name, result.Alerts[1].Name, result.Alerts[2].Name})
+		assert.Equal(t, "Latency is high", result.Alerts[2].Annotations["summary"])
+		assert.Equal(t, "0.7", result.Alerts[2].Value)
+		require.Len(t, result.Rules, 3)
+		assert.Equal(t, "alert", result.Rules[0].Type)
+		assert.Equal(t, "firing", result.Rules[0].State)
+		assert.Equal(t, "api", result.Rules[0].Group)
+		assert.Equal(t, "api.yml", result.Rules[0].File)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		result, err := listPrometheusAlerts(ctx, ListPrometheusAlertsParams{
+			DatasourceUID:  "prom",
+			State:          "firing",
+			LabelSelectors: []Selector{{Filters: []LabelMatcher{{Name: "severity", Value: "critical", Type: "="}}}},
+			RuleType:       "alert",
+		})
+		require.NoError(t, err)
+		assert.Len(t, result.Alerts, 2)
+		require.Len(t, result.Rules, 1)
+		assert.Equal(t, "APIDown", result.Rules[0].Name)
+	})
+
+	t.Run("unhealthy recording rules", func(t *testing.T) {
+		result, err := listPrometheusAlerts(ctx, ListPrometheusAlertsParams{DatasourceUID: "prom", RuleType: "record", UnhealthyOnly: true})
+		require.NoError(t, err)
+		require.Len(t, result.Rules, 1)
+		assert.Equal(t, "job:requests:rate5m", result.Rules[0].Name)
+		assert.Equal(t, "err", result.Rules[0].Health)
+		assert.Equal(t, "many-to-many matching not allowed", result.Rules[0].LastError)
+		assert.Equal(t, 0.02, result.Rules[0].EvaluationTimeSeconds)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		_, err := listPrometheusAlerts(ctx, ListPrometheusAlertsParams{DatasourceUID: "prom", State: "inactive"})
+		assert.ErrorContains(t, err, "invalid state")
+	})
+}
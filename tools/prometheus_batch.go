@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultBatchConcurrency = 4
+	maxBatchConcurrency     = 10
+	maxBatchQueries         = 50
+)
+
+// BatchPrometheusQuery is a named expression in a batch.
+type BatchPrometheusQuery struct {
+	Name          string `json:"name" jsonschema:"required,description=A unique name for the query\\, used to identify its result"`
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
+	DatasourceUID string `json:"datasourceUid,omitempty" jsonschema:"description=The UID of the datasource to query. Defaults to the batch's datasourceUid"`
+}
+
+type QueryPrometheusBatchParams struct {
+	DatasourceUID string                 `json:"datasourceUid,omitempty" jsonschema:"description=The UID of the datasource used by queries that don't set their own"`
+	Queries       []BatchPrometheusQuery `json:"queries" jsonschema:"required,description=The queries to run (max 50)"`
+	StartTime     string                 `json:"startTime" jsonschema:"required,description=The start time shared by all queries (the evaluation time of instant queries) in RFC3339 or relative to now (e.g. 'now-1h')"`
+	EndTime       string                 `json:"endTime,omitempty" jsonschema:"description=The end time shared by range queries in RFC3339 or relative to now. Defaults to now"`
+	StepSeconds   int                    `json:"stepSeconds,omitempty" jsonschema:"description=The step of range queries in seconds. Chosen automatically from maxPoints when omitted"`
+	QueryType     string                 `json:"queryType,omitempty" jsonschema:"enum=range,enum=instant,description=The type of all queries: 'range' (default) or 'instant'"`
+	MaxPoints     int                    `json:"maxPoints,omitempty" jsonschema:"description=The maximum number of points per series used to choose the step when stepSeconds is omitted (default 300)"`
+	Output        string                 `json:"output,omitempty" jsonschema:"enum=summary,enum=full,description=How range query results are returned: 'summary' (default) or 'full'"`
+	Concurrency   int                    `json:"concurrency,omitempty" jsonschema:"default=4,description=Number of queries executed in parallel (max 10)"`
+}
+
+// BatchQueryResult is the result of one query in a batch. Exactly one of
+// Result and Error is set.
+type BatchQueryResult struct {
+	Name          string                 `json:"name"`
+	DatasourceUID string                 `json:"datasourceUid"`
+	Expr          string                 `json:"expr"`
+	Result        *PrometheusQueryResult `json:"result,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+}
+
+type PrometheusBatchResult struct {
+	Results   []BatchQueryResult `json:"results"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+}
+
+func queryPrometheusBatch(ctx context.Context, args QueryPrometheusBatchParams) (*PrometheusBatchResult, error) {
+	if len(args.Queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	if len(args.Queries) > maxBatchQueries {
+		return nil, fmt.Errorf("too many queries: %d (max %d)", len(args.Queries), maxBatchQueries)
+	}
+	names := make(map[string]bool, len(args.Queries))
+	for i, q := range args.Queries {
+		switch {
+		case q.Name == "":
+			return nil, fmt.Errorf("query %d: name is required", i)
+		case names[q.Name]:
+			return nil, fmt.Errorf("query %d: duplicate name %q", i, q.Name)
+		case q.Expr == "":
+			return nil, fmt.Errorf("query %q: expr is required", q.Name)
+		case q.DatasourceUID == "" && args.DatasourceUID == "":
+			return nil, fmt.Errorf("query %q: datasourceUid is required when the batch has no default datasourceUid", q.Name)
+		}
+		names[q.Name] = true
+	}
+	concurrency := args.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	concurrency = min(concurrency, maxBatchConcurrency)
+
+	// Resolve relative times once so that every query covers exactly the
+	// same range.
+	start, err := parseTime(args.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	endTime := args.EndTime
+	if endTime == "" {
+		endTime = "now"
+	}
+	end, err := parseTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	queryType := args.QueryType
+	if queryType == "" {
+		queryType = "range"
+	}
+	if queryType != "range" && queryType != "instant" {
+		return nil, fmt.Errorf("invalid query type %q: must be range or instant", args.QueryType)
+	}
+	if args.Output != "" && args.Output != "summary" && args.Output != "full" {
+		return nil, fmt.Errorf("invalid output %q: must be 'summary' or 'full'", args.Output)
+	}
+	if queryType == "range" && args.StepSeconds == 0 {
+		// Choose the step once too, rather than per query.
+		args.StepSeconds = max(1, int(autoStep(start, end, args.MaxPoints).Seconds()))
+	}
+
+	result := &PrometheusBatchResult{Results: make([]BatchQueryResult, len(args.Queries))}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, q := range args.Queries {
+		datasourceUID := q.DatasourceUID
+		if datasourceUID == "" {
+			datasourceUID = args.DatasourceUID
+		}
+		result.Results[i] = BatchQueryResult{Name: q.Name, DatasourceUID: datasourceUID, Expr: q.Expr}
+		wg.Add(1)
+		go func(r *BatchQueryResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res, err := queryPrometheusWithOutput(ctx, QueryPrometheusParams{
+				DatasourceUID: r.DatasourceUID,
+				Expr:          r.Expr,
+				StartTime:     start.Format(time.RFC3339),
+				EndTime:       end.Format(time.RFC3339),
+				StepSeconds:   args.StepSeconds,
+				QueryType:     queryType,
+				MaxPoints:     args.MaxPoints,
+				Output:        args.Output,
+			})
+			if err != nil {
+				r.Error = err.Error()
+				return
+			}
+			r.Result = res
+		}(&result.Results[i])
+	}
+	wg.Wait()
+
+	for _, r := range result.Results {
+		if r.Error != "" {
+			result.Failed++
+		} else {
+			result.Succeeded++
+		}
+	}
+	return result, nil
+}
+
+var QueryPrometheusBatch = mcpgrafana.MustTool(
+	"query_prometheus_batch",
+	"Run several named PromQL queries at once over a shared time range, in parallel and against one or more Prometheus datasources. Each query can override the default datasourceUid. Returns each query's result or error in the order given; a failing query doesn't fail the batch. Range query results are summarized per series unless output is 'full'.",
+	queryPrometheusBatch,
+	mcp.WithTitleAnnotation("Query Prometheus in batch"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,101 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPrometheusBatch(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		ranges = map[string]bool{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom", r.URL.Path == "/api/datasources/uid/mimir":
+			_, _ = w.Write([]byte(`{"uid": "` + strings.TrimPrefix(r.URL.Path, "/api/datasources/uid/") + `", "type": "prometheus"}`))
+		case r.URL.Path == "/api/datasources/uid/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Data source not found"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query_range"):
+			require.NoError(t, r.ParseForm())
+			mu.Lock()
+			ranges[r.Form.Get("start")+"-"+r.Form.Get("end")+"-"+r.Form.Get("step")] = true
+			mu.Unlock()
+			if r.Form.Get("query") == "bad(" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "parse error"}`))
+				return
+			}
+			job := "prom"
+			if strings.Contains(r.URL.Path, "/mimir/") {
+				job = "mimir"
+			}
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": [
+				{"metric": {"source": "` + job + `"}, "values": [[1704067200, "1"], [1704067260, "2"]]}
+			]}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	result, err := queryPrometheusBatch(ctx, QueryPrometheusBatchParams{
+		DatasourceUID: "prom",
+		Queries: []BatchPrometheusQuery{
+			{Name: "requests", Expr: "sum(rate(requests_total[5m]))"},
+			{Name: "broken", Expr: "bad("},
+			{Name: "mimir", Expr: "up", DatasourceUID: "mimir"},
+			{Name: "missing", Expr: "up", DatasourceUID: "missing"},
+			{Name: "errors", Expr: "sum(rate(errors_total[5m]))"},
+		},
+		StartTime:   "now-1h",
+		Concurrency: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Succeeded)
+	assert.Equal(t, 2, result.Failed)
+	require.Len(t, result.Results, 5)
+
+	// Results keep the order of the queries.
+	names := make([]string, len(result.Results))
+	for i, r := range result.Results {
+		names[i] = r.Name
+	}
+	assert.Equal(t, []string{"requests", "broken", "mimir", "missing", "errors"}, names)
+
+	require.NotNil(t, result.Results[0].Result, result.Results[0].Error)
+	assert.Equal(t, "matrix", result.Results[0].Result.ResultType)
+	assert.Equal(t, 15.0, result.Results[0].Result.StepSeconds)
+	assert.Contains(t, result.Results[1].Error, "parse error")
+	assert.Equal(t, "mimir", result.Results[2].DatasourceUID)
+	assert.Equal(t, "mimir", result.Results[2].Result.Summary[0].Labels["source"])
+	assert.Contains(t, result.Results[3].Error, "not found")
+	assert.Empty(t, result.Results[4].Error)
+
+	// Every query used the same range and step.
+	assert.Len(t, ranges, 1)
+
+	t.Run("validation", func(t *testing.T) {
+		for name, args := range map[string]QueryPrometheusBatchParams{
+			"no queries":     {DatasourceUID: "prom", StartTime: "now-1h"},
+			"duplicate name": {DatasourceUID: "prom", StartTime: "now-1h", Queries: []BatchPrometheusQuery{{Name: "a", Expr: "up"}, {Name: "a", Expr: "up"}}},
+			"no datasource":  {StartTime: "now-1h", Queries: []BatchPrometheusQuery{{Name: "a", Expr: "up"}}},
+			"bad output":     {DatasourceUID: "prom", StartTime: "now-1h", Output: "csv", Queries: []BatchPrometheusQuery{{Name: "a", Expr: "up"}}},
+		} {
+			_, err := queryPrometheusBatch(ctx, args)
+			assert.Error(t, err, name)
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultCardinalityLimit = 10
+	maxCardinalityLimit     = 100
+	// maxCardinalityLabelScan bounds the label values requests made per call
+	// when falling back to queries.
+	maxCardinalityLabelScan = 200
+)
+
+// CardinalityEntry is a metric, label or label pair and the number of
+// series or values it accounts for.
+type CardinalityEntry struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+	// Share is the fraction of all series, when the total is known.
+	Share float64 `json:"share,omitempty"`
+}
+
+type GetPrometheusCardinalityParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus or Mimir datasource"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=The number of entries to return in each list (default 10\\, max 100)"`
+	Method        string `json:"method,omitempty" jsonschema:"enum=auto,enum=tsdb,enum=query,description=How to compute cardinality. 'tsdb' uses the /api/v1/status/tsdb endpoint\\, 'query' uses count by queries and the label values API\\, and 'auto' (the default) tries tsdb first and falls back to queries"`
+}
+
+type PrometheusCardinalityResult struct {
+	// Source is "tsdb" or "query", depending on how the statistics were
+	// computed.
+	Source      string             `json:"source"`
+	TotalSeries uint64             `json:"totalSeries,omitempty"`
+	TopMetrics  []CardinalityEntry `json:"topMetrics"`
+	TopLabels   []CardinalityEntry `json:"topLabels"`
+	// TopLabelPairs is only available from the tsdb endpoint.
+	TopLabelPairs []CardinalityEntry `json:"topLabelPairs,omitempty"`
+	Notices       []string           `json:"notices,omitempty"`
+}
+
+func cardinalityLimit(limit int) int {
+	if limit <= 0 {
+		return defaultCardinalityLimit
+	}
+	return min(limit, maxCardinalityLimit)
+}
+
+// cardinalityEntries converts TSDB statistics into entries, adding each
+// one's share of the total when known.
+func cardinalityEntries(stats []promv1.Stat, total uint64, limit int) []CardinalityEntry {
+	entries := make([]CardinalityEntry, 0, len(stats))
+	for _, s := range stats {
+		entries = append(entries, CardinalityEntry{Name: s.Name, Count: s.Value})
+	}
+	return topCardinalityEntries(entries, total, limit)
+}
+
+// topCardinalityEntries sorts entries by count and keeps the first limit.
+func topCardinalityEntries(entries []CardinalityEntry, total uint64, limit int) []CardinalityEntry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if total > 0 {
+		for i := range entries {
+			entries[i].Share = float64(entries[i].Count) / float64(total)
+		}
+	}
+	return entries
+}
+
+// queryVector runs an instant query that must return a vector.
+func queryVector(ctx context.Context, client promv1.API, query string, ts time.Time) (model.Vector, error) {
+	value, _, err := client.Query(ctx, query, ts)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", query, err)
+	}
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("querying %s: unexpected result type %s", query, value.Type())
+	}
+	return vector, nil
+}
+
+// queryScalarCount runs a query returning a single count, such as
+// count(...). An empty result counts as zero.
+func queryScalarCount(ctx context.Context, client promv1.API, query string, ts time.Time) (uint64, error) {
+	vector, err := queryVector(ctx, client, query, ts)
+	if err != nil || len(vector) == 0 {
+		return 0, err
+	}
+	return uint64(vector[0].Value), nil
+}
+
+func tsdbCardinality(ctx context.Context, client promv1.API, limit int) (*PrometheusCardinalityResult, error) {
+	stats, err := client.TSDB(ctx, promv1.WithLimit(uint64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("getting TSDB status: %w", err)
+	}
+	total := uint64(stats.HeadStats.NumSeries)
+	return &PrometheusCardinalityResult{
+		Source:        "tsdb",
+		TotalSeries:   total,
+		TopMetrics:    cardinalityEntries(stats.SeriesCountByMetricName, total, limit),
+		TopLabels:     cardinalityEntries(stats.LabelValueCountByLabelName, 0, limit),
+		TopLabelPairs: cardinalityEntries(stats.SeriesCountByLabelValuePair, total, limit),
+	}, nil
+}
+
+func queryCardinality(ctx context.Context, client promv1.API, limit int) (*PrometheusCardinalityResult, error) {
+	now := time.Now()
+	result := &PrometheusCardinalityResult{Source: "query"}
+
+	total, err := queryScalarCount(ctx, client, `count({__name__=~".+"})`, now)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalSeries = total
+
+	vector, err := queryVector(ctx, client, fmt.Sprintf(`topk(%d, count by (__name__) ({__name__=~".+"}))`, limit), now)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range vector {
+		result.TopMetrics = append(result.TopMetrics, CardinalityEntry{Name: string(s.Metric[model.MetricNameLabel]), Count: uint64(s.Value)})
+	}
+	result.TopMetrics = topCardinalityEntries(result.TopMetrics, total, limit)
+
+	// Label value counts come from the label values API over the last hour,
+	// which is much cheaper than a count query per label.
+	names, _, err := client.LabelNames(ctx, nil, now.Add(-time.Hour), now)
+	if err != nil {
+		return nil, fmt.Errorf("listing label names: %w", err)
+	}
+	if len(names) > maxCardinalityLabelScan {
+		result.Notices = append(result.Notices, fmt.Sprintf("only the first %d of %d label names were scanned", maxCardinalityLabelScan, len(names)))
+		names = names[:maxCardinalityLabelScan]
+	}
+	for _, name := range names {
+		if name == model.MetricNameLabel {
+			continue
+		}
+		values, _, err := client.LabelValues(ctx, name, nil, now.Add(-time.Hour), now)
+		if err != nil {
+			return nil, fmt.Errorf("listing values of label %s: %w", name, err)
+		}
+		result.TopLabels = append(result.TopLabels, CardinalityEntry{Name: name, Count: uint64(len(values))})
+	}
+	result.TopLabels = topCardinalityEntries(result.TopLabels, 0, limit)
+	return result, nil
+}
+
+func getPrometheusCardinality(ctx context.Context, args GetPrometheusCardinalityParams) (*PrometheusCardinalityResult, error) {
+	client, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	limit := cardinalityLimit(args.Limit)
+
+	switch args.Method {
+	case "tsdb":
+		return tsdbCardinality(ctx, client, limit)
+	case "query":
+		return queryCardinality(ctx, client, limit)
+	case "", "auto":
+		result, tsdbErr := tsdbCardinality(ctx, client, limit)
+		if tsdbErr == nil {
+			return result, nil
+		}
+		result, err := queryCardinality(ctx, client, limit)
+		if err != nil {
+			return nil, fmt.Errorf("%w (after %v)", err, tsdbErr)
+		}
+		result.Notices = append([]string{fmt.Sprintf("TSDB status endpoint unavailable, fell back to queries: %v", tsdbErr)}, result.Notices...)
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid method %q: must be auto, tsdb or query", args.Method)
+	}
+}
+
+var GetPrometheusCardinality = mcpgrafana.MustTool(
+	"get_prometheus_cardinality",
+	"Find high-cardinality metrics and labels in a Prometheus or Mimir datasource. Returns the metrics with the most series, the labels with the most values and, when the /api/v1/status/tsdb endpoint is available, the label pairs with the most series. Falls back to count by queries when the endpoint isn't available. Use get_prometheus_metric_cardinality to break down a single metric.",
+	getPrometheusCardinality,
+	mcp.WithTitleAnnotation("Get Prometheus cardinality"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type GetPrometheusMetricCardinalityParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus or Mimir datasource"`
+	Metric        string `json:"metric" jsonschema:"required,description=The metric name to break down"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=The number of top values to return per label (default 10\\, max 100)"`
+}
+
+// LabelCardinality is the number of distinct values of a label within a
+// metric, with the values that account for the most series.
+type LabelCardinality struct {
+	Name      string             `json:"name"`
+	Values    uint64             `json:"values"`
+	TopValues []CardinalityEntry `json:"topValues"`
+}
+
+type PrometheusMetricCardinalityResult struct {
+	Metric      string             `json:"metric"`
+	TotalSeries uint64             `json:"totalSeries"`
+	Labels      []LabelCardinality `json:"labels"`
+}
+
+func getPrometheusMetricCardinality(ctx context.Context, args GetPrometheusMetricCardinalityParams) (*PrometheusMetricCardinalityResult, error) {
+	if args.Metric == "" {
+		return nil, fmt.Errorf("metric is required")
+	}
+	client, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	limit := cardinalityLimit(args.Limit)
+	now := time.Now()
+	selector := fmt.Sprintf(`{__name__=%s}`, strconv.Quote(args.Metric))
+
+	result := &PrometheusMetricCardinalityResult{Metric: args.Metric, Labels: []LabelCardinality{}}
+	result.TotalSeries, err = queryScalarCount(ctx, client, fmt.Sprintf("count(%s)", selector), now)
+	if err != nil {
+		return nil, err
+	}
+	if result.TotalSeries == 0 {
+		return result, nil
+	}
+
+	names, _, err := client.LabelNames(ctx, []string{selector}, now.Add(-time.Hour), now)
+	if err != nil {
+		return nil, fmt.Errorf("listing label names: %w", err)
+	}
+	for _, name := range names {
+		if name == model.MetricNameLabel {
+			continue
+		}
+		label := LabelCardinality{Name: name}
+		label.Values, err = queryScalarCount(ctx, client, fmt.Sprintf("count(count by (%s) (%s))", name, selector), now)
+		if err != nil {
+			return nil, err
+		}
+		vector, err := queryVector(ctx, client, fmt.Sprintf("topk(%d, count by (%s) (%s))", limit, name, selector), now)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range vector {
+			label.TopValues = append(label.TopValues, CardinalityEntry{Name: string(s.Metric[model.LabelName(name)]), Count: uint64(s.Value)})
+		}
+		label.TopValues = topCardinalityEntries(label.TopValues, result.TotalSeries, limit)
+		result.Labels = append(result.Labels, label)
+	}
+	sort.SliceStable(result.Labels, func(i, j int) bool {
+		return result.Labels[i].Values > result.Labels[j].Values
+	})
+	return result, nil
+}
+
+var GetPrometheusMetricCardinality = mcpgrafana.MustTool(
+	"get_prometheus_metric_cardinality",
+	"Break down the cardinality of a single Prometheus metric. Returns the metric's total series count and, for each of its labels, the number of distinct values and the values with the most series, sorted by the labels with the most values.",
+	getPrometheusMetricCardinality,
+	mcp.WithTitleAnnotation("Get Prometheus metric cardinality"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,159 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cardinalityServer serves a Prometheus datasource whose TSDB status
+// endpoint is optionally unavailable.
+func cardinalityServer(t *testing.T, tsdb bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, r.ParseForm())
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/status/tsdb"):
+			if !tsdb {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"status": "error", "errorType": "not_found", "error": "not found"}`))
+				return
+			}
+			assert.Equal(t, "2", r.Form.Get("limit"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": {
+				"headStats": {"numSeries": 1000},
+				"seriesCountByMetricName": [{"name": "up", "value": 100}, {"name": "http_requests_total", "value": 600}],
+				"labelValueCountByLabelName": [{"name": "pod", "value": 300}, {"name": "job", "value": 5}],
+				"memoryInBytesByLabelName": [],
+				"seriesCountByLabelValuePair": [{"name": "job=api", "value": 500}]
+			}}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query"):
+			switch q := r.Form.Get("query"); q {
+			case `count({__name__=~".+"})`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1704067200, "200"]}]}}`))
+			case `topk(2, count by (__name__) ({__name__=~".+"}))`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [
+					{"metric": {"__name__": "up"}, "value": [1704067200, "50"]},
+					{"metric": {"__name__": "http_requests_total"}, "value": [1704067200, "150"]}
+				]}}`))
+			case `count({__name__="http_requests_total"})`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1704067200, "150"]}]}}`))
+			case `count(count by (code) ({__name__="http_requests_total"}))`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1704067200, "3"]}]}}`))
+			case `count(count by (pod) ({__name__="http_requests_total"}))`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1704067200, "50"]}]}}`))
+			case `topk(2, count by (code) ({__name__="http_requests_total"}))`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [
+					{"metric": {"code": "200"}, "value": [1704067200, "100"]},
+					{"metric": {"code": "500"}, "value": [1704067200, "30"]}
+				]}}`))
+			case `topk(2, count by (pod) ({__name__="http_requests_total"}))`:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [
+					{"metric": {"pod": "a"}, "value": [1704067200, "3"]},
+					{"metric": {"pod": "b"}, "value": [1704067200, "3"]}
+				]}}`))
+			default:
+				t.Errorf("unexpected query: %s", q)
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case strings.HasSuffix(r.URL.Path, "/api/v1/labels"):
+			if len(r.Form["match[]"]) > 0 {
+				assert.Equal(t, []string{`{__name__="http_requests_total"}`}, r.Form["match[]"])
+				_, _ = w.Write([]byte(`{"status": "success", "data": ["__name__", "code", "pod"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["__name__", "job", "pod", "code"]}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/label/job/values"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["api", "db"]}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/label/pod/values"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["a", "b", "c", "d"]}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/label/code/values"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["200", "500", "404"]}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGetPrometheusCardinality(t *testing.T) {
+	t.Run("tsdb", func(t *testing.T) {
+		server := cardinalityServer(t, true)
+		defer server.Close()
+
+		result, err := getPrometheusCardinality(mockCtxWithGrafanaURL(server), GetPrometheusCardinalityParams{DatasourceUID: "prom", Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "tsdb", result.Source)
+		assert.Equal(t, uint64(1000), result.TotalSeries)
+		assert.Equal(t, []CardinalityEntry{
+			{Name: "http_requests_total", Count: 600, Share: 0.6},
+			{Name: "up", Count: 100, Share: 0.1},
+		}, result.TopMetrics)
+		assert.Equal(t, []CardinalityEntry{{Name: "pod", Count: 300}, {Name: "job", Count: 5}}, result.TopLabels)
+		assert.Equal(t, []CardinalityEntry{{Name: "job=api", Count: 500, Share: 0.5}}, result.TopLabelPairs)
+		assert.Empty(t, result.Notices)
+	})
+
+	t.Run("query fallback", func(t *testing.T) {
+		server := cardinalityServer(t, false)
+		defer server.Close()
+
+		result, err := getPrometheusCardinality(mockCtxWithGrafanaURL(server), GetPrometheusCardinalityParams{DatasourceUID: "prom", Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "query", result.Source)
+		assert.Equal(t, uint64(200), result.TotalSeries)
+		assert.Equal(t, []CardinalityEntry{
+			{Name: "http_requests_total", Count: 150, Share: 0.75},
+			{Name: "up", Count: 50, Share: 0.25},
+		}, result.TopMetrics)
+		assert.Equal(t, []CardinalityEntry{{Name: "pod", Count: 4}, {Name: "code", Count: 3}}, result.TopLabels)
+		require.Len(t, result.Notices, 1)
+		assert.Contains(t, result.Notices[0], "fell back to queries")
+	})
+
+	t.Run("tsdb only", func(t *testing.T) {
+		server := cardinalityServer(t, false)
+		defer server.Close()
+
+		_, err := getPrometheusCardinality(mockCtxWithGrafanaURL(server), GetPrometheusCardinalityParams{DatasourceUID: "prom", Method: "tsdb"})
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid method", func(t *testing.T) {
+		server := cardinalityServer(t, true)
+		defer server.Close()
+
+		_, err := getPrometheusCardinality(mockCtxWithGrafanaURL(server), GetPrometheusCardinalityParams{DatasourceUID: "prom", Method: "series"})
+		assert.ErrorContains(t, err, "invalid method")
+	})
+}
+
+func TestGetPrometheusMetricCardinality(t *testing.T) {
+	server := cardinalityServer(t, true)
+	defer server.Close()
+
+	result, err := getPrometheusMetricCardinality(mockCtxWithGrafanaURL(server), GetPrometheusMetricCardinalityParams{
+		DatasourceUID: "prom",
+		Metric:        "http_requests_total",
+		Limit:         2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), result.TotalSeries)
+	require.Len(t, result.Labels, 2)
+	assert.Equal(t, "pod", result.Labels[0].Name)
+	assert.Equal(t, uint64(50), result.Labels[0].Values)
+	assert.Equal(t, "code", result.Labels[1].Name)
+	assert.Equal(t, uint64(3), result.Labels[1].Values)
+	assert.Equal(t, []CardinalityEntry{
+		{Name: "200", Count: 100, Share: 100.0 / 150},
+		{Name: "500", Count: 30, Share: 0.2},
+	}, result.Labels[1].TopValues)
+}
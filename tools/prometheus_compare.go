@@ -0,0 +1,306 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultComparisonLimit = 10
+	// maxComparedSeries bounds the series returned with their full
+	// comparison; the ranked deviations always cover every series.
+	maxComparedSeries = 100
+)
+
+var defaultComparisonOffsets = []string{"1d", "1w"}
+
+type ComparePrometheusPeriodsParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Expr          string   `json:"expr" jsonschema:"required,description=The PromQL expression to compare"`
+	StartTime     string   `json:"startTime,omitempty" jsonschema:"description=The start of the current window in RFC3339 or relative to now (e.g. 'now-1h'). Defaults to now-1h"`
+	EndTime       string   `json:"endTime,omitempty" jsonschema:"description=The end of the current window in RFC3339 or relative to now. Defaults to now"`
+	Offsets       []string `json:"offsets,omitempty" jsonschema:"description=How far back to shift the window for each comparison as Prometheus durations (default [\"1d\"\\, \"1w\"])"`
+	StepSeconds   int      `json:"stepSeconds,omitempty" jsonschema:"description=The step in seconds. Chosen automatically from maxPoints when omitted"`
+	MaxPoints     int      `json:"maxPoints,omitempty" jsonschema:"description=The maximum number of points per series used to choose the step (default 300)"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=The number of biggest deviations to return (default 10)"`
+}
+
+// PeriodStats summarizes a series over one window.
+type PeriodStats struct {
+	Avg    float64 `json:"avg"`
+	Last   float64 `json:"last"`
+	Points int     `json:"points"`
+}
+
+// OffsetComparison compares a series with the same series in a shifted
+// window.
+type OffsetComparison struct {
+	Offset string `json:"offset"`
+	// Missing is set when the series doesn't exist in the shifted window.
+	Missing  bool         `json:"missing,omitempty"`
+	Previous *PeriodStats `json:"previous,omitempty"`
+	// Delta and PercentChange compare the averages of both windows.
+	// PercentChange is omitted when the previous average is zero.
+	Delta         float64  `json:"delta"`
+	PercentChange *float64 `json:"percentChange,omitempty"`
+	// MaxDeviation is the largest difference between aligned points of the
+	// two windows, and MaxDeviationTime when it happened in the current one.
+	MaxDeviation     float64    `json:"maxDeviation"`
+	MaxDeviationTime *time.Time `json:"maxDeviationTime,omitempty"`
+}
+
+type SeriesComparison struct {
+	Labels      map[string]string  `json:"labels"`
+	Current     PeriodStats        `json:"current"`
+	Comparisons []OffsetComparison `json:"comparisons"`
+}
+
+// PeriodDeviation is an entry in the ranked list of biggest changes.
+type PeriodDeviation struct {
+	Labels        map[string]string `json:"labels"`
+	Offset        string            `json:"offset"`
+	Current       float64           `json:"current"`
+	Previous      float64           `json:"previous"`
+	Delta         float64           `json:"delta"`
+	PercentChange *float64          `json:"percentChange,omitempty"`
+}
+
+type PeriodComparisonResult struct {
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	StepSeconds float64            `json:"stepSeconds"`
+	Deviations  []PeriodDeviation  `json:"deviations"`
+	Series      []SeriesComparison `json:"series"`
+	// Disappeared lists, per offset, the series that existed in the shifted
+	// window but not in the current one.
+	Disappeared map[string][]map[string]string `json:"disappeared,omitempty"`
+	Truncated   bool                           `json:"truncated,omitempty"`
+}
+
+// alignedValues indexes a series' finite values by step from start.
+func alignedValues(stream *model.SampleStream, start time.Time, step time.Duration) map[int]float64 {
+	values := make(map[int]float64, len(stream.Values))
+	for _, p := range stream.Values {
+		v := float64(p.Value)
+		if !isFinite(v) {
+			continue
+		}
+		values[stepIndex(p.Timestamp.Time(), start, step)] = v
+	}
+	return values
+}
+
+// stepIndex returns the step t falls on counting from start. Prometheus
+// timestamps have millisecond precision while start may not, so the
+// offset is rounded rather than truncated.
+func stepIndex(t, start time.Time, step time.Duration) int {
+	return int(math.Round(float64(t.Sub(start)) / float64(step)))
+}
+
+func periodStats(stream *model.SampleStream) PeriodStats {
+	var stats PeriodStats
+	var sum float64
+	for _, p := range stream.Values {
+		v := float64(p.Value)
+		if !isFinite(v) {
+			continue
+		}
+		sum += v
+		stats.Last = v
+		stats.Points++
+	}
+	if stats.Points > 0 {
+		stats.Avg = sum / float64(stats.Points)
+	}
+	return stats
+}
+
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	pct := (current - previous) / math.Abs(previous) * 100
+	return &pct
+}
+
+// deviationScore orders deviations by relative change, with changes from
+// zero ranked above everything else.
+func deviationScore(d PeriodDeviation) float64 {
+	if d.PercentChange == nil {
+		if d.Delta == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return math.Abs(*d.PercentChange)
+}
+
+func comparePrometheusPeriods(ctx context.Context, args ComparePrometheusPeriodsParams) (*PeriodComparisonResult, error) {
+	if args.Expr == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	startTime, endTime := args.StartTime, args.EndTime
+	if startTime == "" {
+		startTime = "now-1h"
+	}
+	if endTime == "" {
+		endTime = "now"
+	}
+	start, err := parseTime(startTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := parseTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	offsetNames := args.Offsets
+	if len(offsetNames) == 0 {
+		offsetNames = defaultComparisonOffsets
+	}
+	offsets := make([]time.Duration, len(offsetNames))
+	for i, o := range offsetNames {
+		d, err := model.ParseDuration(o)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid offset %q: must be a positive duration such as 1d or 1w", o)
+		}
+		offsets[i] = time.Duration(d)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultComparisonLimit
+	}
+	step := time.Duration(args.StepSeconds) * time.Second
+	if step <= 0 {
+		step = autoStep(start, end, args.MaxPoints)
+	}
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	queryMatrix := func(offset time.Duration) (model.Matrix, error) {
+		r := promv1.Range{Start: start.Add(-offset), End: end.Add(-offset), Step: step}
+		value, _, err := promClient.QueryRange(ctx, args.Expr, r)
+		if err != nil {
+			return nil, fmt.Errorf("querying Prometheus range: %w", err)
+		}
+		matrix, ok := value.(model.Matrix)
+		if !ok {
+			return nil, fmt.Errorf("expected a matrix result, got %s", value.Type())
+		}
+		return matrix, nil
+	}
+
+	current, err := queryMatrix(0)
+	if err != nil {
+		return nil, err
+	}
+	result := &PeriodComparisonResult{Start: start, End: end, StepSeconds: step.Seconds(), Deviations: []PeriodDeviation{}}
+	for _, stream := range current {
+		result.Series = append(result.Series, SeriesComparison{
+			Labels:  labelSetMap(model.LabelSet(stream.Metric)),
+			Current: periodStats(stream),
+		})
+	}
+
+	for i, offset := range offsets {
+		previous, err := queryMatrix(offset)
+		if err != nil {
+			return nil, fmt.Errorf("offset %s: %w", offsetNames[i], err)
+		}
+		byLabels := make(map[model.Fingerprint]*model.SampleStream, len(previous))
+		for _, stream := range previous {
+			byLabels[stream.Metric.Fingerprint()] = stream
+		}
+		for j, stream := range current {
+			comparison := OffsetComparison{Offset: offsetNames[i]}
+			prev, ok := byLabels[stream.Metric.Fingerprint()]
+			delete(byLabels, stream.Metric.Fingerprint())
+			series := &result.Series[j]
+			if !ok {
+				comparison.Missing = true
+				series.Comparisons = append(series.Comparisons, comparison)
+				continue
+			}
+			prevStats := periodStats(prev)
+			comparison.Previous = &prevStats
+			comparison.Delta = series.Current.Avg - prevStats.Avg
+			comparison.PercentChange = percentChange(series.Current.Avg, prevStats.Avg)
+
+			prevValues := alignedValues(prev, start.Add(-offset), step)
+			for k, v := range alignedValues(stream, start, step) {
+				pv, ok := prevValues[k]
+				if !ok || math.Abs(v-pv) <= math.Abs(comparison.MaxDeviation) {
+					continue
+				}
+				t := start.Add(time.Duration(k) * step)
+				comparison.MaxDeviation, comparison.MaxDeviationTime = v-pv, &t
+			}
+			series.Comparisons = append(series.Comparisons, comparison)
+			result.Deviations = append(result.Deviations, PeriodDeviation{
+				Labels:        series.Labels,
+				Offset:        comparison.Offset,
+				Current:       series.Current.Avg,
+				Previous:      prevStats.Avg,
+				Delta:         comparison.Delta,
+				PercentChange: comparison.PercentChange,
+			})
+		}
+		for _, stream := range previous {
+			if _, ok := byLabels[stream.Metric.Fingerprint()]; ok {
+				if result.Disappeared == nil {
+					result.Disappeared = map[string][]map[string]string{}
+				}
+				result.Disappeared[offsetNames[i]] = append(result.Disappeared[offsetNames[i]], labelSetMap(model.LabelSet(stream.Metric)))
+			}
+		}
+	}
+
+	sort.SliceStable(result.Deviations, func(i, j int) bool {
+		return deviationScore(result.Deviations[i]) > deviationScore(result.Deviations[j])
+	})
+	// Order series by their biggest deviation too, so truncation keeps the
+	// interesting ones.
+	seriesScore := func(s SeriesComparison) float64 {
+		score := 0.0
+		for _, c := range s.Comparisons {
+			if !c.Missing {
+				score = max(score, deviationScore(PeriodDeviation{Delta: c.Delta, PercentChange: c.PercentChange}))
+			}
+		}
+		return score
+	}
+	sort.SliceStable(result.Series, func(i, j int) bool {
+		return seriesScore(result.Series[i]) > seriesScore(result.Series[j])
+	})
+	if len(result.Deviations) > limit {
+		result.Deviations = result.Deviations[:limit]
+	}
+	if len(result.Series) > maxComparedSeries {
+		result.Series = result.Series[:maxComparedSeries]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+var ComparePrometheusPeriods = mcpgrafana.MustTool(
+	"compare_prometheus_periods",
+	"Answer \"is this normal?\" by running a PromQL range query over a window and over the same window shifted back by one or more offsets (1d and 1w by default). Series are matched by their labels and compared by average, with the delta, percentage change and largest point-by-point deviation for each offset, plus a ranked list of the biggest deviations and the series that disappeared.",
+	comparePrometheusPeriods,
+	mcp.WithTitleAnnotation("Compare Prometheus periods"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
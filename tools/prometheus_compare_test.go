@@ -0,0 +1,104 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparePrometheusPeriods(t *testing.T) {
+	// The current window starts at 1704153600 (2024-01-02T00:00:00Z); the
+	// 1d window starts at 1704067200.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query_range"):
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "60", r.Form.Get("step"))
+			switch r.Form.Get("start") {
+			case "1704153600":
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": [
+					{"metric": {"job": "api"}, "values": [[1704153600, "10"], [1704153660, "30"], [1704153720, "20"]]},
+					{"metric": {"job": "db"}, "values": [[1704153600, "5"], [1704153660, "5"], [1704153720, "5"]]},
+					{"metric": {"job": "new"}, "values": [[1704153600, "1"]]}
+				]}}`))
+			case "1704067200":
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": [
+					{"metric": {"job": "api"}, "values": [[1704067200, "10"], [1704067260, "10"], [1704067320, "10"]]},
+					{"metric": {"job": "db"}, "values": [[1704067200, "5"], [1704067260, "4"], [1704067320, "6"]]},
+					{"metric": {"job": "old"}, "values": [[1704067200, "1"]]}
+				]}}`))
+			default:
+				t.Errorf("unexpected start: %s", r.Form.Get("start"))
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	result, err := comparePrometheusPeriods(ctx, ComparePrometheusPeriodsParams{
+		DatasourceUID: "prom",
+		Expr:          "sum by (job) (rate(requests_total[5m]))",
+		StartTime:     "2024-01-02T00:00:00Z",
+		EndTime:       "2024-01-02T00:02:00Z",
+		Offsets:       []string{"1d"},
+		StepSeconds:   60,
+	})
+	require.NoError(t, err)
+
+	// api doubled, db is unchanged and new has no baseline.
+	require.Len(t, result.Deviations, 2)
+	assert.Equal(t, "api", result.Deviations[0].Labels["job"])
+	assert.Equal(t, "1d", result.Deviations[0].Offset)
+	assert.Equal(t, 10.0, result.Deviations[0].Delta)
+	require.NotNil(t, result.Deviations[0].PercentChange)
+	assert.InDelta(t, 100, *result.Deviations[0].PercentChange, 1e-9)
+	assert.Equal(t, "db", result.Deviations[1].Labels["job"])
+	assert.Equal(t, 0.0, result.Deviations[1].Delta)
+
+	require.Len(t, result.Series, 3)
+	api := result.Series[0]
+	assert.Equal(t, "api", api.Labels["job"])
+	assert.Equal(t, PeriodStats{Avg: 20, Last: 20, Points: 3}, api.Current)
+	require.Len(t, api.Comparisons, 1)
+	assert.Equal(t, 20.0, api.Comparisons[0].MaxDeviation)
+	require.NotNil(t, api.Comparisons[0].MaxDeviationTime)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 1, 0, 0, time.UTC), api.Comparisons[0].MaxDeviationTime.UTC())
+
+	var newSeries *SeriesComparison
+	for i := range result.Series {
+		if result.Series[i].Labels["job"] == "new" {
+			newSeries = &result.Series[i]
+		}
+	}
+	require.NotNil(t, newSeries)
+	assert.True(t, newSeries.Comparisons[0].Missing)
+	assert.Equal(t, []map[string]string{{"job": "old"}}, result.Disappeared["1d"])
+
+	t.Run("invalid offset", func(t *testing.T) {
+		_, err := comparePrometheusPeriods(ctx, ComparePrometheusPeriodsParams{DatasourceUID: "prom", Expr: "up", Offsets: []string{"yesterday"}})
+		assert.ErrorContains(t, err, "invalid offset")
+	})
+}
+
+func TestStepIndex(t *testing.T) {
+	// Prometheus returns millisecond timestamps; a start with sub-millisecond
+	// precision must not push samples onto the previous step.
+	start := time.Date(2024, 1, 2, 0, 0, 0, 400_000, time.UTC)
+	sample := time.Date(2024, 1, 2, 0, 3, 0, 0, time.UTC)
+	assert.Equal(t, 3, stepIndex(sample, start, time.Minute))
+	assert.Equal(t, 0, stepIndex(start, start, time.Minute))
+}
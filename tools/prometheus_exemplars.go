@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultExemplarsLimit = 100
+	maxExemplarsLimit     = 1000
+	// exemplarTraceWindow is the time range either side of an exemplar used
+	// for its trace link.
+	exemplarTraceWindow = 30 * time.Minute
+)
+
+// defaultTraceIDLabels are the exemplar labels checked for a trace ID when
+// the datasource has no exemplar trace destinations configured.
+var defaultTraceIDLabels = []string{"trace_id", "traceID", "traceId", "TraceID"}
+
+type QueryPrometheusExemplarsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL selector or expression to get exemplars for\\, e.g. http_request_duration_seconds_bucket{job=\"api\"}"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=The start time in RFC3339 or relative to now (e.g. 'now-1h'). Defaults to now-1h"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=The end time in RFC3339 or relative to now. Defaults to now"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=The maximum number of exemplars to return\\, highest values first (default 100\\, max 1000)"`
+}
+
+// PrometheusExemplar is a single exemplar with the series it belongs to and
+// a link to its trace when one can be built.
+type PrometheusExemplar struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Labels       map[string]string `json:"labels"`
+	Value        float64           `json:"value"`
+	Timestamp    time.Time         `json:"timestamp"`
+	TraceID      string            `json:"traceId,omitempty"`
+	TraceURL     string            `json:"traceUrl,omitempty"`
+}
+
+type PrometheusExemplarsResult struct {
+	Exemplars []PrometheusExemplar `json:"exemplars"`
+	Total     int                  `json:"total"`
+	Truncated bool                 `json:"truncated,omitempty"`
+	Notices   []string             `json:"notices,omitempty"`
+}
+
+// exemplarTraceDestination mirrors an entry of the Prometheus datasource's
+// jsonData.exemplarTraceIdDestinations setting. Name is the exemplar label
+// holding the trace ID; either DatasourceUID (an internal link) or URL (an
+// external link with ${__value.raw}) says where the trace lives.
+type exemplarTraceDestination struct {
+	Name          string `json:"name"`
+	DatasourceUID string `json:"datasourceUid"`
+	URL           string `json:"url"`
+}
+
+// exemplarTraceDestinations reads the exemplar trace destinations configured
+// on a Prometheus datasource.
+func exemplarTraceDestinations(ds *models.DataSource) []exemplarTraceDestination {
+	if ds == nil || ds.JSONData == nil {
+		return nil
+	}
+	data, err := json.Marshal(ds.JSONData)
+	if err != nil {
+		return nil
+	}
+	var jsonData struct {
+		Destinations []exemplarTraceDestination `json:"exemplarTraceIdDestinations"`
+	}
+	if err := json.Unmarshal(data, &jsonData); err != nil {
+		return nil
+	}
+	return jsonData.Destinations
+}
+
+// traceExploreURL returns a Grafana Explore link that opens a trace in a
+// tracing datasource such as Tempo.
+func traceExploreURL(baseURL, datasourceUID, traceID string, ts time.Time) string {
+	panes := map[string]interface{}{
+		"trace": map[string]interface{}{
+			"datasource": datasourceUID,
+			"queries": []map[string]interface{}{{
+				"refId":      "A",
+				"datasource": map[string]string{"uid": datasourceUID},
+				"queryType":  "traceql",
+				"query":      traceID,
+			}},
+			"range": map[string]string{
+				"from": fmt.Sprintf("%d", ts.Add(-exemplarTraceWindow).UnixMilli()),
+				"to":   fmt.Sprintf("%d", ts.Add(exemplarTraceWindow).UnixMilli()),
+			},
+		},
+	}
+	state, _ := json.Marshal(panes)
+	params := url.Values{}
+	params.Set("schemaVersion", "1")
+	params.Set("panes", string(state))
+	return fmt.Sprintf("%s/explore?%s", strings.TrimRight(baseURL, "/"), params.Encode())
+}
+
+// exemplarTraceLink finds an exemplar's trace ID and builds a link to the
+// trace from the first destination whose label is set.
+func exemplarTraceLink(baseURL string, destinations []exemplarTraceDestination, exemplarLabels map[string]string, ts time.Time) (string, string) {
+	for _, d := range destinations {
+		traceID := exemplarLabels[d.Name]
+		if traceID == "" {
+			continue
+		}
+		switch {
+		case d.DatasourceUID != "" && baseURL != "":
+			return traceID, traceExploreURL(baseURL, d.DatasourceUID, traceID, ts)
+		case d.URL != "":
+			return traceID, strings.ReplaceAll(d.URL, "${__value.raw}", traceID)
+		default:
+			return traceID, ""
+		}
+	}
+	if len(destinations) == 0 {
+		for _, name := range defaultTraceIDLabels {
+			if traceID := exemplarLabels[name]; traceID != "" {
+				return traceID, ""
+			}
+		}
+	}
+	return "", ""
+}
+
+func queryPrometheusExemplars(ctx context.Context, args QueryPrometheusExemplarsParams) (*PrometheusExemplarsResult, error) {
+	if args.Expr == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	startTime, endTime := args.StartTime, args.EndTime
+	if startTime == "" {
+		startTime = "now-1h"
+	}
+	if endTime == "" {
+		endTime = "now"
+	}
+	start, err := parseTime(startTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := parseTime(endTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultExemplarsLimit
+	}
+	limit = min(limit, maxExemplarsLimit)
+
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return nil, err
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	results, err := promClient.QueryExemplars(ctx, args.Expr, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus exemplars: %w", err)
+	}
+
+	result := &PrometheusExemplarsResult{Exemplars: []PrometheusExemplar{}}
+	for _, r := range results {
+		seriesLabels := labelSetMap(r.SeriesLabels)
+		for _, e := range r.Exemplars {
+			result.Exemplars = append(result.Exemplars, PrometheusExemplar{
+				SeriesLabels: seriesLabels,
+				Labels:       labelSetMap(e.Labels),
+				Value:        float64(e.Value),
+				Timestamp:    e.Timestamp.Time().UTC(),
+			})
+		}
+	}
+	result.Total = len(result.Exemplars)
+	sort.SliceStable(result.Exemplars, func(i, j int) bool {
+		return result.Exemplars[i].Value > result.Exemplars[j].Value
+	})
+	if len(result.Exemplars) > limit {
+		result.Exemplars = result.Exemplars[:limit]
+		result.Truncated = true
+	}
+
+	destinations := exemplarTraceDestinations(ds)
+	if len(destinations) == 0 {
+		result.Notices = append(result.Notices, "the datasource has no exemplar trace destinations configured, so no trace links were generated")
+	}
+	baseURL := mcpgrafana.GrafanaConfigFromContext(ctx).URL
+	for i := range result.Exemplars {
+		e := &result.Exemplars[i]
+		e.TraceID, e.TraceURL = exemplarTraceLink(baseURL, destinations, e.Labels, e.Timestamp)
+	}
+	return result, nil
+}
+
+var QueryPrometheusExemplars = mcpgrafana.MustTool(
+	"query_prometheus_exemplars",
+	"Get the exemplars recorded for a PromQL selector over a time range, such as the requests behind a latency histogram, highest values first. Each exemplar includes its trace ID and, when the datasource has exemplar trace destinations configured, a link that opens the trace in Grafana Explore (e.g. in Tempo). Use it to jump from a latency spike to concrete traces.",
+	queryPrometheusExemplars,
+	mcp.WithTitleAnnotation("Query Prometheus exemplars"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
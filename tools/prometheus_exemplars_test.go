@@ -0,0 +1,107 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exemplarsServer(t *testing.T, jsonData string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus", "jsonData": ` + jsonData + `}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query_exemplars"):
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, `latency_bucket{job="api"}`, r.Form.Get("query"))
+			assert.NotEmpty(t, r.Form.Get("start"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": [
+				{"seriesLabels": {"__name__": "latency_bucket", "job": "api", "le": "1"}, "exemplars": [
+					{"labels": {"trace_id": "abc123"}, "value": "0.4", "timestamp": 1704067200},
+					{"labels": {"trace_id": "def456"}, "value": "2.5", "timestamp": 1704067260.5}
+				]},
+				{"seriesLabels": {"__name__": "latency_bucket", "job": "api", "le": "+Inf"}, "exemplars": [
+					{"labels": {"span_id": "1"}, "value": "9", "timestamp": 1704067320}
+				]}
+			]}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestQueryPrometheusExemplars(t *testing.T) {
+	t.Run("internal trace links", func(t *testing.T) {
+		server := exemplarsServer(t, `{"exemplarTraceIdDestinations": [{"name": "trace_id", "datasourceUid": "tempo"}]}`)
+		defer server.Close()
+
+		result, err := queryPrometheusExemplars(mockCtxWithGrafanaURL(server), QueryPrometheusExemplarsParams{
+			DatasourceUID: "prom",
+			Expr:          `latency_bucket{job="api"}`,
+			Limit:         2,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Total)
+		assert.True(t, result.Truncated)
+		require.Len(t, result.Exemplars, 2)
+
+		// Highest values first.
+		assert.Equal(t, 9.0, result.Exemplars[0].Value)
+		assert.Empty(t, result.Exemplars[0].TraceID)
+		assert.Empty(t, result.Exemplars[0].TraceURL)
+
+		e := result.Exemplars[1]
+		assert.Equal(t, 2.5, e.Value)
+		assert.Equal(t, "def456", e.TraceID)
+		assert.Equal(t, "1", e.SeriesLabels["le"])
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 1, 0, 500_000_000, time.UTC), e.Timestamp)
+
+		link, err := url.Parse(e.TraceURL)
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/explore", link.Scheme+"://"+link.Host+link.Path)
+		var panes map[string]struct {
+			Datasource string `json:"datasource"`
+			Queries    []struct {
+				Query      string            `json:"query"`
+				Datasource map[string]string `json:"datasource"`
+			} `json:"queries"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(link.Query().Get("panes")), &panes))
+		assert.Equal(t, "tempo", panes["trace"].Datasource)
+		assert.Equal(t, "def456", panes["trace"].Queries[0].Query)
+		assert.Empty(t, result.Notices)
+	})
+
+	t.Run("external trace links", func(t *testing.T) {
+		server := exemplarsServer(t, `{"exemplarTraceIdDestinations": [{"name": "trace_id", "url": "https://traces.example.com/trace/${__value.raw}"}]}`)
+		defer server.Close()
+
+		result, err := queryPrometheusExemplars(mockCtxWithGrafanaURL(server), QueryPrometheusExemplarsParams{DatasourceUID: "prom", Expr: `latency_bucket{job="api"}`})
+		require.NoError(t, err)
+		require.Len(t, result.Exemplars, 3)
+		assert.Equal(t, "https://traces.example.com/trace/abc123", result.Exemplars[2].TraceURL)
+	})
+
+	t.Run("no destinations", func(t *testing.T) {
+		server := exemplarsServer(t, `{}`)
+		defer server.Close()
+
+		result, err := queryPrometheusExemplars(mockCtxWithGrafanaURL(server), QueryPrometheusExemplarsParams{DatasourceUID: "prom", Expr: `latency_bucket{job="api"}`})
+		require.NoError(t, err)
+		require.Len(t, result.Exemplars, 3)
+		assert.Equal(t, "def456", result.Exemplars[1].TraceID)
+		assert.Empty(t, result.Exemplars[1].TraceURL)
+		require.Len(t, result.Notices, 1)
+	})
+}
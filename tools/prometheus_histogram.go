@@ -0,0 +1,332 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+var (
+	defaultHistogramQuantiles    = []float64{0.5, 0.9, 0.99}
+	defaultHistogramCalculations = []string{"quantile"}
+	// histogramSuffixRegex matches the suffixes of classic histogram series.
+	histogramSuffixRegex = regexp.MustCompile(`_(bucket|count|sum)$`)
+)
+
+type QueryPrometheusHistogramParams struct {
+	DatasourceUID  string         `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Metric         string         `json:"metric" jsonschema:"required,description=The histogram metric name\\, with or without the _bucket suffix\\, e.g. http_request_duration_seconds"`
+	Matchers       []LabelMatcher `json:"matchers,omitempty" jsonschema:"description=Optionally\\, label matchers to filter the histogram's series by"`
+	GroupBy        []string       `json:"groupBy,omitempty" jsonschema:"description=Optionally\\, labels to group the results by\\, e.g. [\"job\"\\, \"route\"]. Don't include le: it is added when needed"`
+	Calculations   []string       `json:"calculations,omitempty" jsonschema:"description=What to calculate: 'quantile'\\, 'average' and/or 'apdex'. Defaults to quantile"`
+	Quantiles      []float64      `json:"quantiles,omitempty" jsonschema:"description=The quantiles to calculate between 0 and 1 (default [0.5\\, 0.9\\, 0.99])"`
+	ApdexThreshold float64        `json:"apdexThreshold,omitempty" jsonschema:"description=The satisfied threshold for apdex in the metric's unit (e.g. 0.3 for 300ms when the metric is in seconds). Requests up to 4 times the threshold count as tolerating. For classic histograms both values must be bucket boundaries"`
+	HistogramType  string         `json:"histogramType,omitempty" jsonschema:"enum=auto,enum=classic,enum=native,description=The kind of histogram. 'auto' (the default) detects it from the series that exist"`
+	RateInterval   string         `json:"rateInterval,omitempty" jsonschema:"description=The range used in rate() (default 5m)"`
+	QueryType      string         `json:"queryType,omitempty" jsonschema:"enum=instant,enum=range,description=Whether to run instant queries at endTime (the default) or range queries"`
+	StartTime      string         `json:"startTime,omitempty" jsonschema:"description=The start time for range queries in RFC3339 or relative to now (default now-1h)"`
+	EndTime        string         `json:"endTime,omitempty" jsonschema:"description=The end time in RFC3339 or relative to now (default now)"`
+	StepSeconds    int            `json:"stepSeconds,omitempty" jsonschema:"description=The step for range queries in seconds. Chosen automatically when omitted"`
+}
+
+// HistogramQuery is a generated query and its result or error.
+type HistogramQuery struct {
+	Name   string                 `json:"name"`
+	Expr   string                 `json:"expr"`
+	Result *PrometheusQueryResult `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+type HistogramQueryResult struct {
+	Metric        string           `json:"metric"`
+	HistogramType string           `json:"histogramType"`
+	Queries       []HistogramQuery `json:"queries"`
+	Notices       []string         `json:"notices,omitempty"`
+}
+
+// histogramSelector renders label matchers as the inside of a PromQL
+// selector, validating them on the way.
+func histogramSelector(matchers []LabelMatcher, extra ...*labels.Matcher) (string, error) {
+	parts := make([]string, 0, len(matchers)+len(extra))
+	for _, m := range matchers {
+		matchType, ok := matchTypeMap[m.Type]
+		if !ok {
+			return "", fmt.Errorf("invalid matcher type: %s", m.Type)
+		}
+		matcher, err := labels.NewMatcher(matchType, m.Name, m.Value)
+		if err != nil {
+			return "", fmt.Errorf("creating matcher: %w", err)
+		}
+		parts = append(parts, matcher.String())
+	}
+	for _, m := range extra {
+		parts = append(parts, m.String())
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// detectHistogramType reports whether the metric exists as a classic
+// histogram (base_bucket series), a native histogram (base series) or both.
+func detectHistogramType(ctx context.Context, client promv1.API, base, selector string) (classic, native bool, err error) {
+	match := fmt.Sprintf(`{__name__=~%s}`, strconv.Quote(regexp.QuoteMeta(base)+"(_bucket)?"))
+	if selector != "" {
+		match = fmt.Sprintf(`{__name__=~%s, %s}`, strconv.Quote(regexp.QuoteMeta(base)+"(_bucket)?"), selector)
+	}
+	now := time.Now()
+	names, _, err := client.LabelValues(ctx, labels.MetricName, []string{match}, now.Add(-time.Hour), now)
+	if err != nil {
+		return false, false, fmt.Errorf("listing series of %s: %w", base, err)
+	}
+	for _, name := range names {
+		switch string(name) {
+		case base + "_bucket":
+			classic = true
+		case base:
+			native = true
+		}
+	}
+	return classic, native, nil
+}
+
+// bucketBoundary returns the le label value of the classic histogram
+// bucket equal to the given boundary, since exporters format them
+// differently (e.g. "1" or "1.0").
+func bucketBoundary(ctx context.Context, client promv1.API, base, selector string, boundary float64) (string, error) {
+	match := fmt.Sprintf(`{__name__=%s}`, strconv.Quote(base+"_bucket"))
+	if selector != "" {
+		match = fmt.Sprintf(`{__name__=%s, %s}`, strconv.Quote(base+"_bucket"), selector)
+	}
+	now := time.Now()
+	values, _, err := client.LabelValues(ctx, "le", []string{match}, now.Add(-time.Hour), now)
+	if err != nil {
+		return "", fmt.Errorf("listing bucket boundaries of %s: %w", base, err)
+	}
+	available := make([]string, 0, len(values))
+	for _, v := range values {
+		le, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			continue
+		}
+		if le == boundary {
+			return string(v), nil
+		}
+		available = append(available, string(v))
+	}
+	return "", fmt.Errorf("%s has no bucket with le=%g: the apdex thresholds must be bucket boundaries, available boundaries are %s", base, boundary, strings.Join(available, ", "))
+}
+
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'f', -1, 64)
+}
+
+// sumBy renders a sum aggregation over the given labels.
+func sumBy(grouping []string, expr string) string {
+	if len(grouping) == 0 {
+		return fmt.Sprintf("sum(%s)", expr)
+	}
+	return fmt.Sprintf("sum by (%s) (%s)", strings.Join(grouping, ", "), expr)
+}
+
+// histogramQueries builds the named queries for a classic or native
+// histogram.
+func histogramQueries(ctx context.Context, client promv1.API, args QueryPrometheusHistogramParams, base, histogramType, selector string) ([]HistogramQuery, error) {
+	rateInterval := args.RateInterval
+	if rateInterval == "" {
+		rateInterval = "5m"
+	}
+	quantiles := args.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = defaultHistogramQuantiles
+	}
+	calculations := args.Calculations
+	if len(calculations) == 0 {
+		calculations = defaultHistogramCalculations
+	}
+	rate := func(name, sel string) string {
+		if sel == "" {
+			return fmt.Sprintf("rate(%s[%s])", name, rateInterval)
+		}
+		return fmt.Sprintf("rate(%s{%s}[%s])", name, sel, rateInterval)
+	}
+	bucketGrouping := append([]string{"le"}, args.GroupBy...)
+
+	var queries []HistogramQuery
+	for _, calculation := range calculations {
+		switch calculation {
+		case "quantile":
+			for _, q := range quantiles {
+				if q < 0 || q > 1 {
+					return nil, fmt.Errorf("invalid quantile %g: must be between 0 and 1", q)
+				}
+				expr := fmt.Sprintf("histogram_quantile(%g, %s)", q, sumBy(args.GroupBy, rate(base, selector)))
+				if histogramType == "classic" {
+					expr = fmt.Sprintf("histogram_quantile(%g, %s)", q, sumBy(bucketGrouping, rate(base+"_bucket", selector)))
+				}
+				queries = append(queries, HistogramQuery{Name: quantileName(q), Expr: expr})
+			}
+		case "average":
+			expr := fmt.Sprintf("%s / %s", sumBy(args.GroupBy, rate(base+"_sum", selector)), sumBy(args.GroupBy, rate(base+"_count", selector)))
+			if histogramType == "native" {
+				h := sumBy(args.GroupBy, rate(base, selector))
+				expr = fmt.Sprintf("histogram_sum(%s) / histogram_count(%s)", h, h)
+			}
+			queries = append(queries, HistogramQuery{Name: "average", Expr: expr})
+		case "apdex":
+			t := args.ApdexThreshold
+			if t <= 0 {
+				return nil, fmt.Errorf("apdexThreshold is required for apdex")
+			}
+			var expr string
+			if histogramType == "native" {
+				h := sumBy(args.GroupBy, rate(base, selector))
+				expr = fmt.Sprintf("(histogram_fraction(0, %g, %s) + histogram_fraction(0, %g, %s)) / 2", t, h, 4*t, h)
+			} else {
+				satisfied, err := bucketBoundary(ctx, client, base, selector, t)
+				if err != nil {
+					return nil, err
+				}
+				tolerating, err := bucketBoundary(ctx, client, base, selector, 4*t)
+				if err != nil {
+					return nil, err
+				}
+				withLe := func(le string) string {
+					sel := fmt.Sprintf("le=%s", strconv.Quote(le))
+					if selector != "" {
+						sel = selector + ", " + sel
+					}
+					return sumBy(args.GroupBy, rate(base+"_bucket", sel))
+				}
+				expr = fmt.Sprintf("(%s + %s) / 2 / %s", withLe(satisfied), withLe(tolerating), sumBy(args.GroupBy, rate(base+"_count", selector)))
+			}
+			queries = append(queries, HistogramQuery{Name: "apdex", Expr: expr})
+		default:
+			return nil, fmt.Errorf("invalid calculation %q: must be quantile, average or apdex", calculation)
+		}
+	}
+	for _, q := range queries {
+		if _, err := parser.ParseExpr(q.Expr); err != nil {
+			return nil, fmt.Errorf("building %s query: %w", q.Name, err)
+		}
+	}
+	return queries, nil
+}
+
+func queryPrometheusHistogram(ctx context.Context, args QueryPrometheusHistogramParams) (*HistogramQueryResult, error) {
+	base := histogramSuffixRegex.ReplaceAllString(args.Metric, "")
+	if base == "" {
+		return nil, fmt.Errorf("metric is required")
+	}
+	for _, l := range args.GroupBy {
+		if l == "le" {
+			return nil, fmt.Errorf("groupBy must not include le: it is added for classic histograms")
+		}
+	}
+	queryType := args.QueryType
+	if queryType == "" {
+		queryType = "instant"
+	}
+	if queryType != "instant" && queryType != "range" {
+		return nil, fmt.Errorf("invalid query type %q: must be instant or range", args.QueryType)
+	}
+	selector, err := histogramSelector(args.Matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	result := &HistogramQueryResult{Metric: base, Queries: []HistogramQuery{}}
+
+	metadata, err := client.Metadata(ctx, base, "")
+	if err == nil {
+		if md := metadata[base]; len(md) > 0 {
+			switch md[0].Type {
+			case promv1.MetricTypeHistogram, promv1.MetricTypeGaugeHistogram:
+			case promv1.MetricTypeSummary:
+				return nil, fmt.Errorf("%s is a summary, not a histogram: its quantiles are precomputed, so query %s{quantile=\"0.99\"} instead", base, base)
+			default:
+				return nil, fmt.Errorf("%s is a %s, not a histogram", base, md[0].Type)
+			}
+			if md[0].Type == promv1.MetricTypeGaugeHistogram {
+				result.Notices = append(result.Notices, fmt.Sprintf("%s is a gauge histogram, so rate() may not be meaningful", base))
+			}
+		}
+	}
+
+	histogramType := args.HistogramType
+	switch histogramType {
+	case "classic", "native":
+	case "", "auto":
+		classic, native, err := detectHistogramType(ctx, client, base, selector)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case classic && native:
+			histogramType = "classic"
+			result.Notices = append(result.Notices, fmt.Sprintf("%s exists as both a classic and a native histogram; using the classic buckets. Set histogramType to native to use the native histogram", base))
+		case classic:
+			histogramType = "classic"
+		case native:
+			histogramType = "native"
+		default:
+			return nil, fmt.Errorf("no series found for %s or %s_bucket in the last hour", base, base)
+		}
+	default:
+		return nil, fmt.Errorf("invalid histogram type %q: must be auto, classic or native", args.HistogramType)
+	}
+	result.HistogramType = histogramType
+
+	queries, err := histogramQueries(ctx, client, args, base, histogramType, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, endTime := args.StartTime, args.EndTime
+	if endTime == "" {
+		endTime = "now"
+	}
+	if queryType == "instant" {
+		startTime = endTime
+	} else if startTime == "" {
+		startTime = "now-1h"
+	}
+	for _, q := range queries {
+		res, err := queryPrometheusWithOutput(ctx, QueryPrometheusParams{
+			DatasourceUID: args.DatasourceUID,
+			Expr:          q.Expr,
+			StartTime:     startTime,
+			EndTime:       endTime,
+			StepSeconds:   args.StepSeconds,
+			QueryType:     queryType,
+		})
+		if err != nil {
+			q.Error = err.Error()
+		} else {
+			q.Result = res
+		}
+		result.Queries = append(result.Queries, q)
+	}
+	return result, nil
+}
+
+var QueryPrometheusHistogram = mcpgrafana.MustTool(
+	"query_prometheus_histogram",
+	"Calculate latency quantiles, averages or apdex from a Prometheus histogram without writing the PromQL by hand. Detects whether the metric is a classic histogram (_bucket series) or a native histogram, builds the correct query (rate() over the buckets, sum by le plus the grouping labels for classic histograms), runs it and returns each generated PromQL expression with its result so it can be reused.",
+	queryPrometheusHistogram,
+	mcp.WithTitleAnnotation("Query Prometheus histogram"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,140 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// histogramServer serves a datasource where latency_seconds is a classic
+// histogram, rpc_seconds a native one and rpc_summary a summary.
+func histogramServer(t *testing.T, queries *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, r.ParseForm())
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/metadata"):
+			switch r.Form.Get("metric") {
+			case "latency_seconds", "rpc_seconds":
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"` + r.Form.Get("metric") + `": [{"type": "histogram", "help": "", "unit": ""}]}}`))
+			case "rpc_summary":
+				_, _ = w.Write([]byte(`{"status": "success", "data": {"rpc_summary": [{"type": "summary", "help": "", "unit": ""}]}}`))
+			default:
+				_, _ = w.Write([]byte(`{"status": "success", "data": {}}`))
+			}
+		case strings.HasSuffix(r.URL.Path, "/api/v1/label/__name__/values"):
+			match := strings.Join(r.Form["match[]"], "")
+			switch {
+			case strings.Contains(match, "latency_seconds"):
+				assert.Contains(t, match, `job="api"`)
+				_, _ = w.Write([]byte(`{"status": "success", "data": ["latency_seconds_bucket"]}`))
+			case strings.Contains(match, "rpc_seconds"):
+				_, _ = w.Write([]byte(`{"status": "success", "data": ["rpc_seconds"]}`))
+			default:
+				_, _ = w.Write([]byte(`{"status": "success", "data": []}`))
+			}
+		case strings.HasSuffix(r.URL.Path, "/api/v1/label/le/values"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["0.1", "0.25", "0.5", "1.0", "+Inf"]}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query"):
+			*queries = append(*queries, r.Form.Get("query"))
+			if strings.Contains(r.Form.Get("query"), "_sum") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "boom"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {"job": "api"}, "value": [1704067200, "0.2"]}]}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestQueryPrometheusHistogram(t *testing.T) {
+	var queries []string
+	server := histogramServer(t, &queries)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	t.Run("classic", func(t *testing.T) {
+		queries = nil
+		result, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{
+			DatasourceUID:  "prom",
+			Metric:         "latency_seconds_bucket",
+			Matchers:       []LabelMatcher{{Name: "job", Value: "api", Type: "="}},
+			GroupBy:        []string{"job"},
+			Calculations:   []string{"quantile", "average", "apdex"},
+			Quantiles:      []float64{0.99},
+			ApdexThreshold: 0.25,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "latency_seconds", result.Metric)
+		assert.Equal(t, "classic", result.HistogramType)
+		require.Len(t, result.Queries, 3)
+
+		assert.Equal(t, "p99", result.Queries[0].Name)
+		assert.Equal(t, `histogram_quantile(0.99, sum by (le, job) (rate(latency_seconds_bucket{job="api"}[5m])))`, result.Queries[0].Expr)
+		require.NotNil(t, result.Queries[0].Result)
+		assert.Equal(t, "vector", result.Queries[0].Result.ResultType)
+
+		assert.Equal(t, `sum by (job) (rate(latency_seconds_sum{job="api"}[5m])) / sum by (job) (rate(latency_seconds_count{job="api"}[5m]))`, result.Queries[1].Expr)
+		// A failing query doesn't fail the others.
+		assert.Contains(t, result.Queries[1].Error, "boom")
+		assert.Nil(t, result.Queries[1].Result)
+
+		assert.Equal(t, `(sum by (job) (rate(latency_seconds_bucket{job="api", le="0.25"}[5m])) + sum by (job) (rate(latency_seconds_bucket{job="api", le="1.0"}[5m]))) / 2 / sum by (job) (rate(latency_seconds_count{job="api"}[5m]))`, result.Queries[2].Expr)
+		assert.Empty(t, result.Queries[2].Error)
+		assert.Len(t, queries, 3)
+	})
+
+	t.Run("native", func(t *testing.T) {
+		result, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{
+			DatasourceUID:  "prom",
+			Metric:         "rpc_seconds",
+			Calculations:   []string{"quantile", "average", "apdex"},
+			Quantiles:      []float64{0.5},
+			ApdexThreshold: 0.1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "native", result.HistogramType)
+		require.Len(t, result.Queries, 3)
+		assert.Equal(t, "p50", result.Queries[0].Name)
+		assert.Equal(t, `histogram_quantile(0.5, sum(rate(rpc_seconds[5m])))`, result.Queries[0].Expr)
+		assert.Equal(t, `histogram_sum(sum(rate(rpc_seconds[5m]))) / histogram_count(sum(rate(rpc_seconds[5m])))`, result.Queries[1].Expr)
+		assert.Equal(t, `(histogram_fraction(0, 0.1, sum(rate(rpc_seconds[5m]))) + histogram_fraction(0, 0.4, sum(rate(rpc_seconds[5m])))) / 2`, result.Queries[2].Expr)
+	})
+
+	t.Run("apdex threshold not a bucket", func(t *testing.T) {
+		_, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{
+			DatasourceUID:  "prom",
+			Metric:         "latency_seconds",
+			Matchers:       []LabelMatcher{{Name: "job", Value: "api", Type: "="}},
+			Calculations:   []string{"apdex"},
+			ApdexThreshold: 0.3,
+		})
+		assert.ErrorContains(t, err, "no bucket with le=0.3")
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		_, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{DatasourceUID: "prom", Metric: "rpc_summary"})
+		assert.ErrorContains(t, err, "is a summary")
+	})
+
+	t.Run("missing metric", func(t *testing.T) {
+		_, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{DatasourceUID: "prom", Metric: "nothing"})
+		assert.ErrorContains(t, err, "no series found")
+	})
+
+	t.Run("le in groupBy", func(t *testing.T) {
+		_, err := queryPrometheusHistogram(ctx, QueryPrometheusHistogramParams{DatasourceUID: "prom", Metric: "latency_seconds", GroupBy: []string{"le"}})
+		assert.ErrorContains(t, err, "must not include le")
+	})
+}
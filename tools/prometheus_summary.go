@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	defaultPrometheusMaxPoints = 300
+	// maxPrometheusPoints is Prometheus' own limit on points per series.
+	maxPrometheusPoints = 11000
+
+	maxSummaryGaps         = 10
+	maxSummaryChangepoints = 5
+	// changepointThreshold is the minimum shift in mean, in pooled standard
+	// deviations, reported as a changepoint.
+	changepointThreshold = 3
+	// flatTrendRatio is the fraction of a series' range below which the
+	// fitted change over the window is considered flat.
+	flatTrendRatio = 0.1
+)
+
+// niceSteps are the step sizes autoStep rounds up to, so that steps line up
+// with the intervals people use in dashboards.
+var niceSteps = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// autoStep returns the smallest step that keeps a range query under
+// maxPoints points per series.
+func autoStep(start, end time.Time, maxPoints int) time.Duration {
+	if maxPoints <= 0 {
+		maxPoints = defaultPrometheusMaxPoints
+	}
+	maxPoints = min(maxPoints, maxPrometheusPoints)
+	// A range query returns one point per step, including both ends.
+	minStep := end.Sub(start) / time.Duration(max(maxPoints-1, 1))
+	for _, step := range niceSteps {
+		if step >= minStep {
+			return step
+		}
+	}
+	return minStep.Truncate(time.Second) + time.Second
+}
+
+// SeriesStats are the statistics of the finite values of a series.
+type SeriesStats struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Avg  float64 `json:"avg"`
+	Last float64 `json:"last"`
+	P95  float64 `json:"p95"`
+}
+
+// SeriesGap is a period with no samples, for example while a target was
+// down or before a series existed.
+type SeriesGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SeriesChangepoint is a sustained shift in the level of a series.
+type SeriesChangepoint struct {
+	Time       time.Time `json:"time"`
+	MeanBefore float64   `json:"meanBefore"`
+	MeanAfter  float64   `json:"meanAfter"`
+}
+
+// SeriesSummary summarizes a range query series.
+type SeriesSummary struct {
+	Labels map[string]string `json:"labels"`
+	Points int               `json:"points"`
+	// NonFinite is the number of NaN or infinite values, which are excluded
+	// from the statistics.
+	NonFinite int          `json:"nonFinite,omitempty"`
+	First     time.Time    `json:"first"`
+	Last      time.Time    `json:"last"`
+	Stats     *SeriesStats `json:"stats,omitempty"`
+	// Trend is "increasing", "decreasing" or "flat", based on a linear fit.
+	// TrendChange is the change of the fitted line over the series.
+	Trend        string              `json:"trend,omitempty"`
+	TrendChange  float64             `json:"trendChange"`
+	Gaps         []SeriesGap         `json:"gaps,omitempty"`
+	MissingSteps int                 `json:"missingSteps,omitempty"`
+	Changepoints []SeriesChangepoint `json:"changepoints,omitempty"`
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// percentile returns the p-th percentile (0-1) of sorted values using linear
+// interpolation between the closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// linearTrend fits a line to the values by least squares and returns its
+// slope per second.
+func linearTrend(times []time.Time, values []float64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0
+	}
+	t0 := times[0]
+	var sx, sy, sxx, sxy float64
+	for i, v := range values {
+		x := times[i].Sub(t0).Seconds()
+		sx += x
+		sy += v
+		sxx += x * x
+		sxy += x * v
+	}
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / denom
+}
+
+// findChangepoints finds level shifts by comparing the mean of a window
+// before and after each point, in units of the pooled standard deviation of
+// the two windows. Only the strongest local maxima are kept.
+func findChangepoints(times []time.Time, values []float64) []SeriesChangepoint {
+	window := max(5, len(values)/10)
+	if len(values) < 2*window {
+		return nil
+	}
+	type candidate struct {
+		idx                  int
+		score, before, after float64
+	}
+	var candidates []candidate
+	for i := window; i <= len(values)-window; i++ {
+		before, beforeStd := meanStd(values[i-window : i])
+		after, afterStd := meanStd(values[i : i+window])
+		pooled := math.Sqrt((beforeStd*beforeStd + afterStd*afterStd) / 2)
+		shift := math.Abs(after - before)
+		if shift == 0 {
+			continue
+		}
+		// Perfectly flat windows on both sides are a clear step.
+		score := math.Inf(1)
+		if pooled > 0 {
+			score = shift / pooled
+		}
+		if score >= changepointThreshold {
+			candidates = append(candidates, candidate{idx: i, score: score, before: before, after: after})
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].score != candidates[b].score {
+			return candidates[a].score > candidates[b].score
+		}
+		return math.Abs(candidates[a].after-candidates[a].before) > math.Abs(candidates[b].after-candidates[b].before)
+	})
+	var picked []candidate
+	for _, c := range candidates {
+		tooClose := false
+		for _, p := range picked {
+			if absInt(c.idx-p.idx) < window {
+				tooClose = true
+				break
+			}
+		}
+		if !tooClose {
+			picked = append(picked, c)
+		}
+		if len(picked) == maxSummaryChangepoints {
+			break
+		}
+	}
+	sort.Slice(picked, func(a, b int) bool { return picked[a].idx < picked[b].idx })
+	changepoints := make([]SeriesChangepoint, 0, len(picked))
+	for _, p := range picked {
+		changepoints = append(changepoints, SeriesChangepoint{Time: times[p.idx], MeanBefore: p.before, MeanAfter: p.after})
+	}
+	return changepoints
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// summarizeSeries summarizes a range query series queried between start and
+// end with the given step.
+func summarizeSeries(stream *model.SampleStream, start, end time.Time, step time.Duration) SeriesSummary {
+	summary := SeriesSummary{Labels: make(map[string]string, len(stream.Metric)), Points: len(stream.Values)}
+	for k, v := range stream.Metric {
+		summary.Labels[string(k)] = string(v)
+	}
+	if len(stream.Values) == 0 {
+		return summary
+	}
+	summary.First = stream.Values[0].Timestamp.Time().UTC()
+	summary.Last = stream.Values[len(stream.Values)-1].Timestamp.Time().UTC()
+
+	// Gaps are measured against the query range so that series which start
+	// late or stop early are reported too.
+	addGap := func(from, to time.Time) {
+		missing := int(to.Sub(from)/step) - 1
+		if missing <= 0 {
+			return
+		}
+		summary.MissingSteps += missing
+		if len(summary.Gaps) < maxSummaryGaps {
+			summary.Gaps = append(summary.Gaps, SeriesGap{Start: from.Add(step).UTC(), End: to.Add(-step).UTC()})
+		}
+	}
+	addGap(start.Add(-step), summary.First)
+	times := make([]time.Time, 0, len(stream.Values))
+	values := make([]float64, 0, len(stream.Values))
+	for i, s := range stream.Values {
+		t := s.Timestamp.Time()
+		if i > 0 {
+			addGap(stream.Values[i-1].Timestamp.Time(), t)
+		}
+		v := float64(s.Value)
+		if !isFinite(v) {
+			summary.NonFinite++
+			continue
+		}
+		times = append(times, t.UTC())
+		values = append(values, v)
+	}
+	addGap(summary.Last, end.Add(step))
+	if len(values) == 0 {
+		return summary
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mean, _ := meanStd(values)
+	stats := &SeriesStats{
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		Avg:  mean,
+		Last: values[len(values)-1],
+		P95:  percentile(sorted, 0.95),
+	}
+	summary.Stats = stats
+
+	slope := linearTrend(times, values)
+	summary.TrendChange = slope * times[len(times)-1].Sub(times[0]).Seconds()
+	switch {
+	case stats.Max == stats.Min || math.Abs(summary.TrendChange) < flatTrendRatio*(stats.Max-stats.Min):
+		summary.Trend = "flat"
+	case summary.TrendChange > 0:
+		summary.Trend = "increasing"
+	default:
+		summary.Trend = "decreasing"
+	}
+	summary.Changepoints = findChangepoints(times, values)
+	return summary
+}
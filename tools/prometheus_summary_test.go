@@ -0,0 +1,126 @@
+//go:build unit
+
+package tools
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoStep(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Minute, autoStep(start, start.Add(24*time.Hour), 300))
+	assert.Equal(t, 15*time.Second, autoStep(start, start.Add(time.Hour), 300))
+	assert.Equal(t, time.Second, autoStep(start, start.Add(time.Minute), 0))
+	// Longer than the largest nice step.
+	assert.Equal(t, 87601*time.Second, autoStep(start, start.Add(365*24*time.Hour), 361))
+}
+
+func sampleStream(start time.Time, step time.Duration, values ...float64) *model.SampleStream {
+	stream := &model.SampleStream{Metric: model.Metric{"job": "api"}}
+	for i, v := range values {
+		if math.IsInf(v, -1) {
+			// -Inf marks a missing sample in these tests.
+			continue
+		}
+		stream.Values = append(stream.Values, model.SamplePair{
+			Timestamp: model.TimeFromUnixNano(start.Add(time.Duration(i) * step).UnixNano()),
+			Value:     model.SampleValue(v),
+		})
+	}
+	return stream
+}
+
+func TestSummarizeSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := time.Minute
+	missing := math.Inf(-1)
+	values := []float64{missing, missing}
+	for i := 0; i < 20; i++ {
+		values = append(values, 10)
+	}
+	values = append(values, missing, missing, missing, math.NaN())
+	for i := 0; i < 20; i++ {
+		values = append(values, 50)
+	}
+	end := start.Add(time.Duration(len(values)-1) * step)
+
+	summary := summarizeSeries(sampleStream(start, step, values...), start, end, step)
+	assert.Equal(t, map[string]string{"job": "api"}, summary.Labels)
+	assert.Equal(t, 41, summary.Points)
+	assert.Equal(t, 1, summary.NonFinite)
+	require.NotNil(t, summary.Stats)
+	assert.Equal(t, SeriesStats{Min: 10, Max: 50, Avg: 30, Last: 50, P95: 50}, *summary.Stats)
+	assert.Equal(t, "increasing", summary.Trend)
+	assert.Equal(t, 5, summary.MissingSteps)
+	assert.Equal(t, []SeriesGap{
+		{Start: start, End: start.Add(step)},
+		{Start: start.Add(22 * step), End: start.Add(24 * step)},
+	}, summary.Gaps)
+	require.Len(t, summary.Changepoints, 1)
+	assert.Equal(t, 10.0, summary.Changepoints[0].MeanBefore)
+	assert.Equal(t, 50.0, summary.Changepoints[0].MeanAfter)
+
+	var oscillating []float64
+	for i := 0; i < 60; i++ {
+		oscillating = append(oscillating, float64(1+i%2))
+	}
+	flat := summarizeSeries(sampleStream(start, step, oscillating...), start, start.Add(59*step), step)
+	assert.Equal(t, "flat", flat.Trend)
+	assert.Empty(t, flat.Gaps)
+	assert.Empty(t, flat.Changepoints)
+	assert.InDelta(t, 2, flat.Stats.P95, 1e-9)
+}
+
+func TestQueryPrometheusWithOutput(t *testing.T) {
+	var steps []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/query_range"):
+			require.NoError(t, r.ParseForm())
+			steps = append(steps, r.Form.Get("step"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "matrix", "result": [
+				{"metric": {"job": "api"}, "values": [[1704067200, "1"], [1704067500, "2"], [1704067800, "3"]]}
+			]}}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+	args := QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2024-01-01T00:00:00Z",
+		EndTime:       "2024-01-02T00:00:00Z",
+	}
+
+	result, err := queryPrometheusWithOutput(ctx, args)
+	require.NoError(t, err)
+	assert.Equal(t, "matrix", result.ResultType)
+	assert.Equal(t, 300.0, result.StepSeconds)
+	assert.Nil(t, result.Result)
+	require.Len(t, result.Summary, 1)
+	assert.Equal(t, "increasing", result.Summary[0].Trend)
+	assert.Equal(t, 3.0, result.Summary[0].Stats.Last)
+
+	args.Output = "full"
+	args.StepSeconds = 60
+	result, err = queryPrometheusWithOutput(ctx, args)
+	require.NoError(t, err)
+	assert.Empty(t, result.Summary)
+	require.IsType(t, model.Matrix{}, result.Result)
+	assert.Equal(t, []string{"300", "60"}, steps)
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultTargetsLimit = 100
+	maxTargetsLimit     = 1000
+)
+
+type ListPrometheusTargetsParams struct {
+	DatasourceUID string     `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Job           string     `json:"job,omitempty" jsonschema:"description=Optionally\\, only return targets of this job or scrape pool"`
+	Matches       []Selector `json:"matches,omitempty" jsonschema:"description=Optionally\\, label selectors that target labels must match. A target is returned if it matches any selector. Dropped targets are matched against their discovered labels"`
+	State         string     `json:"state,omitempty" jsonschema:"enum=active,enum=dropped,enum=any,description=Which targets to return: 'active' (default)\\, 'dropped' or 'any'"`
+	Health        string     `json:"health,omitempty" jsonschema:"enum=up,enum=down,enum=unknown,description=Optionally\\, only return active targets with this health"`
+	Limit         int        `json:"limit,omitempty" jsonschema:"description=The maximum number of active and of dropped targets to return (default 100\\, max 1000)"`
+}
+
+// PrometheusTarget is an active scrape target.
+type PrometheusTarget struct {
+	Labels                    map[string]string `json:"labels"`
+	ScrapePool                string            `json:"scrapePool"`
+	ScrapeURL                 string            `json:"scrapeUrl"`
+	Health                    string            `json:"health"`
+	LastError                 string            `json:"lastError,omitempty"`
+	LastScrape                time.Time         `json:"lastScrape"`
+	LastScrapeDurationSeconds float64           `json:"lastScrapeDurationSeconds"`
+}
+
+// PrometheusDroppedTarget is a discovered target dropped by relabelling.
+type PrometheusDroppedTarget struct {
+	DiscoveredLabels map[string]string `json:"discoveredLabels"`
+}
+
+// PrometheusTargetsSummary counts the targets matching the filters, before
+// the limit is applied.
+type PrometheusTargetsSummary struct {
+	Up      int `json:"up"`
+	Down    int `json:"down"`
+	Unknown int `json:"unknown"`
+	Dropped int `json:"dropped"`
+}
+
+type PrometheusTargetsResult struct {
+	Summary   PrometheusTargetsSummary  `json:"summary"`
+	Active    []PrometheusTarget        `json:"active,omitempty"`
+	Dropped   []PrometheusDroppedTarget `json:"dropped,omitempty"`
+	Truncated bool                      `json:"truncated,omitempty"`
+}
+
+// labelsMatchSelectors reports whether labels match any of the selectors.
+// No selectors match everything.
+func labelsMatchSelectors(lbls map[string]string, selectors []Selector) (bool, error) {
+	if len(selectors) == 0 {
+		return true, nil
+	}
+	ls := labels.FromMap(lbls)
+	for _, s := range selectors {
+		ok, err := s.Matches(ls)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func listPrometheusTargets(ctx context.Context, args ListPrometheusTargetsParams) (*PrometheusTargetsResult, error) {
+	state := args.State
+	switch state {
+	case "":
+		state = "active"
+	case "active", "dropped", "any":
+	default:
+		return nil, fmt.Errorf("invalid state %q: must be active, dropped or any", args.State)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultTargetsLimit
+	}
+	limit = min(limit, maxTargetsLimit)
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	targets, err := promClient.Targets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus targets: %w", err)
+	}
+
+	result := &PrometheusTargetsResult{}
+	if state != "dropped" {
+		for _, t := range targets.Active {
+			lbls := make(map[string]string, len(t.Labels))
+			for k, v := range t.Labels {
+				lbls[string(k)] = string(v)
+			}
+			if args.Job != "" && lbls["job"] != args.Job && t.ScrapePool != args.Job {
+				continue
+			}
+			if args.Health != "" && string(t.Health) != args.Health {
+				continue
+			}
+			ok, err := labelsMatchSelectors(lbls, args.Matches)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			switch t.Health {
+			case promv1.HealthGood:
+				result.Summary.Up++
+			case promv1.HealthBad:
+				result.Summary.Down++
+			default:
+				result.Summary.Unknown++
+			}
+			result.Active = append(result.Active, PrometheusTarget{
+				Labels:                    lbls,
+				ScrapePool:                t.ScrapePool,
+				ScrapeURL:                 t.ScrapeURL,
+				Health:                    string(t.Health),
+				LastError:                 t.LastError,
+				LastScrape:                t.LastScrape,
+				LastScrapeDurationSeconds: t.LastScrapeDuration,
+			})
+		}
+		// Unhealthy targets first, since they are usually what the caller
+		// is looking for.
+		sort.SliceStable(result.Active, func(i, j int) bool {
+			return (result.Active[i].Health != string(promv1.HealthGood)) && (result.Active[j].Health == string(promv1.HealthGood))
+		})
+		if len(result.Active) > limit {
+			result.Active = result.Active[:limit]
+			result.Truncated = true
+		}
+	}
+
+	if state != "active" && args.Health == "" {
+		for _, t := range targets.Dropped {
+			if args.Job != "" && t.DiscoveredLabels["job"] != args.Job {
+				continue
+			}
+			ok, err := labelsMatchSelectors(t.DiscoveredLabels, args.Matches)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			result.Summary.Dropped++
+			if len(result.Dropped) == limit {
+				result.Truncated = true
+				continue
+			}
+			result.Dropped = append(result.Dropped, PrometheusDroppedTarget{DiscoveredLabels: t.DiscoveredLabels})
+		}
+	}
+	return result, nil
+}
+
+var ListPrometheusTargets = mcpgrafana.MustTool(
+	"list_prometheus_targets",
+	"List the scrape targets of a Prometheus datasource with their health, last scrape error, last scrape time and duration. Use it to check whether a target is up when its metrics disappear. Filter by job, label selectors, state (active, dropped or any) and health. Unhealthy targets are listed first and the summary counts all matching targets.",
+	listPrometheusTargets,
+	mcp.WithTitleAnnotation("List Prometheus targets"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListPrometheusTargetsMetadataParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	MatchTarget   string `json:"matchTarget,omitempty" jsonschema:"description=Optionally\\, a label selector matching the targets to return metadata for\\, e.g. {job=\"api\"}. Defaults to all targets"`
+	Metric        string `json:"metric,omitempty" jsonschema:"description=Optionally\\, only return metadata for this metric"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"default=100,description=The maximum number of entries to return"`
+}
+
+func listPrometheusTargetsMetadata(ctx context.Context, args ListPrometheusTargetsMetadataParams) ([]promv1.MetricMetadata, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+
+	limit := args.Limit
+	if limit == 0 {
+		limit = 100
+	}
+
+	metadata, err := promClient.TargetsMetadata(ctx, args.MatchTarget, args.Metric, strconv.Itoa(limit))
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus targets metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+var ListPrometheusTargetsMetadata = mcpgrafana.MustTool(
+	"list_prometheus_targets_metadata",
+	"List metric metadata (type, help and unit) as reported by the scrape targets of a Prometheus datasource, together with the labels of the target exposing it. Filter by a target label selector and metric name.",
+	listPrometheusTargetsMetadata,
+	mcp.WithTitleAnnotation("List Prometheus targets metadata"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
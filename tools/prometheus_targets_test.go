@@ -0,0 +1,106 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func targetsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/datasources/uid/prom":
+			_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/targets"):
+			_, _ = w.Write([]byte(`{"status": "success", "data": {
+				"activeTargets": [
+					{"labels": {"job": "api", "instance": "api-1:8080"}, "scrapePool": "api", "scrapeUrl": "http://api-1:8080/metrics",
+						"health": "up", "lastError": "", "lastScrape": "2024-01-01T00:00:00Z", "lastScrapeDuration": 0.01},
+					{"labels": {"job": "api", "instance": "api-2:8080"}, "scrapePool": "api", "scrapeUrl": "http://api-2:8080/metrics",
+						"health": "down", "lastError": "connection refused", "lastScrape": "2024-01-01T00:00:00Z", "lastScrapeDuration": 0.5},
+					{"labels": {"job": "db", "instance": "db-1:9187"}, "scrapePool": "db", "scrapeUrl": "http://db-1:9187/metrics",
+						"health": "up", "lastError": "", "lastScrape": "2024-01-01T00:00:00Z", "lastScrapeDuration": 0.02}
+				],
+				"droppedTargets": [
+					{"discoveredLabels": {"job": "api", "__address__": "api-3:9090"}},
+					{"discoveredLabels": {"job": "db", "__address__": "db-2:9090"}}
+				]
+			}}`))
+		case strings.HasSuffix(r.URL.Path, "/api/v1/targets/metadata"):
+			assert.Equal(t, `{job="api"}`, r.URL.Query().Get("match_target"))
+			assert.Equal(t, "100", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": [
+				{"target": {"job": "api", "instance": "api-1:8080"}, "metric": "http_requests_total", "type": "counter", "help": "Requests.", "unit": ""}
+			]}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestListPrometheusTargets(t *testing.T) {
+	server := targetsServer(t)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	t.Run("by job", func(t *testing.T) {
+		result, err := listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom", Job: "api"})
+		require.NoError(t, err)
+		assert.Equal(t, PrometheusTargetsSummary{Up: 1, Down: 1}, result.Summary)
+		require.Len(t, result.Active, 2)
+		// Unhealthy targets come first.
+		assert.Equal(t, "down", result.Active[0].Health)
+		assert.Equal(t, "connection refused", result.Active[0].LastError)
+		assert.Equal(t, "api-2:8080", result.Active[0].Labels["instance"])
+		assert.Equal(t, 0.5, result.Active[0].LastScrapeDurationSeconds)
+		assert.Empty(t, result.Dropped)
+	})
+
+	t.Run("by selector and health", func(t *testing.T) {
+		result, err := listPrometheusTargets(ctx, ListPrometheusTargetsParams{
+			DatasourceUID: "prom",
+			Matches:       []Selector{{Filters: []LabelMatcher{{Name: "instance", Value: ".*-1:.*", Type: "=~"}}}},
+			Health:        "up",
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Active, 2)
+		assert.Equal(t, PrometheusTargetsSummary{Up: 2}, result.Summary)
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		result, err := listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom", Job: "api", State: "any", Limit: 1})
+		require.NoError(t, err)
+		assert.Equal(t, PrometheusTargetsSummary{Up: 1, Down: 1, Dropped: 1}, result.Summary)
+		assert.Len(t, result.Active, 1)
+		assert.True(t, result.Truncated)
+		require.Len(t, result.Dropped, 1)
+		assert.Equal(t, "api-3:9090", result.Dropped[0].DiscoveredLabels["__address__"])
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		_, err := listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom", State: "up"})
+		assert.ErrorContains(t, err, "invalid state")
+	})
+}
+
+func TestListPrometheusTargetsMetadata(t *testing.T) {
+	server := targetsServer(t)
+	defer server.Close()
+
+	metadata, err := listPrometheusTargetsMetadata(mockCtxWithGrafanaURL(server), ListPrometheusTargetsMetadataParams{
+		DatasourceUID: "prom",
+		MatchTarget:   `{job="api"}`,
+	})
+	require.NoError(t, err)
+	require.Len(t, metadata, 1)
+	assert.Equal(t, "http_requests_total", metadata[0].Metric)
+	assert.Equal(t, "api-1:8080", metadata[0].Target["instance"])
+}
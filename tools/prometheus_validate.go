@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// maxValidateMetadataLookups bounds the metadata requests made for a single
+// expression.
+const maxValidateMetadataLookups = 20
+
+var (
+	// counterFunctions expect a counter as their argument.
+	counterFunctions = map[string]bool{"rate": true, "irate": true, "increase": true, "resets": true}
+	// gaugeFunctions expect a gauge as their argument.
+	gaugeFunctions = map[string]bool{"delta": true, "idelta": true, "deriv": true, "predict_linear": true}
+	// rawCounterFunctions and rawCounterAggregations are meaningful on the
+	// raw value of a counter, so they don't trigger the counter without
+	// rate() warning.
+	rawCounterFunctions = map[string]bool{
+		"absent": true, "absent_over_time": true, "present_over_time": true, "timestamp": true,
+		"changes": true, "last_over_time": true, "count_over_time": true,
+	}
+	rawCounterAggregations = map[parser.ItemType]bool{parser.COUNT: true, parser.COUNT_VALUES: true, parser.GROUP: true}
+)
+
+type ValidatePromQLParams struct {
+	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to validate. Grafana template variables such as $__rate_interval are replaced with placeholders before parsing"`
+	DatasourceUID string `json:"datasourceUid,omitempty" jsonschema:"description=The UID of a Prometheus datasource. When set\\, metric metadata is used to check that functions such as rate() are applied to the right metric types"`
+}
+
+// PromQLError is a parse error with its position in the expression.
+// Offsets are 0-based byte offsets; lines and columns are 1-based.
+type PromQLError struct {
+	Message string `json:"message"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// PromQLSelector is a series selector used by the expression.
+type PromQLSelector struct {
+	Selector string   `json:"selector"`
+	Metric   string   `json:"metric,omitempty"`
+	Matchers []string `json:"matchers,omitempty"`
+	Range    string   `json:"range,omitempty"`
+}
+
+type PromQLValidationResult struct {
+	Valid bool `json:"valid"`
+	// Interpolated is the expression that was parsed when template variables
+	// were replaced. Error positions refer to it rather than to expr.
+	Interpolated string           `json:"interpolated,omitempty"`
+	Errors       []PromQLError    `json:"errors,omitempty"`
+	Formatted    string           `json:"formatted,omitempty"`
+	Type         string           `json:"type,omitempty"`
+	Metrics      []string         `json:"metrics,omitempty"`
+	Selectors    []PromQLSelector `json:"selectors,omitempty"`
+	Warnings     []string         `json:"warnings,omitempty"`
+}
+
+// promQLErrorPosition converts a byte offset into a 1-based line and column.
+func promQLErrorPosition(expr string, offset int) (int, int) {
+	offset = max(0, min(offset, len(expr)))
+	line := strings.Count(expr[:offset], "\n") + 1
+	column := offset - strings.LastIndex(expr[:offset], "\n")
+	return line, column
+}
+
+// promQLErrors converts a parser error into positioned errors.
+func promQLErrors(expr string, err error) []PromQLError {
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) {
+		return []PromQLError{{Message: err.Error(), Line: 1, Column: 1}}
+	}
+	out := make([]PromQLError, 0, len(parseErrs))
+	for _, e := range parseErrs {
+		start, end := int(e.PositionRange.Start), int(e.PositionRange.End)
+		line, column := promQLErrorPosition(expr, start)
+		perr := PromQLError{Message: e.Err.Error(), Start: start, End: end, Line: line, Column: column}
+		if strings.Contains(perr.Message, "expected type range vector") {
+			perr.Hint = "add a range selector to the argument, such as [5m] or [$__rate_interval]"
+		}
+		out = append(out, perr)
+	}
+	return out
+}
+
+// selectorMetric returns the metric name of a selector, if it selects one.
+func selectorMetric(vs *parser.VectorSelector) string {
+	if vs.Name != "" {
+		return vs.Name
+	}
+	for _, m := range vs.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// metricTypeByName guesses a metric's type from the naming conventions.
+func metricTypeByName(metric string) promv1.MetricType {
+	for _, suffix := range []string{"_total", "_count", "_sum", "_bucket"} {
+		if strings.HasSuffix(metric, suffix) {
+			return promv1.MetricTypeCounter
+		}
+	}
+	return promv1.MetricTypeUnknown
+}
+
+// promQLMetricTypes returns the type of each metric, from the datasource's
+// metadata when available and from naming conventions otherwise.
+func promQLMetricTypes(ctx context.Context, datasourceUID string, metrics []string) (map[string]promv1.MetricType, []string) {
+	types := make(map[string]promv1.MetricType, len(metrics))
+	for _, m := range metrics {
+		types[m] = metricTypeByName(m)
+	}
+	if datasourceUID == "" {
+		return types, nil
+	}
+	client, err := promClientFromContext(ctx, datasourceUID)
+	if err != nil {
+		return types, []string{fmt.Sprintf("metric metadata unavailable: %v", err)}
+	}
+	for i, m := range metrics {
+		if i == maxValidateMetadataLookups {
+			break
+		}
+		// Histogram and summary series are described by their base name.
+		name := m
+		for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		metadata, err := client.Metadata(ctx, name, "")
+		if err != nil {
+			return types, []string{fmt.Sprintf("metric metadata unavailable: %v", err)}
+		}
+		if md := metadata[name]; len(md) > 0 {
+			switch {
+			case name != m && (md[0].Type == promv1.MetricTypeHistogram || md[0].Type == promv1.MetricTypeSummary || md[0].Type == promv1.MetricTypeGaugeHistogram):
+				// _count, _sum and _bucket series of histograms and summaries
+				// are counters.
+				types[m] = promv1.MetricTypeCounter
+			case name == m:
+				types[m] = md[0].Type
+			}
+		}
+	}
+	return types, nil
+}
+
+// unwrapParens strips any parentheses around an expression.
+func unwrapParens(expr parser.Expr) parser.Expr {
+	for {
+		p, ok := expr.(*parser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.Expr
+	}
+}
+
+// rangeArgumentMetrics returns the metrics selected by a function's range
+// vector argument.
+func rangeArgumentMetrics(call *parser.Call) []string {
+	var metrics []string
+	for _, arg := range call.Args {
+		ms, ok := unwrapParens(arg).(*parser.MatrixSelector)
+		if !ok {
+			continue
+		}
+		if vs, ok := ms.VectorSelector.(*parser.VectorSelector); ok {
+			if m := selectorMetric(vs); m != "" {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics
+}
+
+// usedWithoutRate reports whether a selector is used without a function
+// that handles counters among its ancestors.
+func usedWithoutRate(path []parser.Node) bool {
+	for _, node := range path {
+		switch n := node.(type) {
+		case *parser.Call:
+			if counterFunctions[n.Func.Name] || gaugeFunctions[n.Func.Name] || rawCounterFunctions[n.Func.Name] {
+				return false
+			}
+		case *parser.AggregateExpr:
+			if rawCounterAggregations[n.Op] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// histogramQuantileWarnings checks that the buckets passed to
+// histogram_quantile keep the le label when aggregated.
+func histogramQuantileWarnings(call *parser.Call) []string {
+	if call.Func.Name != "histogram_quantile" || len(call.Args) < 2 {
+		return nil
+	}
+	agg, ok := unwrapParens(call.Args[1]).(*parser.AggregateExpr)
+	if !ok {
+		return nil
+	}
+	hasLe := false
+	for _, l := range agg.Grouping {
+		if l == "le" {
+			hasLe = true
+		}
+	}
+	if hasLe == agg.Without {
+		return []string{fmt.Sprintf("histogram_quantile() is applied to %s() that drops the le label, so the buckets are merged: aggregate by (le, ...) instead", agg.Op)}
+	}
+	return nil
+}
+
+func validatePromQL(ctx context.Context, args ValidatePromQLParams) (*PromQLValidationResult, error) {
+	if strings.TrimSpace(args.Expr) == "" {
+		return nil, fmt.Errorf("expr is required")
+	}
+	result := &PromQLValidationResult{}
+	expr := args.Expr
+	if templateVariableRegex.MatchString(expr) {
+		expr = interpolatePromQLVariables(expr)
+		result.Interpolated = expr
+	}
+
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		result.Errors = promQLErrors(expr, err)
+		return result, nil
+	}
+	result.Valid = true
+	result.Formatted = parser.Prettify(parsed)
+	result.Type = string(parsed.Type())
+
+	metricSet := map[string]bool{}
+	var counterChecks []func(map[string]promv1.MetricType) []string
+	parser.Inspect(parsed, func(node parser.Node, path []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			sel := PromQLSelector{Selector: n.String(), Metric: selectorMetric(n)}
+			for _, m := range n.LabelMatchers {
+				if m.Name != labels.MetricName {
+					sel.Matchers = append(sel.Matchers, m.String())
+				}
+			}
+			if len(path) > 0 {
+				if ms, ok := path[len(path)-1].(*parser.MatrixSelector); ok {
+					sel.Range = model.Duration(ms.Range).String()
+					if ms.RangeExpr != nil {
+						sel.Range = ms.RangeExpr.String()
+					}
+				}
+			}
+			result.Selectors = append(result.Selectors, sel)
+			if sel.Metric == "" {
+				return nil
+			}
+			metricSet[sel.Metric] = true
+			if usedWithoutRate(path) {
+				metric := sel.Metric
+				counterChecks = append(counterChecks, func(types map[string]promv1.MetricType) []string {
+					if types[metric] == promv1.MetricTypeCounter {
+						return []string{fmt.Sprintf("%s is a counter used without rate(), irate() or increase(); its raw value only ever grows and resets on restarts", metric)}
+					}
+					return nil
+				})
+			}
+		case *parser.Call:
+			result.Warnings = append(result.Warnings, histogramQuantileWarnings(n)...)
+			name := n.Func.Name
+			if !counterFunctions[name] && !gaugeFunctions[name] {
+				return nil
+			}
+			for _, metric := range rangeArgumentMetrics(n) {
+				counterChecks = append(counterChecks, func(types map[string]promv1.MetricType) []string {
+					switch {
+					case counterFunctions[name] && types[metric] == promv1.MetricTypeGauge:
+						return []string{fmt.Sprintf("%s() is applied to %s, which is a gauge: use deriv() or delta() instead", name, metric)}
+					case gaugeFunctions[name] && types[metric] == promv1.MetricTypeCounter:
+						return []string{fmt.Sprintf("%s() is applied to %s, which is a counter: use rate() or increase() instead", name, metric)}
+					}
+					return nil
+				})
+			}
+			if len(n.Args) > 0 {
+				if sq, ok := unwrapParens(n.Args[0]).(*parser.SubqueryExpr); ok && counterFunctions[name] {
+					if _, agg := unwrapParens(sq.Expr).(*parser.AggregateExpr); agg {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("%s() over a subquery of an aggregation can't detect counter resets: aggregate the %s() instead, e.g. sum(%s(metric[5m]))", name, name, name))
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	for m := range metricSet {
+		result.Metrics = append(result.Metrics, m)
+	}
+	sort.Strings(result.Metrics)
+	types, warnings := promQLMetricTypes(ctx, args.DatasourceUID, result.Metrics)
+	result.Warnings = append(result.Warnings, warnings...)
+	for _, check := range counterChecks {
+		result.Warnings = append(result.Warnings, check(types)...)
+	}
+	return result, nil
+}
+
+var ValidatePromQL = mcpgrafana.MustTool(
+	"validate_promql",
+	"Validate a PromQL expression locally before running it. Returns parse errors with their line and column, a pretty-printed version of the query, the metrics and label matchers it uses, and warnings about likely mistakes such as rate() on a gauge, counters used without rate(), or histogram_quantile() without the le label. Pass a Prometheus datasourceUid to check metric types against the datasource's metadata.",
+	validatePromQL,
+	mcp.WithTitleAnnotation("Validate PromQL"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,103 @@
+//go:build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePromQL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("parse error position", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{Expr: "sum(rate(http_requests_total[5m])\n  by (job)"})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.NotEmpty(t, result.Errors)
+		assert.Equal(t, 2, result.Errors[0].Line)
+		assert.Empty(t, result.Formatted)
+	})
+
+	t.Run("missing range selector", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{Expr: "rate(http_requests_total)"})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		require.Len(t, result.Errors, 1)
+		assert.Contains(t, result.Errors[0].Hint, "[5m]")
+		assert.Equal(t, 1, result.Errors[0].Line)
+	})
+
+	t.Run("metrics, selectors and formatting", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{
+			Expr: `sum by (le) (rate(http_request_duration_seconds_bucket{job="api",code=~"5.."}[$__rate_interval])) / on() group_left sum(up{job="api"})`,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Contains(t, result.Interpolated, "[5m]")
+		assert.Equal(t, "vector", result.Type)
+		assert.Equal(t, []string{"http_request_duration_seconds_bucket", "up"}, result.Metrics)
+		require.Len(t, result.Selectors, 2)
+		assert.Equal(t, "http_request_duration_seconds_bucket", result.Selectors[0].Metric)
+		assert.Equal(t, []string{`job="api"`, `code=~"5.."`}, result.Selectors[0].Matchers)
+		assert.Equal(t, "5m", result.Selectors[0].Range)
+		assert.Contains(t, result.Formatted, "\n")
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("heuristic warnings", func(t *testing.T) {
+		result, err := validatePromQL(ctx, ValidatePromQLParams{
+			Expr: `histogram_quantile(0.9, sum by (job) (rate(latency_bucket[5m]))) + delta(requests_total[5m]) + requests_total + count(errors_total)`,
+		})
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		warnings := strings.Join(result.Warnings, "\n")
+		assert.Contains(t, warnings, "drops the le label")
+		assert.Contains(t, warnings, "delta() is applied to requests_total, which is a counter")
+		assert.Contains(t, warnings, "requests_total is a counter used without rate()")
+		assert.NotContains(t, warnings, "errors_total")
+	})
+
+	t.Run("metadata warnings", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.URL.Path == "/api/datasources/uid/prom":
+				_, _ = w.Write([]byte(`{"uid": "prom", "type": "prometheus"}`))
+			case strings.HasSuffix(r.URL.Path, "/api/v1/metadata"):
+				switch r.URL.Query().Get("metric") {
+				case "memory_usage_bytes":
+					_, _ = w.Write([]byte(`{"status": "success", "data": {"memory_usage_bytes": [{"type": "gauge", "help": "", "unit": ""}]}}`))
+				case "jobs_processed":
+					_, _ = w.Write([]byte(`{"status": "success", "data": {"jobs_processed": [{"type": "counter", "help": "", "unit": ""}]}}`))
+				default:
+					_, _ = w.Write([]byte(`{"status": "success", "data": {}}`))
+				}
+			default:
+				t.Errorf("unexpected request: %s", r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		result, err := validatePromQL(mockCtxWithGrafanaURL(server), ValidatePromQLParams{
+			Expr:          `rate(memory_usage_bytes[5m]) + jobs_processed`,
+			DatasourceUID: "prom",
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Warnings, 2)
+		assert.Contains(t, result.Warnings[0], "rate() is applied to memory_usage_bytes, which is a gauge")
+		assert.Contains(t, result.Warnings[1], "jobs_processed is a counter used without rate()")
+	})
+
+	t.Run("empty expression", func(t *testing.T) {
+		_, err := validatePromQL(ctx, ValidatePromQLParams{})
+		assert.Error(t, err)
+	})
+}
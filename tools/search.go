@@ -3,7 +3,10 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
@@ -15,33 +18,201 @@ import (
 var dashboardTypeStr = "dash-db"
 var folderTypeStr = "dash-folder"
 
+// repeatedQueryParams is a search client option that sends list parameters as
+// repeated query parameters (?a=1&a=2), which is what Grafana's search API
+// expects. The generated client joins list values with commas instead.
+func repeatedQueryParams(values map[string][]string) search.ClientOption {
+	return func(op *runtime.ClientOperation) {
+		params := op.Params
+		op.Params = runtime.ClientRequestWriterFunc(func(r runtime.ClientRequest, reg strfmt.Registry) error {
+			if err := params.WriteToRequest(r, reg); err != nil {
+				return err
+			}
+			for name, vals := range values {
+				if len(vals) == 0 {
+					continue
+				}
+				if err := r.SetQueryParam(name, vals...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 5000
+)
+
 type SearchDashboardsParams struct {
-	Query string `json:"query" jsonschema:"description=The query to search for"`
+	Query         string   `json:"query" jsonschema:"description=The query to search for"`
+	Tags          []string `json:"tags,omitempty" jsonschema:"description=Only return dashboards that have all of these tags"`
+	FolderUIDs    []string `json:"folderUIDs,omitempty" jsonschema:"description=Only return dashboards in these folders. Use 'general' for the root folder"`
+	DashboardUIDs []string `json:"dashboardUIDs,omitempty" jsonschema:"description=Only return dashboards with these UIDs"`
+	Starred       bool     `json:"starred,omitempty" jsonschema:"description=Only return dashboards starred by the current user"`
+	Sort          string   `json:"sort,omitempty" jsonschema:"description=Sort order: 'alpha-asc' or 'alpha-desc'. Defaults to relevance or alphabetical order"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Maximum number of dashboards to return (default 50\\, max 5000)"`
+	Page          int      `json:"page,omitempty" jsonschema:"description=Page number to return\\, starting at 1 (default 1)"`
 }
 
 func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.HitList, error) {
 	c := mcpgrafana.GrafanaClientFromContext(ctx)
 	params := search.NewSearchParamsWithContext(ctx)
+	params.SetType(&dashboardTypeStr)
 	if args.Query != "" {
 		params.SetQuery(&args.Query)
-		params.SetType(&dashboardTypeStr)
 	}
-	search, err := c.Search.Search(params)
+	if args.Starred {
+		params.SetStarred(&args.Starred)
+	}
+	if args.Sort != "" {
+		params.SetSort(&args.Sort)
+	}
+	if args.Limit > 0 {
+		limit := int64(min(args.Limit, maxSearchLimit))
+		params.SetLimit(&limit)
+	}
+	if args.Page > 0 {
+		page := int64(args.Page)
+		params.SetPage(&page)
+	}
+	search, err := c.Search.Search(params, repeatedQueryParams(map[string][]string{
+		"tag":           args.Tags,
+		"folderUIDs":    args.FolderUIDs,
+		"dashboardUIDs": args.DashboardUIDs,
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("search dashboards for %+v: %w", c, err)
 	}
 	return search.Payload, nil
 }
 
+// DashboardSearchHit is the compact form of a dashboard search result.
+type DashboardSearchHit struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	// FolderUID and FolderPath are empty for dashboards in the root folder.
+	FolderUID  string   `json:"folderUid,omitempty"`
+	FolderPath string   `json:"folderPath,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	URL        string   `json:"url"`
+}
+
+type SearchDashboardsResult struct {
+	Dashboards []DashboardSearchHit `json:"dashboards"`
+	Page       int                  `json:"page"`
+	Limit      int                  `json:"limit"`
+	// HasMore is true when the page is full and the next page may contain
+	// more dashboards.
+	HasMore bool `json:"hasMore"`
+}
+
+// folderPaths resolves the slash-separated path of each folder from its
+// parents. Nested folders are not supported on older Grafana versions, so
+// the title from the search hit is used when the folder can't be fetched.
+func folderPaths(ctx context.Context, hits models.HitList) map[string]string {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	paths := map[string]string{}
+	for _, hit := range hits {
+		if hit.FolderUID == "" {
+			continue
+		}
+		if _, ok := paths[hit.FolderUID]; ok {
+			continue
+		}
+		paths[hit.FolderUID] = hit.FolderTitle
+		resp, err := c.Folders.GetFolderByUID(hit.FolderUID)
+		if err != nil {
+			continue
+		}
+		titles := make([]string, 0, len(resp.Payload.Parents)+1)
+		for _, parent := range resp.Payload.Parents {
+			titles = append(titles, parent.Title)
+		}
+		paths[hit.FolderUID] = strings.Join(append(titles, resp.Payload.Title), "/")
+	}
+	return paths
+}
+
+func searchDashboardsCompact(ctx context.Context, args SearchDashboardsParams) (*SearchDashboardsResult, error) {
+	if args.Limit <= 0 {
+		args.Limit = defaultSearchLimit
+	}
+	args.Limit = min(args.Limit, maxSearchLimit)
+	if args.Page <= 0 {
+		args.Page = 1
+	}
+	hits, err := searchDashboards(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	paths := folderPaths(ctx, hits)
+	result := &SearchDashboardsResult{
+		Dashboards: make([]DashboardSearchHit, 0, len(hits)),
+		Page:       args.Page,
+		Limit:      args.Limit,
+		HasMore:    len(hits) >= args.Limit,
+	}
+	for _, hit := range hits {
+		result.Dashboards = append(result.Dashboards, DashboardSearchHit{
+			UID:        hit.UID,
+			Title:      hit.Title,
+			FolderUID:  hit.FolderUID,
+			FolderPath: paths[hit.FolderUID],
+			Tags:       hit.Tags,
+			URL:        hit.URL,
+		})
+	}
+	return result, nil
+}
+
 var SearchDashboards = mcpgrafana.MustTool(
 	"search_dashboards",
-	"Search for Grafana dashboards by a query string. Returns a list of matching dashboards with details like title, UID, folder, tags, and URL.",
-	searchDashboards,
+	"Search for Grafana dashboards by a query string, tags, folders, dashboard UIDs or starred status. Returns a page of matching dashboards with their UID, title, folder path, tags and URL. Use limit and page to page through large result sets.",
+	searchDashboardsCompact,
 	mcp.WithTitleAnnotation("Search dashboards"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+type ListDashboardTagsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Only return tags containing this string (case-insensitive)"`
+}
+
+// DashboardTag is a dashboard tag with the number of dashboards using it.
+type DashboardTag struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+func listDashboardTags(ctx context.Context, args ListDashboardTagsParams) ([]DashboardTag, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Dashboards.GetDashboardTags()
+	if err != nil {
+		return nil, fmt.Errorf("list dashboard tags: %w", err)
+	}
+	query := strings.ToLower(args.Query)
+	tags := []DashboardTag{}
+	for _, item := range resp.Payload {
+		if item == nil || !strings.Contains(strings.ToLower(item.Term), query) {
+			continue
+		}
+		tags = append(tags, DashboardTag{Tag: item.Term, Count: item.Count})
+	}
+	return tags, nil
+}
+
+var ListDashboardTags = mcpgrafana.MustTool(
+	"list_dashboard_tags",
+	"List the tags used on dashboards with the number of dashboards using each tag. Use the tags to filter search_dashboards.",
+	listDashboardTags,
+	mcp.WithTitleAnnotation("List dashboard tags"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 type SearchFoldersParams struct {
 	Query string `json:"query" jsonschema:"description=The query to search for"`
 }
@@ -72,4 +243,5 @@ var SearchFolders = mcpgrafana.MustTool(
 func AddSearchTools(mcp *server.MCPServer) {
 	SearchDashboards.Register(mcp)
 	SearchFolders.Register(mcp)
+	ListDashboardTags.Register(mcp)
 }
@@ -0,0 +1,71 @@
+//go:build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDashboardsCompact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/search":
+			q := r.URL.Query()
+			assert.Equal(t, "dash-db", q.Get("type"))
+			assert.Equal(t, []string{"prod", "api"}, q["tag"])
+			assert.Equal(t, []string{"api", "web"}, q["folderUIDs"])
+			assert.Equal(t, "true", q.Get("starred"))
+			assert.Equal(t, "alpha-desc", q.Get("sort"))
+			assert.Equal(t, "2", q.Get("limit"))
+			assert.Equal(t, "3", q.Get("page"))
+			_, _ = w.Write([]byte(`[
+				{"uid": "d1", "title": "API", "type": "dash-db", "folderUid": "api", "folderTitle": "API", "tags": ["prod", "api"], "url": "/d/d1/api", "isStarred": true},
+				{"uid": "d2", "title": "Web", "type": "dash-db", "folderUid": "web", "folderTitle": "Web", "tags": ["prod", "api"], "url": "/d/d2/web", "isStarred": true}
+			]`))
+		case "/api/folders/api":
+			_, _ = w.Write([]byte(`{"uid": "api", "title": "API", "parents": [{"uid": "team", "title": "Team"}]}`))
+		case "/api/folders/web":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "folder not found"}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.String())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	result, err := searchDashboardsCompact(mockCtxWithClient(server), SearchDashboardsParams{
+		Tags:       []string{"prod", "api"},
+		FolderUIDs: []string{"api", "web"},
+		Starred:    true,
+		Sort:       "alpha-desc",
+		Limit:      2,
+		Page:       3,
+	})
+	require.NoError(t, err)
+	assert.True(t, result.HasMore)
+	assert.Equal(t, 3, result.Page)
+	assert.Equal(t, []DashboardSearchHit{
+		{UID: "d1", Title: "API", FolderUID: "api", FolderPath: "Team/API", Tags: []string{"prod", "api"}, URL: "/d/d1/api"},
+		{UID: "d2", Title: "Web", FolderUID: "web", FolderPath: "Web", Tags: []string{"prod", "api"}, URL: "/d/d2/web"},
+	}, result.Dashboards)
+}
+
+func TestListDashboardTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/dashboards/tags", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"term": "prod", "count": 4}, {"term": "Production-EU", "count": 1}, {"term": "staging", "count": 2}]`))
+	}))
+	defer server.Close()
+
+	tags, err := listDashboardTags(mockCtxWithClient(server), ListDashboardTagsParams{Query: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, []DashboardTag{{Tag: "prod", Count: 4}, {Tag: "Production-EU", Count: 1}}, tags)
+}
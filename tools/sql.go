@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// sqlDialect describes a SQL datasource plugin: the datasource types it
+// covers, the tool that queries it and how its query model is built.
+type sqlDialect struct {
+	name  string
+	types []string
+	tool  string
+	// queryModel returns the plugin-specific fields of a query for the given
+	// SQL and format ("table" or "time_series").
+	queryModel func(rawSQL, format string) map[string]interface{}
+	catalog    sqlCatalog
+}
+
+// grafanaSQLQueryModel is the query model shared by the core SQL
+// datasources (PostgreSQL, MySQL and Microsoft SQL Server).
+func grafanaSQLQueryModel(rawSQL, format string) map[string]interface{} {
+	return map[string]interface{}{
+		"rawSql":     rawSQL,
+		"format":     format,
+		"rawQuery":   true,
+		"editorMode": "code",
+	}
+}
+
+// clickHouseQueryModel builds a query for the ClickHouse plugin, which uses
+// a numeric format (0 = time series, 1 = table).
+func clickHouseQueryModel(rawSQL, format string) map[string]interface{} {
+	model := map[string]interface{}{
+		"rawSql":     rawSQL,
+		"editorType": "sql",
+		"format":     1,
+		"queryType":  "table",
+	}
+	if format == "time_series" {
+		model["format"] = 0
+		model["queryType"] = "timeseries"
+	}
+	return model
+}
+
+var (
+	postgresDialect = &sqlDialect{
+		name:       "PostgreSQL",
+		types:      []string{"grafana-postgresql-datasource", "postgres"},
+		tool:       "query_postgres",
+		queryModel: grafanaSQLQueryModel,
+		catalog: postgresCatalog{informationSchemaCatalog{
+			currentSchema: "current_schema()",
+			systemSchemas: []string{"information_schema", "pg_catalog", "pg_toast"},
+			dataType:      "CASE WHEN data_type = 'USER-DEFINED' THEN udt_name ELSE data_type END",
+		}},
+	}
+	mysqlDialect = &sqlDialect{
+		name:       "MySQL",
+		types:      []string{"mysql"},
+		tool:       "query_mysql",
+		queryModel: grafanaSQLQueryModel,
+		catalog: mysqlCatalog{informationSchemaCatalog{
+			currentSchema:    "DATABASE()",
+			systemSchemas:    []string{"information_schema", "mysql", "performance_schema", "sys"},
+			backslashEscapes: true,
+			dataType:         "column_type",
+		}},
+	}
+	mssqlDialect = &sqlDialect{
+		name:       "Microsoft SQL Server",
+		types:      []string{"mssql"},
+		tool:       "query_mssql",
+		queryModel: grafanaSQLQueryModel,
+		catalog: mssqlCatalog{informationSchemaCatalog{
+			currentSchema: "SCHEMA_NAME()",
+			systemSchemas: []string{"INFORMATION_SCHEMA", "sys", "guest", "db_owner", "db_accessadmin", "db_securityadmin", "db_ddladmin",
+				"db_backupoperator", "db_datareader", "db_datawriter", "db_denydatareader", "db_denydatawriter"},
+			dataType: "data_type",
+		}},
+	}
+	clickHouseDialect = &sqlDialect{
+		name:       "ClickHouse",
+		types:      []string{"grafana-clickhouse-datasource"},
+		tool:       "query_clickhouse",
+		queryModel: clickHouseQueryModel,
+		catalog:    clickHouseCatalog{},
+	}
+	sqlDialects = []*sqlDialect{postgresDialect, mysqlDialect, mssqlDialect, clickHouseDialect}
+)
+
+// sqlDialectForType returns the dialect of a datasource type, or nil if the
+// type is not a supported SQL datasource.
+func sqlDialectForType(dsType string) *sqlDialect {
+	for _, d := range sqlDialects {
+		if slices.Contains(d.types, dsType) {
+			return d
+		}
+	}
+	return nil
+}
+
+// sqlDatasourceType looks up a datasource and checks that it belongs to the
+// dialect, so a query is never sent to a datasource that speaks another SQL
+// dialect.
+func sqlDatasourceType(ctx context.Context, dialect *sqlDialect, uid string) (string, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSourceByUID(uid)
+	if err != nil {
+		return "", fmt.Errorf("get datasource %s: %w", uid, err)
+	}
+	dsType := resp.Payload.Type
+	if slices.Contains(dialect.types, dsType) {
+		return dsType, nil
+	}
+	if other := sqlDialectForType(dsType); other != nil {
+		return "", fmt.Errorf("datasource %s is a %s datasource, not %s: use %s instead", uid, other.name, dialect.name, other.tool)
+	}
+	return "", fmt.Errorf("datasource %s has type %q, not %s", uid, dsType, dialect.name)
+}
+
+const (
+	defaultSQLMaxRows        = 1000
+	maxSQLMaxRows            = 10000
+	defaultSQLTimeoutSeconds = 30
+	maxSQLTimeoutSeconds     = 300
+)
+
+type QuerySQLParams struct {
+	DatasourceUID  string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	RawSQL         string `json:"rawSql" jsonschema:"required,description=The raw SQL query to execute. Time macros such as $__timeFilter(column) are expanded using the from and to time range"`
+	Format         string `json:"format,omitempty" jsonschema:"default=table,enum=table,enum=time_series,description=The format of the response: 'table' or 'time_series'. Time series queries must return a time column"`
+	From           string `json:"from,omitempty" jsonschema:"description=Start of the time range used by time macros. Relative ('now-1h') or RFC3339. Defaults to now-1h"`
+	To             string `json:"to,omitempty" jsonschema:"description=End of the time range used by time macros. Relative ('now') or RFC3339. Defaults to now"`
+	MaxRows        int    `json:"maxRows,omitempty" jsonschema:"description=Maximum number of rows to return (default 1000\\, max 10000). Queries are limited in the database so rows beyond the limit are not scanned where possible"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty" jsonschema:"description=Query timeout in seconds (default 30\\, max 300). MySQL and ClickHouse also stop the statement in the database; PostgreSQL and SQL Server rely on the statement timeout configured for the datasource's database user"`
+}
+
+type SQLQueryResult struct {
+	DatasourceUID  string       `json:"datasourceUid"`
+	DatasourceType string       `json:"datasourceType"`
+	Frames         []QueryFrame `json:"frames"`
+	// RowLimit is the maximum number of rows returned per frame, and
+	// Truncated is true if a frame had more rows. Rows beyond the limit are
+	// not fetched, so the totalRows of a truncated frame is a lower bound.
+	RowLimit  int  `json:"rowLimit"`
+	Truncated bool `json:"truncated"`
+}
+
+// querySQL runs a raw SQL query against a datasource of the given dialect
+// and returns every frame of the result. Unless allowWrites is set, the
+// query must be a single read-only statement.
+func querySQL(ctx context.Context, dialect *sqlDialect, args QuerySQLParams, allowWrites bool) (*SQLQueryResult, error) {
+	if args.DatasourceUID == "" {
+		return nil, fmt.Errorf("datasourceUid is required")
+	}
+	if strings.TrimSpace(args.RawSQL) == "" {
+		return nil, fmt.Errorf("rawSql is required")
+	}
+	format := args.Format
+	if format == "" {
+		format = "table"
+	}
+	if format != "table" && format != "time_series" {
+		return nil, fmt.Errorf("invalid format %q: must be 'table' or 'time_series'", format)
+	}
+	if !allowWrites {
+		if _, err := checkReadOnlySQL(args.RawSQL, dialect); err != nil {
+			return nil, err
+		}
+	}
+	dsType, err := sqlDatasourceType(ctx, dialect, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	args.Format = format
+	return runSQL(ctx, dialect, dsType, args)
+}
+
+// runSQL runs a query against a datasource whose type has already been
+// checked against the dialect. Single read-only statements are rewritten to
+// enforce the row limit and statement timeout in the database; the
+// timeout is also applied to the request to Grafana.
+func runSQL(ctx context.Context, dialect *sqlDialect, dsType string, args QuerySQLParams) (*SQLQueryResult, error) {
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultSQLMaxRows
+	}
+	maxRows = min(maxRows, maxSQLMaxRows)
+	timeoutSeconds := args.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultSQLTimeoutSeconds
+	}
+	timeoutSeconds = min(timeoutSeconds, maxSQLTimeoutSeconds)
+
+	rawSQL := args.RawSQL
+	if statements, err := splitSQLStatements(rawSQL, dialect); err == nil && len(statements) == 1 && readOnlySQLError(statements[0]) == nil {
+		// One extra row is fetched to tell whether the result was truncated.
+		if limited, ok := limitSQL(statements[0], dialect, maxRows+1, timeoutSeconds); ok {
+			rawSQL = limited
+		}
+	}
+
+	query := dialect.queryModel(rawSQL, args.Format)
+	query["datasource"] = map[string]string{"uid": args.DatasourceUID, "type": dsType}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+	result, err := queryDatasource(ctx, QueryDatasourceParams{
+		DatasourceUID: args.DatasourceUID,
+		Queries:       []map[string]interface{}{query},
+		From:          args.From,
+		To:            args.To,
+		MaxRows:       maxRows,
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s query timed out after %ds", dialect.name, timeoutSeconds)
+		}
+		return nil, err
+	}
+	res := result.Results["A"]
+	if res.Error != "" {
+		return nil, fmt.Errorf("%s query failed: %s", dialect.name, res.Error)
+	}
+	out := &SQLQueryResult{
+		DatasourceUID:  args.DatasourceUID,
+		DatasourceType: dsType,
+		Frames:         res.Frames,
+		RowLimit:       maxRows,
+	}
+	for _, frame := range res.Frames {
+		out.Truncated = out.Truncated || frame.Truncated
+	}
+	return out, nil
+}
+
+// newSQLQueryTool creates the query tool of a dialect. Write statements are
+// only accepted when allowWrites is set.
+func newSQLQueryTool(dialect *sqlDialect, title, description string, allowWrites bool) mcpgrafana.Tool {
+	handler := func(ctx context.Context, args QuerySQLParams) (*SQLQueryResult, error) {
+		return querySQL(ctx, dialect, args, allowWrites)
+	}
+	if allowWrites {
+		return mcpgrafana.MustTool(dialect.tool, description+" Statements that modify data are allowed.", handler,
+			mcp.WithTitleAnnotation(title),
+			mcp.WithDestructiveHintAnnotation(true),
+		)
+	}
+	return mcpgrafana.MustTool(dialect.tool, description+" Only single read-only statements (SELECT, WITH, SHOW, DESCRIBE, EXPLAIN) are allowed.", handler,
+		mcp.WithTitleAnnotation(title),
+		mcp.WithIdempotentHintAnnotation(true),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+}
+
+func AddSQLTools(mcp *server.MCPServer, enableWriteTools bool) {
+	tool := newSQLQueryTool(mysqlDialect, "Query MySQL",
+		"Execute a raw SQL query against a MySQL or MariaDB datasource. Supports Grafana time macros such as $__timeFilter(column) and $__timeGroup(column, interval) over the from/to time range. Returns the result as a table or time series, limited to maxRows rows.",
+		enableWriteTools)
+	tool.Register(mcp)
+	tool = newSQLQueryTool(mssqlDialect, "Query Microsoft SQL Server",
+		"Execute a raw T-SQL query against a Microsoft SQL Server datasource. Supports Grafana time macros such as $__timeFilter(column) and $__timeGroup(column, interval) over the from/to time range. Returns the result as a table or time series, limited to maxRows rows.",
+		enableWriteTools)
+	tool.Register(mcp)
+	tool = newSQLQueryTool(clickHouseDialect, "Query ClickHouse",
+		"Execute a raw SQL query against a ClickHouse datasource. Supports the plugin's time macros such as $__timeFilter(column) and $__timeInterval(column) over the from/to time range. Returns the result as a table or time series, limited to maxRows rows.",
+		enableWriteTools)
+	tool.Register(mcp)
+	ListSQLSchemas.Register(mcp)
+	ListSQLTables.Register(mcp)
+	DescribeSQLTable.Register(mcp)
+	SampleSQLRows.Register(mcp)
+}
@@ -0,0 +1,319 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a keyword or identifier outside of comments, string literals
+// and quoted identifiers.
+type sqlToken struct {
+	word string // upper-cased
+	pos  int    // byte offset in the statement
+	// call is true when the word is followed by an opening parenthesis,
+	// i.e. it is a function call such as replace(...).
+	call bool
+	// depth is the number of enclosing parentheses; clauses of the
+	// statement itself are at depth 0.
+	depth int
+}
+
+// sqlStatement is one statement of a SQL string, without its terminating
+// semicolon.
+type sqlStatement struct {
+	text   string
+	tokens []sqlToken
+}
+
+// firstWord returns the first keyword of the statement.
+func (s sqlStatement) firstWord() string {
+	if len(s.tokens) == 0 {
+		return ""
+	}
+	return s.tokens[0].word
+}
+
+var dollarQuoteTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitSQLStatements splits SQL into statements and extracts their
+// keywords, skipping comments, string literals and quoted identifiers in
+// the syntax of the given dialect.
+func splitSQLStatements(sql string, dialect *sqlDialect) ([]sqlStatement, error) {
+	var (
+		statements []sqlStatement
+		current    sqlStatement
+		start      int
+		depth      int
+	)
+	flush := func(end int) {
+		current.text = strings.TrimSpace(sql[start:end])
+		if current.text != "" {
+			offset := start + strings.Index(sql[start:end], current.text)
+			for i := range current.tokens {
+				current.tokens[i].pos -= offset
+			}
+			statements = append(statements, current)
+		}
+		current = sqlStatement{}
+	}
+	backslashEscapes := dialect != postgresDialect && dialect != mssqlDialect
+
+	// skipQuoted returns the offset after a quoted section starting at i,
+	// where a doubled closing quote is an escaped quote.
+	skipQuoted := func(i int, closing byte, backslash bool) (int, error) {
+		for j := i + 1; j < len(sql); j++ {
+			switch {
+			case backslash && sql[j] == '\\':
+				j++
+			case sql[j] == closing:
+				if j+1 < len(sql) && sql[j+1] == closing {
+					j++
+					continue
+				}
+				return j + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("unterminated %c at offset %d", sql[i], i)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"), c == '#' && dialect == mysqlDialect:
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			// MySQL executes the contents of /*! ... */ comments.
+			if dialect == mysqlDialect && strings.HasPrefix(sql[i:], "/*!") {
+				return nil, fmt.Errorf("MySQL executable comments (/*! ... */) are not allowed")
+			}
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+		case c == '\'':
+			// PostgreSQL only treats backslashes as escapes in E'...' strings.
+			backslash := backslashEscapes || (dialect == postgresDialect && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e'))
+			end, err := skipQuoted(i, '\'', backslash)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '"':
+			end, err := skipQuoted(i, '"', backslashEscapes)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '`' && dialect != postgresDialect && dialect != mssqlDialect:
+			end, err := skipQuoted(i, '`', false)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '[' && dialect == mssqlDialect:
+			end, err := skipQuoted(i, ']', false)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '$' && dialect == postgresDialect && dollarQuoteTag.MatchString(sql[i:]):
+			tag := dollarQuoteTag.FindString(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string at offset %d", i)
+			}
+			i += len(tag) + end + len(tag)
+		case c == ';':
+			flush(i)
+			i++
+			start = i
+			depth = 0
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(sql) && (sql[j] == '_' || sql[j] == '$' || unicode.IsLetter(rune(sql[j])) || unicode.IsDigit(rune(sql[j]))) {
+				j++
+			}
+			next := strings.TrimLeftFunc(sql[j:], unicode.IsSpace)
+			current.tokens = append(current.tokens, sqlToken{
+				word:  strings.ToUpper(sql[i:j]),
+				pos:   i,
+				call:  strings.HasPrefix(next, "("),
+				depth: depth,
+			})
+			i = j
+		case c == '$':
+			// Grafana macros such as $__timeFilter(...) and variables.
+			j := i + 1
+			for j < len(sql) && (sql[j] == '_' || unicode.IsLetter(rune(sql[j])) || unicode.IsDigit(rune(sql[j]))) {
+				j++
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	flush(len(sql))
+	return statements, nil
+}
+
+// readOnlySQLStatements are the statements allowed when write tools are
+// disabled.
+var readOnlySQLStatements = map[string]bool{
+	"SELECT": true, "WITH": true, "SHOW": true, "DESCRIBE": true, "DESC": true, "EXPLAIN": true, "VALUES": true,
+}
+
+// sqlWriteKeywords are keywords that modify data or schema, change session
+// state or run procedures. They are allowed as function names, such as
+// MySQL's replace() and insert() string functions. ClickHouse SYSTEM
+// statements are not listed: they are rejected as a first word, and the
+// keyword also names ClickHouse's system database (system.tables).
+var sqlWriteKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true, "REPLACE": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "LOAD": true, "INTO": true, "OUTFILE": true, "DUMPFILE": true,
+	"CALL": true, "EXEC": true, "EXECUTE": true, "DO": true, "PREPARE": true, "DEALLOCATE": true, "HANDLER": true,
+	"LOCK": true, "UNLOCK": true, "VACUUM": true, "ATTACH": true, "DETACH": true, "OPTIMIZE": true, "KILL": true,
+	"BEGIN": true, "COMMIT": true, "ROLLBACK": true, "SAVEPOINT": true, "DECLARE": true,
+}
+
+// readOnlySQLError returns an error if the statement is not a read-only
+// query.
+func readOnlySQLError(stmt sqlStatement) error {
+	if !readOnlySQLStatements[stmt.firstWord()] {
+		return fmt.Errorf("%s statements are not allowed", stmt.firstWord())
+	}
+	for _, t := range stmt.tokens {
+		if sqlWriteKeywords[t.word] && !t.call {
+			return fmt.Errorf("%s is not allowed in a read-only query", t.word)
+		}
+	}
+	return nil
+}
+
+// checkReadOnlySQL parses a SQL string and returns its only statement, or
+// an error if it contains more than one statement or anything other than a
+// read-only query. This guards against accidental writes by the model; it
+// is not a security boundary, and the datasource should still use a
+// read-only database user.
+func checkReadOnlySQL(sql string, dialect *sqlDialect) (sqlStatement, error) {
+	statements, err := splitSQLStatements(sql, dialect)
+	if err != nil {
+		return sqlStatement{}, fmt.Errorf("parsing SQL: %w", err)
+	}
+	if len(statements) != 1 {
+		return sqlStatement{}, fmt.Errorf("expected a single SQL statement, found %d", len(statements))
+	}
+	if err := readOnlySQLError(statements[0]); err != nil {
+		return sqlStatement{}, fmt.Errorf("only read-only queries (SELECT, WITH, SHOW, DESCRIBE, EXPLAIN) are allowed unless write tools are enabled: %w", err)
+	}
+	return statements[0], nil
+}
+
+// sqlLimitClauses are top-level clauses after which a LIMIT can't simply be
+// appended: the statement already limits its rows, or the clause must come
+// after LIMIT.
+var sqlLimitClauses = map[string]bool{
+	"LIMIT": true, "FETCH": true, "OFFSET": true, "FOR": true, "SETTINGS": true, "FORMAT": true,
+}
+
+// topLevelClauses returns the keywords of the statement outside of
+// parentheses. ClickHouse's LIMIT n BY columns limits rows per group, not
+// the result, so it is not reported as LIMIT.
+func (s sqlStatement) topLevelClauses() map[string]bool {
+	clauses := map[string]bool{}
+	for i, t := range s.tokens {
+		if t.depth != 0 || t.call {
+			continue
+		}
+		if t.word == "LIMIT" {
+			next := i + 1
+			if next < len(s.tokens) && s.tokens[next].word == "OFFSET" {
+				next++
+			}
+			if next < len(s.tokens) && s.tokens[next].word == "BY" {
+				continue
+			}
+		}
+		clauses[t.word] = true
+	}
+	return clauses
+}
+
+// limitSQL rewrites a read-only SELECT or WITH statement so that it returns
+// at most limit rows and, where the dialect supports it, is stopped by the
+// database after timeoutSeconds. The statement is never wrapped in a
+// derived table, which would reject duplicate column names on MySQL and
+// could drop its ORDER BY: LIMIT is only appended when the statement has no
+// row limit of its own, and the timeout is added as a MySQL optimizer hint
+// or a ClickHouse SETTINGS clause. It returns false if the statement is
+// left unchanged, in which case rows are only truncated after they are
+// returned.
+func limitSQL(stmt sqlStatement, dialect *sqlDialect, limit, timeoutSeconds int) (string, bool) {
+	switch stmt.firstWord() {
+	case "SELECT", "WITH":
+	default:
+		return "", false
+	}
+	if dialect == mssqlDialect {
+		// SQL Server has no LIMIT, so TOP is added to the statement itself.
+		if stmt.firstWord() != "SELECT" {
+			return "", false
+		}
+		pos := stmt.tokens[0].pos + len("SELECT")
+		if len(stmt.tokens) > 1 {
+			switch stmt.tokens[1].word {
+			case "TOP":
+				return "", false
+			case "DISTINCT", "ALL":
+				if len(stmt.tokens) > 2 && stmt.tokens[2].word == "TOP" {
+					return "", false
+				}
+				pos = stmt.tokens[1].pos + len(stmt.tokens[1].word)
+			}
+		}
+		return fmt.Sprintf("%s TOP (%d)%s", stmt.text[:pos], limit, stmt.text[pos:]), true
+	}
+
+	clauses := stmt.topLevelClauses()
+	text := stmt.text
+	if dialect == mysqlDialect {
+		// MAX_EXECUTION_TIME must follow the first SELECT of the statement.
+		for _, t := range stmt.tokens {
+			if t.word == "SELECT" {
+				pos := t.pos + len("SELECT")
+				text = fmt.Sprintf("%s /*+ MAX_EXECUTION_TIME(%d) */%s", text[:pos], timeoutSeconds*1000, text[pos:])
+				break
+			}
+		}
+	}
+	// Clauses are appended on their own line so that a trailing line
+	// comment doesn't swallow them.
+	appendLimit := true
+	for clause := range sqlLimitClauses {
+		if clauses[clause] {
+			appendLimit = false
+			break
+		}
+	}
+	if appendLimit {
+		text += fmt.Sprintf("\nLIMIT %d", limit)
+	}
+	if dialect == clickHouseDialect && !clauses["SETTINGS"] && !clauses["FORMAT"] {
+		text += fmt.Sprintf("\nSETTINGS max_execution_time = %d", timeoutSeconds)
+	}
+	return text, text != stmt.text
+}
@@ -0,0 +1,103 @@
+//go:build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReadOnlySQL(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		dialect *sqlDialect
+		sql     string
+		err     string
+	}{
+		{name: "select", dialect: postgresDialect, sql: "SELECT * FROM orders WHERE $__timeFilter(created_at);"},
+		{name: "cte", dialect: postgresDialect, sql: "WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent"},
+		{name: "show", dialect: mysqlDialect, sql: "SHOW TABLES"},
+		{name: "keywords in strings and comments", dialect: postgresDialect, sql: "SELECT 'DROP TABLE x; DELETE' AS s, \"update\" -- INSERT INTO\nFROM t /* TRUNCATE */"},
+		{name: "dollar quoted", dialect: postgresDialect, sql: "SELECT $q$; DELETE FROM t$q$"},
+		{name: "escaped string", dialect: postgresDialect, sql: "SELECT E'it\\'s; DROP TABLE t' AS s"},
+		{name: "function named like a keyword", dialect: mysqlDialect, sql: "SELECT replace(name, 'a', 'b'), insert(name, 1, 2, 'x') FROM t"},
+		{name: "bracket identifier", dialect: mssqlDialect, sql: "SELECT [delete] FROM [my;table]"},
+		{name: "delete", dialect: postgresDialect, sql: "DELETE FROM orders", err: "DELETE statements are not allowed"},
+		{name: "select into", dialect: mssqlDialect, sql: "SELECT * INTO backup FROM orders", err: "INTO is not allowed"},
+		{name: "writable cte", dialect: postgresDialect, sql: "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", err: "DELETE is not allowed"},
+		{name: "multiple statements", dialect: postgresDialect, sql: "SELECT 1; DROP TABLE orders", err: "expected a single SQL statement, found 2"},
+		{name: "mysql hash comment", dialect: mysqlDialect, sql: "SELECT 1 # ; DROP TABLE t"},
+		{name: "mysql executable comment", dialect: mysqlDialect, sql: "SELECT 1 /*!50000 , (DELETE FROM t) */", err: "executable comments"},
+		{name: "mysql backslash escape", dialect: mysqlDialect, sql: `SELECT 'a\'; DROP TABLE t; --'`},
+		{name: "clickhouse system tables", dialect: clickHouseDialect, sql: "SELECT name, engine FROM system.tables WHERE database = currentDatabase()"},
+		{name: "clickhouse system statement", dialect: clickHouseDialect, sql: "SYSTEM FLUSH LOGS", err: "SYSTEM statements are not allowed"},
+		{name: "unterminated string", dialect: postgresDialect, sql: "SELECT 'oops", err: "unterminated"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := checkReadOnlySQL(tc.sql, tc.dialect)
+			if tc.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestLimitSQL(t *testing.T) {
+	limit := func(sql string, dialect *sqlDialect) (string, bool) {
+		statements, err := splitSQLStatements(sql, dialect)
+		require.NoError(t, err)
+		require.Len(t, statements, 1)
+		return limitSQL(statements[0], dialect, 11, 5)
+	}
+
+	sql, ok := limit("SELECT * FROM t -- trailing comment\n;", postgresDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT * FROM t -- trailing comment\nLIMIT 11", sql)
+
+	// Statements are not wrapped in a derived table, which MySQL rejects
+	// when column names repeat.
+	sql, ok = limit("SELECT a.id, b.id FROM a JOIN b ON b.a_id = a.id ORDER BY a.id", mysqlDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(5000) */ a.id, b.id FROM a JOIN b ON b.a_id = a.id ORDER BY a.id\nLIMIT 11", sql)
+
+	sql, ok = limit("WITH x AS (SELECT id FROM t LIMIT 5) SELECT * FROM x", mysqlDialect)
+	require.True(t, ok)
+	assert.Equal(t, "WITH x AS (SELECT /*+ MAX_EXECUTION_TIME(5000) */ id FROM t LIMIT 5) SELECT * FROM x\nLIMIT 11", sql)
+
+	// A statement's own row limit is kept.
+	sql, ok = limit("SELECT * FROM t ORDER BY ts LIMIT 100", mysqlDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(5000) */ * FROM t ORDER BY ts LIMIT 100", sql)
+	_, ok = limit("SELECT * FROM t ORDER BY ts LIMIT 100", postgresDialect)
+	assert.False(t, ok)
+	_, ok = limit("SELECT * FROM t FETCH FIRST 5 ROWS ONLY", postgresDialect)
+	assert.False(t, ok)
+
+	sql, ok = limit("SELECT * FROM t", clickHouseDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT * FROM t\nLIMIT 11\nSETTINGS max_execution_time = 5", sql)
+
+	// LIMIT n BY limits rows per group, not the result.
+	sql, ok = limit("SELECT host, ts FROM t ORDER BY ts DESC LIMIT 1 BY host", clickHouseDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT host, ts FROM t ORDER BY ts DESC LIMIT 1 BY host\nLIMIT 11\nSETTINGS max_execution_time = 5", sql)
+
+	sql, ok = limit("SELECT * FROM t LIMIT 3", clickHouseDialect)
+	require.True(t, ok)
+	assert.Equal(t, "SELECT * FROM t LIMIT 3\nSETTINGS max_execution_time = 5", sql)
+	_, ok = limit("SELECT * FROM t SETTINGS max_threads = 1", clickHouseDialect)
+	assert.False(t, ok)
+
+	sql, ok = limit("  select distinct name from t order by name", mssqlDialect)
+	require.True(t, ok)
+	assert.Equal(t, "select distinct TOP (11) name from t order by name", sql)
+
+	_, ok = limit("SELECT TOP 5 * FROM t", mssqlDialect)
+	assert.False(t, ok)
+	_, ok = limit("SHOW TABLES", mysqlDialect)
+	assert.False(t, ok)
+}
@@ -0,0 +1,444 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	defaultSQLSampleRows = 10
+	maxSQLSampleRows     = 100
+)
+
+// sqlCatalog builds the catalog queries of a SQL dialect. Every query
+// aliases its columns to the same names (schema_name, table_schema,
+// table_name, table_type, column_name, data_type, is_nullable,
+// column_default, index_name and definition) so the results can be read
+// the same way for every dialect. An empty schema means the current schema
+// of the connection, except for tablesQuery where it means every non-system
+// schema.
+type sqlCatalog interface {
+	schemasQuery() string
+	tablesQuery(schema string) string
+	columnsQuery(schema, table string) string
+	indexesQuery(schema, table string) string
+	sampleQuery(schema, table string, limit int) string
+}
+
+// sqlString quotes a string literal. MySQL and ClickHouse also treat
+// backslashes as escape characters.
+func sqlString(s string, backslashEscapes bool) string {
+	if backslashEscapes {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlStringList quotes a list of string literals for use in an IN clause.
+func sqlStringList(values []string, backslashEscapes bool) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = sqlString(v, backslashEscapes)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// qualifiedName joins an optional schema and a table name quoted with the
+// given identifier quoting function.
+func qualifiedName(quote func(string) string, schema, table string) string {
+	if schema == "" {
+		return quote(table)
+	}
+	return quote(schema) + "." + quote(table)
+}
+
+func doubleQuoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func backquoteIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+func bracketIdent(s string) string {
+	return "[" + strings.ReplaceAll(s, "]", "]]") + "]"
+}
+
+// informationSchemaCatalog implements the schema, table and column queries
+// with the standard information_schema views, which PostgreSQL, MySQL and
+// SQL Server all provide.
+type informationSchemaCatalog struct {
+	currentSchema    string
+	systemSchemas    []string
+	backslashEscapes bool
+	dataType         string
+}
+
+func (c informationSchemaCatalog) schemaExpr(schema string) string {
+	if schema == "" {
+		return c.currentSchema
+	}
+	return sqlString(schema, c.backslashEscapes)
+}
+
+func (c informationSchemaCatalog) schemasQuery() string {
+	return fmt.Sprintf("SELECT schema_name AS schema_name FROM information_schema.schemata WHERE schema_name NOT IN (%s) ORDER BY schema_name",
+		sqlStringList(c.systemSchemas, c.backslashEscapes))
+}
+
+func (c informationSchemaCatalog) tablesQuery(schema string) string {
+	filter := fmt.Sprintf("table_schema NOT IN (%s)", sqlStringList(c.systemSchemas, c.backslashEscapes))
+	if schema != "" {
+		filter = "table_schema = " + sqlString(schema, c.backslashEscapes)
+	}
+	return fmt.Sprintf("SELECT table_schema AS table_schema, table_name AS table_name, table_type AS table_type FROM information_schema.tables WHERE %s ORDER BY table_schema, table_name", filter)
+}
+
+func (c informationSchemaCatalog) columnsQuery(schema, table string) string {
+	return fmt.Sprintf("SELECT column_name AS column_name, %s AS data_type, is_nullable AS is_nullable, column_default AS column_default FROM information_schema.columns WHERE table_schema = %s AND table_name = %s ORDER BY ordinal_position",
+		c.dataType, c.schemaExpr(schema), sqlString(table, c.backslashEscapes))
+}
+
+type postgresCatalog struct{ informationSchemaCatalog }
+
+func (c postgresCatalog) indexesQuery(schema, table string) string {
+	return fmt.Sprintf("SELECT indexname AS index_name, indexdef AS definition FROM pg_indexes WHERE schemaname = %s AND tablename = %s ORDER BY indexname",
+		c.schemaExpr(schema), sqlString(table, false))
+}
+
+func (c postgresCatalog) sampleQuery(schema, table string, limit int) string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", qualifiedName(doubleQuoteIdent, schema, table), limit)
+}
+
+type mysqlCatalog struct{ informationSchemaCatalog }
+
+func (c mysqlCatalog) indexesQuery(schema, table string) string {
+	return fmt.Sprintf("SELECT index_name AS index_name, CONCAT(IF(MIN(non_unique) = 0, 'UNIQUE ', ''), '(', GROUP_CONCAT(column_name ORDER BY seq_in_index SEPARATOR ', '), ')') AS definition FROM information_schema.statistics WHERE table_schema = %s AND table_name = %s GROUP BY index_name ORDER BY index_name",
+		c.schemaExpr(schema), sqlString(table, true))
+}
+
+func (c mysqlCatalog) sampleQuery(schema, table string, limit int) string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", qualifiedName(backquoteIdent, schema, table), limit)
+}
+
+type mssqlCatalog struct{ informationSchemaCatalog }
+
+func (c mssqlCatalog) indexesQuery(schema, table string) string {
+	return fmt.Sprintf("SELECT i.name AS index_name, CONCAT(CASE WHEN i.is_primary_key = 1 THEN 'PRIMARY KEY ' WHEN i.is_unique = 1 THEN 'UNIQUE ' ELSE '' END, i.type_desc, ' (', STRING_AGG(c.name, ', ') WITHIN GROUP (ORDER BY ic.key_ordinal), ')') AS definition "+
+		"FROM sys.indexes i JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id "+
+		"WHERE i.object_id = OBJECT_ID(QUOTENAME(%s) + '.' + QUOTENAME(%s)) AND i.name IS NOT NULL GROUP BY i.name, i.is_primary_key, i.is_unique, i.type_desc ORDER BY i.name",
+		c.schemaExpr(schema), sqlString(table, false))
+}
+
+func (c mssqlCatalog) sampleQuery(schema, table string, limit int) string {
+	return fmt.Sprintf("SELECT TOP %d * FROM %s", limit, qualifiedName(bracketIdent, schema, table))
+}
+
+// clickHouseCatalog uses ClickHouse's system tables, which are more complete
+// than its information_schema views. Databases are reported as schemas, and
+// the primary key, sorting key and data skipping indices as indexes.
+type clickHouseCatalog struct{}
+
+var clickHouseSystemDatabases = []string{"system", "INFORMATION_SCHEMA", "information_schema"}
+
+func (clickHouseCatalog) databaseExpr(schema string) string {
+	if schema == "" {
+		return "currentDatabase()"
+	}
+	return sqlString(schema, true)
+}
+
+func (clickHouseCatalog) schemasQuery() string {
+	return fmt.Sprintf("SELECT name AS schema_name FROM system.databases WHERE name NOT IN (%s) ORDER BY name",
+		sqlStringList(clickHouseSystemDatabases, true))
+}
+
+func (c clickHouseCatalog) tablesQuery(schema string) string {
+	filter := fmt.Sprintf("database NOT IN (%s)", sqlStringList(clickHouseSystemDatabases, true))
+	if schema != "" {
+		filter = "database = " + c.databaseExpr(schema)
+	}
+	return fmt.Sprintf("SELECT database AS table_schema, name AS table_name, engine AS table_type FROM system.tables WHERE %s ORDER BY database, name", filter)
+}
+
+func (c clickHouseCatalog) columnsQuery(schema, table string) string {
+	return fmt.Sprintf("SELECT name AS column_name, type AS data_type, if(startsWith(type, 'Nullable'), 'YES', 'NO') AS is_nullable, default_expression AS column_default FROM system.columns WHERE database = %s AND table = %s ORDER BY position",
+		c.databaseExpr(schema), sqlString(table, true))
+}
+
+func (c clickHouseCatalog) indexesQuery(schema, table string) string {
+	db, tbl := c.databaseExpr(schema), sqlString(table, true)
+	return fmt.Sprintf("SELECT 'PRIMARY KEY' AS index_name, primary_key AS definition FROM system.tables WHERE database = %[1]s AND name = %[2]s AND primary_key != '' "+
+		"UNION ALL SELECT 'SORTING KEY' AS index_name, sorting_key AS definition FROM system.tables WHERE database = %[1]s AND name = %[2]s AND sorting_key != '' "+
+		"UNION ALL SELECT name AS index_name, concat(type, ' ', expr) AS definition FROM system.data_skipping_indices WHERE database = %[1]s AND table = %[2]s",
+		db, tbl)
+}
+
+func (clickHouseCatalog) sampleQuery(schema, table string, limit int) string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", qualifiedName(backquoteIdent, schema, table), limit)
+}
+
+// sqlDatasourceDialect looks up a datasource and returns its SQL dialect.
+func sqlDatasourceDialect(ctx context.Context, uid string) (*sqlDialect, string, error) {
+	if uid == "" {
+		return nil, "", fmt.Errorf("datasourceUid is required")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSourceByUID(uid)
+	if err != nil {
+		return nil, "", fmt.Errorf("get datasource %s: %w", uid, err)
+	}
+	dsType := resp.Payload.Type
+	dialect := sqlDialectForType(dsType)
+	if dialect == nil {
+		names := make([]string, len(sqlDialects))
+		for i, d := range sqlDialects {
+			names[i] = d.name
+		}
+		return nil, "", fmt.Errorf("datasource %s has type %q, which is not a supported SQL datasource (%s)", uid, dsType, strings.Join(names, ", "))
+	}
+	return dialect, dsType, nil
+}
+
+// runCatalogQuery runs a catalog query and returns its rows as maps keyed by
+// lower-cased column name.
+func runCatalogQuery(ctx context.Context, dialect *sqlDialect, dsType, uid, rawSQL string) ([]map[string]interface{}, bool, error) {
+	result, err := runSQL(ctx, dialect, dsType, QuerySQLParams{DatasourceUID: uid, RawSQL: rawSQL, Format: "table"})
+	if err != nil {
+		return nil, false, err
+	}
+	var records []map[string]interface{}
+	truncated := false
+	for _, frame := range result.Frames {
+		truncated = truncated || frame.Truncated
+		for _, row := range frame.Rows {
+			record := make(map[string]interface{}, len(row))
+			for i, v := range row {
+				if i < len(frame.Columns) {
+					record[strings.ToLower(frame.Columns[i])] = v
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	return records, truncated, nil
+}
+
+// recordString returns a catalog value as a string, or "" if it is null.
+func recordString(record map[string]interface{}, key string) string {
+	v := record[key]
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type ListSQLSchemasParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the PostgreSQL\\, MySQL\\, Microsoft SQL Server or ClickHouse datasource"`
+}
+
+type SQLSchemas struct {
+	DatasourceType string   `json:"datasourceType"`
+	Schemas        []string `json:"schemas"`
+}
+
+func listSQLSchemas(ctx context.Context, args ListSQLSchemasParams) (*SQLSchemas, error) {
+	dialect, dsType, err := sqlDatasourceDialect(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	records, _, err := runCatalogQuery(ctx, dialect, dsType, args.DatasourceUID, dialect.catalog.schemasQuery())
+	if err != nil {
+		return nil, fmt.Errorf("list schemas: %w", err)
+	}
+	result := &SQLSchemas{DatasourceType: dsType, Schemas: make([]string, 0, len(records))}
+	for _, r := range records {
+		result.Schemas = append(result.Schemas, recordString(r, "schema_name"))
+	}
+	return result, nil
+}
+
+var ListSQLSchemas = mcpgrafana.MustTool(
+	"list_sql_schemas",
+	"List the schemas of a PostgreSQL, MySQL, Microsoft SQL Server or ClickHouse datasource, excluding system schemas. For MySQL and ClickHouse the schemas are databases.",
+	listSQLSchemas,
+	mcp.WithTitleAnnotation("List SQL schemas"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListSQLTablesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the PostgreSQL\\, MySQL\\, Microsoft SQL Server or ClickHouse datasource"`
+	Schema        string `json:"schema,omitempty" jsonschema:"description=Only list tables in this schema. Defaults to every non-system schema"`
+}
+
+type SQLTable struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Type   string `json:"type,omitempty"`
+}
+
+type SQLTables struct {
+	Tables    []SQLTable `json:"tables"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+func listSQLTables(ctx context.Context, args ListSQLTablesParams) (*SQLTables, error) {
+	dialect, dsType, err := sqlDatasourceDialect(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	records, truncated, err := runCatalogQuery(ctx, dialect, dsType, args.DatasourceUID, dialect.catalog.tablesQuery(args.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	result := &SQLTables{Tables: make([]SQLTable, 0, len(records)), Truncated: truncated}
+	for _, r := range records {
+		result.Tables = append(result.Tables, SQLTable{
+			Schema: recordString(r, "table_schema"),
+			Name:   recordString(r, "table_name"),
+			Type:   recordString(r, "table_type"),
+		})
+	}
+	return result, nil
+}
+
+var ListSQLTables = mcpgrafana.MustTool(
+	"list_sql_tables",
+	"List the tables and views of a PostgreSQL, MySQL, Microsoft SQL Server or ClickHouse datasource, optionally in a single schema.",
+	listSQLTables,
+	mcp.WithTitleAnnotation("List SQL tables"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type DescribeSQLTableParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the PostgreSQL\\, MySQL\\, Microsoft SQL Server or ClickHouse datasource"`
+	Schema        string `json:"schema,omitempty" jsonschema:"description=The schema of the table. Defaults to the current schema of the connection"`
+	Table         string `json:"table" jsonschema:"required,description=The name of the table"`
+}
+
+type SQLColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+}
+
+type SQLIndex struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+type SQLTableDescription struct {
+	Schema  string      `json:"schema,omitempty"`
+	Table   string      `json:"table"`
+	Columns []SQLColumn `json:"columns"`
+	Indexes []SQLIndex  `json:"indexes"`
+}
+
+func describeSQLTable(ctx context.Context, args DescribeSQLTableParams) (*SQLTableDescription, error) {
+	if args.Table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	dialect, dsType, err := sqlDatasourceDialect(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	columns, _, err := runCatalogQuery(ctx, dialect, dsType, args.DatasourceUID, dialect.catalog.columnsQuery(args.Schema, args.Table))
+	if err != nil {
+		return nil, fmt.Errorf("list columns of %s: %w", args.Table, err)
+	}
+	if len(columns) == 0 {
+		if args.Schema != "" {
+			return nil, fmt.Errorf("table %s.%s not found", args.Schema, args.Table)
+		}
+		return nil, fmt.Errorf("table %s not found", args.Table)
+	}
+	indexes, _, err := runCatalogQuery(ctx, dialect, dsType, args.DatasourceUID, dialect.catalog.indexesQuery(args.Schema, args.Table))
+	if err != nil {
+		return nil, fmt.Errorf("list indexes of %s: %w", args.Table, err)
+	}
+
+	result := &SQLTableDescription{
+		Schema:  args.Schema,
+		Table:   args.Table,
+		Columns: make([]SQLColumn, 0, len(columns)),
+		Indexes: make([]SQLIndex, 0, len(indexes)),
+	}
+	for _, r := range columns {
+		result.Columns = append(result.Columns, SQLColumn{
+			Name:     recordString(r, "column_name"),
+			Type:     recordString(r, "data_type"),
+			Nullable: strings.EqualFold(recordString(r, "is_nullable"), "YES"),
+			Default:  recordString(r, "column_default"),
+		})
+	}
+	for _, r := range indexes {
+		result.Indexes = append(result.Indexes, SQLIndex{
+			Name:       recordString(r, "index_name"),
+			Definition: recordString(r, "definition"),
+		})
+	}
+	return result, nil
+}
+
+var DescribeSQLTable = mcpgrafana.MustTool(
+	"describe_sql_table",
+	"Describe a table of a PostgreSQL, MySQL, Microsoft SQL Server or ClickHouse datasource: its columns with their types, nullability and defaults, and its indexes. Use this before writing queries against the table.",
+	describeSQLTable,
+	mcp.WithTitleAnnotation("Describe SQL table"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type SampleSQLRowsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the PostgreSQL\\, MySQL\\, Microsoft SQL Server or ClickHouse datasource"`
+	Schema        string `json:"schema,omitempty" jsonschema:"description=The schema of the table. Defaults to the current schema of the connection"`
+	Table         string `json:"table" jsonschema:"required,description=The name of the table"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Number of rows to return (default 10\\, max 100)"`
+}
+
+func sampleSQLRows(ctx context.Context, args SampleSQLRowsParams) (*SQLQueryResult, error) {
+	if args.Table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSQLSampleRows
+	}
+	limit = min(limit, maxSQLSampleRows)
+	dialect, dsType, err := sqlDatasourceDialect(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := runSQL(ctx, dialect, dsType, QuerySQLParams{
+		DatasourceUID: args.DatasourceUID,
+		RawSQL:        dialect.catalog.sampleQuery(args.Schema, args.Table, limit),
+		Format:        "table",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sample rows of %s: %w", args.Table, err)
+	}
+	return result, nil
+}
+
+var SampleSQLRows = mcpgrafana.MustTool(
+	"sample_sql_rows",
+	"Return a few rows of a table of a PostgreSQL, MySQL, Microsoft SQL Server or ClickHouse datasource, to see what its data looks like. At most 100 rows are returned.",
+	sampleSQLRows,
+	mcp.WithTitleAnnotation("Sample SQL rows"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
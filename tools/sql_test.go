@@ -0,0 +1,209 @@
+//go:build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLTestServer(t *testing.T, queries *[]map[string]interface{}) *httptest.Server {
+	types := map[string]string{
+		"pg":    "grafana-postgresql-datasource",
+		"mysql": "mysql",
+		"ch":    "grafana-clickhouse-datasource",
+		"prom":  "prometheus",
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/datasources/uid/"):
+			uid := strings.TrimPrefix(r.URL.Path, "/api/datasources/uid/")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"uid": uid, "name": uid, "type": types[uid]})
+		case r.URL.Path == "/api/ds/query":
+			var body dsQueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			*queries = append(*queries, body.Queries...)
+			_, _ = w.Write([]byte(`{"results": {"A": {"frames": [{
+				"schema": {"fields": [{"name": "host", "type": "string", "typeInfo": {"frame": "string"}}]},
+				"data": {"values": [["a", "b"]]}}]}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestQuerySQL(t *testing.T) {
+	var queries []map[string]interface{}
+	server := newSQLTestServer(t, &queries)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	result, err := querySQL(ctx, mysqlDialect, QuerySQLParams{DatasourceUID: "mysql", RawSQL: "SELECT host FROM hosts WHERE $__timeFilter(ts)"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "mysql", result.DatasourceType)
+	require.Len(t, result.Frames, 1)
+	assert.Equal(t, [][]interface{}{{"a"}, {"b"}}, result.Frames[0].Rows)
+	require.Len(t, queries, 1)
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(30000) */ host FROM hosts WHERE $__timeFilter(ts)\nLIMIT 1001", queries[0]["rawSql"])
+	assert.Equal(t, "table", queries[0]["format"])
+	assert.Equal(t, map[string]interface{}{"uid": "mysql", "type": "mysql"}, queries[0]["datasource"])
+
+	_, err = querySQL(ctx, clickHouseDialect, QuerySQLParams{DatasourceUID: "ch", RawSQL: "SELECT 1", Format: "time_series"}, false)
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	assert.EqualValues(t, 0, queries[1]["format"])
+	assert.Equal(t, "timeseries", queries[1]["queryType"])
+	assert.Equal(t, "sql", queries[1]["editorType"])
+
+	_, err = querySQL(ctx, postgresDialect, QuerySQLParams{DatasourceUID: "pg", RawSQL: "SELECT 1", Format: "logs"}, false)
+	assert.ErrorContains(t, err, "invalid format")
+}
+
+func TestQuerySQL_DatasourceTypeValidation(t *testing.T) {
+	var queries []map[string]interface{}
+	server := newSQLTestServer(t, &queries)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	_, err := querySQL(ctx, mysqlDialect, QuerySQLParams{DatasourceUID: "pg", RawSQL: "SELECT 1"}, false)
+	assert.ErrorContains(t, err, "is a PostgreSQL datasource, not MySQL: use query_postgres instead")
+
+	_, err = querySQL(ctx, mssqlDialect, QuerySQLParams{DatasourceUID: "prom", RawSQL: "SELECT 1"}, false)
+	assert.ErrorContains(t, err, `has type "prometheus"`)
+	assert.Empty(t, queries)
+}
+
+// newSQLCatalogServer answers catalog queries with the frame returned by
+// respond for the query's SQL.
+func newSQLCatalogServer(t *testing.T, dsType string, respond func(rawSQL string) string) (*httptest.Server, *[]string) {
+	var sqls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/datasources/uid/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"uid": "db", "type": dsType})
+		case r.URL.Path == "/api/ds/query":
+			var body dsQueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			rawSQL := body.Queries[0]["rawSql"].(string)
+			sqls = append(sqls, rawSQL)
+			_, _ = w.Write([]byte(`{"results": {"A": {"frames": [` + respond(rawSQL) + `]}}}`))
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, &sqls
+}
+
+// stringFrame builds a frame of nullable string columns.
+func stringFrame(columns []string, rows ...[]interface{}) string {
+	fields := make([]map[string]interface{}, len(columns))
+	values := make([][]interface{}, len(columns))
+	for i, c := range columns {
+		fields[i] = map[string]interface{}{"name": c, "type": "string", "typeInfo": map[string]interface{}{"frame": "string", "nullable": true}}
+		values[i] = []interface{}{}
+		for _, row := range rows {
+			values[i] = append(values[i], row[i])
+		}
+	}
+	// The frame decoder requires the schema to come before the data.
+	schema, _ := json.Marshal(fields)
+	data, _ := json.Marshal(values)
+	return `{"schema": {"fields": ` + string(schema) + `}, "data": {"values": ` + string(data) + `}}`
+}
+
+func TestDescribeSQLTable(t *testing.T) {
+	server, sqls := newSQLCatalogServer(t, "grafana-postgresql-datasource", func(rawSQL string) string {
+		if strings.Contains(rawSQL, "pg_indexes") {
+			return stringFrame([]string{"index_name", "definition"},
+				[]interface{}{"orders_pkey", "CREATE UNIQUE INDEX orders_pkey ON public.orders USING btree (id)"})
+		}
+		return stringFrame([]string{"column_name", "data_type", "is_nullable", "column_default"},
+			[]interface{}{"id", "integer", "NO", "nextval('orders_id_seq'::regclass)"},
+			[]interface{}{"status", "order_status", "YES", nil})
+	})
+	defer server.Close()
+
+	desc, err := describeSQLTable(mockCtxWithGrafanaURL(server), DescribeSQLTableParams{DatasourceUID: "db", Table: "o'rders"})
+	require.NoError(t, err)
+	assert.Equal(t, []SQLColumn{
+		{Name: "id", Type: "integer", Nullable: false, Default: "nextval('orders_id_seq'::regclass)"},
+		{Name: "status", Type: "order_status", Nullable: true},
+	}, desc.Columns)
+	assert.Equal(t, []SQLIndex{{Name: "orders_pkey", Definition: "CREATE UNIQUE INDEX orders_pkey ON public.orders USING btree (id)"}}, desc.Indexes)
+
+	require.Len(t, *sqls, 2)
+	assert.Contains(t, (*sqls)[0], "table_schema = current_schema() AND table_name = 'o''rders'")
+	assert.Contains(t, (*sqls)[1], "schemaname = current_schema() AND tablename = 'o''rders'")
+}
+
+func TestListSQLTables_MySQL(t *testing.T) {
+	server, sqls := newSQLCatalogServer(t, "mysql", func(rawSQL string) string {
+		// MySQL 8 returns information_schema columns in upper case unless
+		// they are aliased, so the lookup must not depend on the case.
+		return stringFrame([]string{"TABLE_SCHEMA", "TABLE_NAME", "TABLE_TYPE"},
+			[]interface{}{"shop", "orders", "BASE TABLE"})
+	})
+	defer server.Close()
+
+	tables, err := listSQLTables(mockCtxWithGrafanaURL(server), ListSQLTablesParams{DatasourceUID: "db", Schema: `sh\op`})
+	require.NoError(t, err)
+	assert.Equal(t, []SQLTable{{Schema: "shop", Name: "orders", Type: "BASE TABLE"}}, tables.Tables)
+	assert.Contains(t, (*sqls)[0], `WHERE table_schema = 'sh\\op'`)
+}
+
+func TestSampleSQLRows(t *testing.T) {
+	server, sqls := newSQLCatalogServer(t, "mssql", func(rawSQL string) string {
+		return stringFrame([]string{"id"}, []interface{}{"1"})
+	})
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	result, err := sampleSQLRows(ctx, SampleSQLRowsParams{DatasourceUID: "db", Schema: "dbo", Table: "odd]name", Limit: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"1"}}, result.Frames[0].Rows)
+	assert.Equal(t, "SELECT TOP 100 * FROM [dbo].[odd]]name]", (*sqls)[0])
+
+	_, err = sampleSQLRows(ctx, SampleSQLRowsParams{DatasourceUID: "db"})
+	assert.ErrorContains(t, err, "table is required")
+}
+
+func TestSQLDatasourceDialect_NotSQL(t *testing.T) {
+	server, _ := newSQLCatalogServer(t, "prometheus", nil)
+	defer server.Close()
+
+	_, err := listSQLSchemas(mockCtxWithGrafanaURL(server), ListSQLSchemasParams{DatasourceUID: "db"})
+	assert.ErrorContains(t, err, `has type "prometheus", which is not a supported SQL datasource`)
+}
+
+func TestQuerySQL_ReadOnlyAndRowLimit(t *testing.T) {
+	var queries []map[string]interface{}
+	server := newSQLTestServer(t, &queries)
+	defer server.Close()
+	ctx := mockCtxWithGrafanaURL(server)
+
+	_, err := querySQL(ctx, postgresDialect, QuerySQLParams{DatasourceUID: "pg", RawSQL: "DELETE FROM hosts"}, false)
+	assert.ErrorContains(t, err, "unless write tools are enabled")
+	assert.Empty(t, queries)
+
+	_, err = querySQL(ctx, postgresDialect, QuerySQLParams{DatasourceUID: "pg", RawSQL: "DELETE FROM hosts"}, true)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+	assert.Equal(t, "DELETE FROM hosts", queries[0]["rawSql"])
+
+	result, err := querySQL(ctx, postgresDialect, QuerySQLParams{DatasourceUID: "pg", RawSQL: "SELECT host FROM hosts", MaxRows: 1}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT host FROM hosts\nLIMIT 2", queries[1]["rawSql"])
+	assert.Equal(t, 1, result.RowLimit)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, [][]interface{}{{"a"}}, result.Frames[0].Rows)
+}